@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/repohooks"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+// deleteAccount shows the account deletion confirmation page.
+func (h *handler) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+	err := templates.DeleteAccount.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+// deleteAccountAction deletes the stored data of the user, and logs the user out. The
+// user confirms the deletion by typing the user login.
+func (h *handler) deleteAccountAction(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+	login := data.User.GetLogin()
+	if r.FormValue("confirm") != login {
+		redirectError(w, r, r.URL.Path, "Type your login to confirm the account deletion")
+		return
+	}
+
+	if err := h.deleteUserData(login, data.User.GetID(), r.FormValue("projects") != ""); err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if err := h.auth.Revoke(login); err != nil {
+		logrus.Errorf("Sessions of %s were not revoked: %s", login, err)
+	}
+	logrus.Infof("Deleted account of %s", login)
+	h.auth.Logout(w, r)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// userRows are the rows of a user in a table.
+type userRows struct {
+	table string
+	where string
+	args  []interface{}
+}
+
+// deleteUserData deletes the API tokens, repository webhooks and audit entries of a user,
+// and the installation of the user account with its plan and API calls. If projects is
+// true, the projects, jobs, settings and artifacts of the repositories of the user account
+// are deleted too.
+func (h *handler) deleteUserData(login string, userID int64, projects bool) error {
+	// The installations of the user are the app installation of the account, and the
+	// installation of the repository webhooks of the user.
+	const installs = "install IN (SELECT id FROM installations WHERE account = ?) OR install = ?"
+	rows := []userRows{
+		{"tokens", "login = ?", []interface{}{login}},
+		{"repo_hooks", "login = ?", []interface{}{login}},
+		{"audit", "login = ?", []interface{}{login}},
+		{"plans", installs, []interface{}{login, repohooks.Install(userID)}},
+		{"api_calls", installs, []interface{}{login, repohooks.Install(userID)}},
+	}
+	if projects {
+		for _, table := range []string{"jobs", "projects", "settings", "artifacts"} {
+			rows = append(rows, userRows{table, "owner = ?", []interface{}{login}})
+		}
+	}
+	// The installation is deleted last, since the deletions of the plan and API calls use it.
+	rows = append(rows, userRows{"installations", "account = ?", []interface{}{login}})
+
+	tx := h.db.Begin()
+	for _, r := range rows {
+		err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", r.table, r.where), r.args...).Error
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed deleting %s of %s", r.table, login)
+		}
+	}
+	return errors.Wrapf(tx.Commit().Error, "failed deleting data of %s", login)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/plans"
+	"github.com/posener/goreadme-server/internal/repohooks"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/posener/goreadme-server/internal/usage"
+)
+
+// accountFixtures inserts the data of an account and its installation.
+func accountFixtures(t *testing.T, h *handler, login string, install, userID int64) {
+	t.Helper()
+	githubtest.Fixtures(t, h.db, &Installation{ID: install, Account: login})
+	p := &Project{Owner: login, Repo: "hello", Install: install, Status: status.Success}
+	j := &Job{Project: *p, Num: 1}
+	githubtest.Fixtures(t, h.db, p, j)
+	if _, err := h.tokens.Issue(login, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.repoHooks.Set(login, "hook", login, userID, "token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.plans.Set(install, plans.Pro.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := usage.RecordCalls(h.db, repohooks.Install(userID), 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// count returns the number of rows of a table that match a condition.
+func count(t *testing.T, h *handler, table, where string, arg interface{}) int {
+	t.Helper()
+	var n int
+	if err := h.db.Table(table).Where(where, arg).Count(&n).Error; err != nil {
+		t.Fatalf("Count %s: %s", table, err)
+	}
+	return n
+}
+
+func TestDeleteUserData(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+
+	accountFixtures(t, h, "posener", 1, 101)
+	accountFixtures(t, h, "other", 2, 102)
+
+	// The projects are kept unless they were requested to be deleted.
+	if err := h.deleteUserData("posener", 101, false); err != nil {
+		t.Fatalf("deleteUserData: %s", err)
+	}
+	for _, c := range []struct {
+		table, where string
+		arg          interface{}
+	}{
+		{"tokens", "login = ?", "posener"},
+		{"repo_hooks", "login = ?", "posener"},
+		{"installations", "account = ?", "posener"},
+		{"plans", "install = ?", 1},
+		{"api_calls", "install = ?", repohooks.Install(101)},
+	} {
+		if n := count(t, h, c.table, c.where, c.arg); n != 0 {
+			t.Errorf("%d %s rows were not deleted", n, c.table)
+		}
+	}
+	if n := count(t, h, "projects", "owner = ?", "posener"); n != 1 {
+		t.Errorf("Got %d projects, want the project to be kept", n)
+	}
+
+	if err := h.deleteUserData("posener", 101, true); err != nil {
+		t.Fatalf("deleteUserData: %s", err)
+	}
+	for _, table := range []string{"projects", "jobs"} {
+		if n := count(t, h, table, "owner = ?", "posener"); n != 0 {
+			t.Errorf("%d %s rows were not deleted", n, table)
+		}
+	}
+
+	// The data of other accounts is kept.
+	for _, c := range []struct {
+		table, where string
+		arg          interface{}
+	}{
+		{"tokens", "login = ?", "other"},
+		{"repo_hooks", "login = ?", "other"},
+		{"installations", "account = ?", "other"},
+		{"plans", "install = ?", 2},
+		{"api_calls", "install = ?", repohooks.Install(102)},
+		{"projects", "owner = ?", "other"},
+		{"jobs", "owner = ?", "other"},
+	} {
+		if n := count(t, h, c.table, c.where, c.arg); n != 1 {
+			t.Errorf("Got %d %s rows of another account, want 1", n, c.table)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+func isAdmin(login string) bool {
+	for _, admin := range cfg.Admins {
+		if admin == login {
+			return true
+		}
+	}
+	return false
+}
+
+// adminData returns the template data of an admin request. It returns nil and
+// responds with an error if the user is not an admin.
+func (h *handler) adminData(w http.ResponseWriter, r *http.Request) *templateData {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil || !data.Admin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil
+	}
+	return data
+}
+
+func (h *handler) adminFlags(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+
+	var err error
+	data.Flags, err = h.flags.List()
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	err = templates.AdminFlags.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+func (h *handler) adminFlagsAction(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+
+	var (
+		name    = r.FormValue("name")
+		enabled = r.FormValue("enabled") == "true"
+	)
+	install, err := strconv.ParseInt(r.FormValue("install"), 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/admin/flags?error=invalid%20installation", http.StatusFound)
+		return
+	}
+
+	if r.FormValue("delete") != "" {
+		err = h.flags.Delete(name, install)
+	} else {
+		err = h.flags.Set(name, install, enabled)
+	}
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	logrus.Infof("Admin %s set flag %s for install %d: enabled=%v deleted=%v",
+		data.User.GetLogin(), name, install, enabled, r.FormValue("delete") != "")
+	http.Redirect(w, r, "/admin/flags", http.StatusFound)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// contextLogin holds the login of the user of the API token of a request.
+	contextLogin contextKey = "login"
+	// contextToken holds the ID of the API token of a request.
+	contextToken contextKey = "token"
+)
+
+// writeJSON writes a JSON API response.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Errorf("Failed encoding API response: %s", err)
+	}
+}
+
+// apiError writes a JSON API error response.
+func apiError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// authenticate authenticates API requests with a bearer API token, and stores the login of
+// the token user and the token ID in the request context. Requests without a valid token
+// are passed on unauthenticated.
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if secret == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, err := h.tokens.Authenticate(secret)
+		if err != nil {
+			logrus.Errorf("Failed authenticating API token: %s", err)
+			apiError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if token != nil {
+			ctx := context.WithValue(r.Context(), contextLogin, token.Login)
+			r = r.WithContext(context.WithValue(ctx, contextToken, token.ID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireToken rejects API requests that were not authenticated with a valid API token.
+func (h *handler) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(contextLogin).(string); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") == "" {
+			apiError(w, http.StatusUnauthorized, "missing API token")
+		} else {
+			apiError(w, http.StatusUnauthorized, "invalid API token")
+		}
+	})
+}
+
+// apiUser returns the user of the API token.
+func (h *handler) apiUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"login": r.Context().Value(contextLogin).(string)})
+}
+
+const (
+	// defaultAPIRateLimit is the number of API requests that a client can send in
+	// apiRateWindow, unless it is changed by the api_rate_limit runtime setting.
+	defaultAPIRateLimit = 60
+	apiRateWindow       = time.Minute
+)
+
+// rateLimit limits the API requests of each client, and reports the limit in the
+// X-RateLimit headers of the responses. Clients are identified by their authenticated API
+// token, and requests without a valid token by their address, so sending random tokens
+// doesn't avoid the limit.
+func (h *handler) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var key string
+		if id, ok := r.Context().Value(contextToken).(int64); ok {
+			key = "token:" + strconv.FormatInt(id, 10)
+		} else {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			key = "addr:" + host
+		}
+		// Start a new window if the client has none, and count the request in it.
+		h.apiLimits.Add(key, 0, apiRateWindow)
+		n, err := h.apiLimits.IncrementInt(key, 1)
+		if err != nil {
+			// The window has just expired.
+			n = 1
+			h.apiLimits.Set(key, n, apiRateWindow)
+		}
+		_, reset, _ := h.apiLimits.GetWithExpiration(key)
+
+		apiRateLimit := int(atomic.LoadInt32(&h.apiRateLimit))
+		remaining := apiRateLimit - n
+		if remaining < 0 {
+			remaining = 0
+		}
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(apiRateLimit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if n > apiRateLimit {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+			apiError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
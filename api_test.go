@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/tokens"
+)
+
+// apiRequest returns an API request from an address, authenticated with the token ID if
+// it is not zero, as the authenticate middleware does.
+func apiRequest(addr, auth string, token int64) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/user", nil)
+	r.RemoteAddr = addr + ":1234"
+	if auth != "" {
+		r.Header.Set("Authorization", "Bearer "+auth)
+	}
+	if token != 0 {
+		ctx := context.WithValue(r.Context(), contextLogin, "posener")
+		r = r.WithContext(context.WithValue(ctx, contextToken, token))
+	}
+	return r
+}
+
+func TestRateLimit(t *testing.T) {
+	h := &handler{
+		apiLimits:    gocache.New(apiRateWindow, 10*time.Minute),
+		apiRateLimit: 2,
+	}
+	limited := h.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	send := func(r *http.Request) int {
+		rec := httptest.NewRecorder()
+		limited.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "token 1", req: apiRequest("10.0.0.1", "a", 1), want: http.StatusOK},
+		{name: "token 1 from another address", req: apiRequest("10.0.0.2", "a", 1), want: http.StatusOK},
+		{name: "token 1 exceeded", req: apiRequest("10.0.0.3", "a", 1), want: http.StatusTooManyRequests},
+		// Other tokens have their own limit.
+		{name: "token 2", req: apiRequest("10.0.0.1", "b", 2), want: http.StatusOK},
+		// Unauthenticated requests are limited by address, regardless of the token
+		// that was sent.
+		{name: "address", req: apiRequest("10.0.0.1", "", 0), want: http.StatusOK},
+		{name: "address with invalid token", req: apiRequest("10.0.0.1", "invalid1", 0), want: http.StatusOK},
+		{name: "address with another invalid token", req: apiRequest("10.0.0.1", "invalid2", 0), want: http.StatusTooManyRequests},
+		{name: "other address", req: apiRequest("10.0.0.2", "invalid3", 0), want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := send(tt.req); got != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	h := &handler{
+		apiLimits:    gocache.New(apiRateWindow, 10*time.Minute),
+		apiRateLimit: 1,
+	}
+	limited := h.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	limited.ServeHTTP(rec, apiRequest("10.0.0.1", "", 0))
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "1" {
+		t.Errorf("X-RateLimit-Limit = %q, want 1", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+
+	rec = httptest.NewRecorder()
+	limited.ServeHTTP(rec, apiRequest("10.0.0.1", "", 0))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Got status %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Missing Retry-After header")
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	h := &handler{}
+	required := h.requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "authenticated", req: apiRequest("10.0.0.1", "a", 1), want: http.StatusOK},
+		{name: "missing", req: apiRequest("10.0.0.1", "", 0), want: http.StatusUnauthorized},
+		{name: "invalid", req: apiRequest("10.0.0.1", "invalid", 0), want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		required.ServeHTTP(rec, tt.req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	h := &handler{
+		tokens:       tokens.New(db),
+		apiLimits:    gocache.New(apiRateWindow, 10*time.Minute),
+		apiRateLimit: 1,
+	}
+	secret, err := h.tokens.Issue("posener", "ci")
+	if err != nil {
+		t.Fatalf("Issue: %s", err)
+	}
+	api := h.authenticate(h.rateLimit(h.requireToken(http.HandlerFunc(h.apiUser))))
+	send := func(addr, auth string) int {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, apiRequest(addr, auth, 0))
+		return rec.Code
+	}
+
+	if got := send("10.0.0.1", secret); got != http.StatusOK {
+		t.Errorf("Valid token: got status %d, want %d", got, http.StatusOK)
+	}
+	// The token limit is exceeded from any address.
+	if got := send("10.0.0.2", secret); got != http.StatusTooManyRequests {
+		t.Errorf("Valid token from another address: got status %d, want %d", got, http.StatusTooManyRequests)
+	}
+	// Invalid tokens are limited by the address, and don't use the limit of the token.
+	if got := send("10.0.0.3", "invalid1"); got != http.StatusUnauthorized {
+		t.Errorf("Invalid token: got status %d, want %d", got, http.StatusUnauthorized)
+	}
+	if got := send("10.0.0.3", "invalid2"); got != http.StatusTooManyRequests {
+		t.Errorf("Another invalid token: got status %d, want %d", got, http.StatusTooManyRequests)
+	}
+}
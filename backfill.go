@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// backfillInterval is the default interval between the jobs that a backfill starts, so
+// the Github API rate limits are not exceeded.
+const backfillInterval = 5 * time.Second
+
+// backfillResult counts the repositories that a backfill went over.
+type backfillResult struct {
+	Installations int
+	Repos         int
+	Started       int
+	Failed        int
+}
+
+func (r backfillResult) String() string {
+	return fmt.Sprintf("installations=%d repos=%d started=%d failed=%d", r.Installations, r.Repos, r.Started, r.Failed)
+}
+
+// backfillCommand runs a backfill from the command line, waits for the jobs that it
+// started, and returns the process exit code. Run it with:
+//
+// 	go run . backfill [-interval 5s] [-dry-run]
+func (h *handler) backfillCommand(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	interval := fs.Duration("interval", backfillInterval, "Interval between started jobs.")
+	dryRun := fs.Bool("dry-run", false, "Only log the repositories that are missing projects.")
+	fs.Parse(args)
+
+	result, err := h.backfill(ctx, *interval, *dryRun)
+	logrus.Infof("Backfill: %s", result)
+	if err != nil {
+		logrus.Errorf("Failed backfill: %s", err)
+		return 1
+	}
+	h.queue.wait()
+	return 0
+}
+
+// adminBackfill starts a backfill in the background.
+func (h *handler) adminBackfill(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+	interval := backfillInterval
+	if v := r.FormValue("interval"); v != "" {
+		var err error
+		interval, err = time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, "Invalid interval: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if !atomic.CompareAndSwapInt32(&h.backfilling, 0, 1) {
+		http.Error(w, "A backfill is already running", http.StatusConflict)
+		return
+	}
+
+	logrus.Infof("Admin %s started a backfill", data.User.GetLogin())
+	go func() {
+		defer atomic.StoreInt32(&h.backfilling, 0)
+		result, err := h.backfill(context.Background(), interval, r.FormValue("dry_run") == "true")
+		if err != nil {
+			logrus.Errorf("Failed backfill: %s", err)
+		}
+		logrus.Infof("Backfill: %s", result)
+	}()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "Backfill started, the progress is reported in the server logs.")
+}
+
+// backfill walks all the installations of the app, and starts an initial job for every
+// installed repository that has no project, such as repositories that were installed
+// before the server tracked them. Jobs are started one per interval.
+func (h *handler) backfill(ctx context.Context, interval time.Duration, dryRun bool) (backfillResult, error) {
+	var result backfillResult
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		installs, resp, err := h.github.Apps.ListInstallations(ctx, opt)
+		if err != nil {
+			return result, errors.Wrap(err, "failed listing installations")
+		}
+		for _, install := range installs {
+			result.Installations++
+			err := h.backfillInstallation(ctx, install, interval, dryRun, &result)
+			if err != nil {
+				logrus.Errorf("Failed backfilling installation %d: %s", install.GetID(), err)
+			}
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+		}
+		if resp.NextPage == 0 {
+			return result, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// backfillInstallation starts initial jobs for the repositories of an installation that
+// have no project.
+func (h *handler) backfillInstallation(ctx context.Context, install *github.Installation, interval time.Duration, dryRun bool, result *backfillResult) error {
+	client, err := h.github.Installation(ctx, install.GetAccount().GetLogin())
+	if err != nil {
+		return err
+	}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		repos, resp, err := client.Github.Apps.ListRepos(ctx, opt)
+		if err != nil {
+			return errors.Wrap(err, "failed listing installation repos")
+		}
+		for _, repo := range repos {
+			result.Repos++
+			owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+			if !h.hookRepoAllowed(owner, name) {
+				continue
+			}
+			var count int
+			err := h.db.Model(&Project{}).Where("owner = ? AND repo = ?", owner, name).Count(&count).Error
+			if err != nil {
+				return errors.Wrap(err, "failed counting projects")
+			}
+			if count > 0 {
+				continue
+			}
+			if dryRun {
+				logrus.Infof("Backfill: %s/%s has no project", owner, name)
+				continue
+			}
+			_, _, err = h.runJob(ctx, &Project{Install: install.GetID(), Owner: owner, Repo: name}, trigger{Name: "Backfill"})
+			if err != nil {
+				result.Failed++
+				logrus.Errorf("Failed backfilling %s/%s: %s", owner, name, err)
+				continue
+			}
+			result.Started++
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
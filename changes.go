@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// settingPushPaths is the project setting key of the path patterns that trigger a job on
+// push. Patterns are separated by commas or whitespace, and are matched with path.Match.
+// Patterns without a slash are matched against the file name. If not set, changes to Go
+// files and to goreadme configuration files trigger a job.
+const settingPushPaths = "push_paths"
+
+// maxPushCommits is the maximal number of commits that Github includes in a push event.
+// The changed files of larger pushes are unknown.
+const maxPushCommits = 20
+
+// maxCompareFiles is the maximal number of files that the Github compare API returns.
+// Comparisons with more files are considered as doc changes.
+const maxCompareFiles = 300
+
+// docsUnchanged returns whether no doc relevant files were changed in the head commit since
+// the last readme generation of the project.
+func (j *Job) docsUnchanged(ctx context.Context, cfg config, last *Project) (bool, error) {
+	if last.SourceSHA == "" {
+		return false, nil
+	}
+	commit, _, err := j.github.Git.GetCommit(ctx, j.Owner, j.Repo, j.HeadSHA)
+	if err != nil {
+		return false, errors.Wrap(err, "failed getting head commit")
+	}
+	j.SourceTree = commit.GetTree().GetSHA()
+	if j.SourceTree == last.SourceTree {
+		return true, nil
+	}
+
+	cmp, _, err := j.github.Repositories.CompareCommits(ctx, j.Owner, j.Repo, last.SourceSHA, j.HeadSHA)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed comparing %s to last generation", shortSHA(j.HeadSHA))
+	}
+	if cmp.GetStatus() != "ahead" || len(cmp.Files) >= maxCompareFiles {
+		return false, nil
+	}
+	for _, f := range cmp.Files {
+		if docRelevant(f.GetFilename(), cfg) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// docRelevant returns whether a change to a file in the repository may change the
+// generated readme.
+func docRelevant(name string, cfg config) bool {
+	switch name {
+	case configPath, "go.mod", cfg.HeaderFile, cfg.FooterFile:
+		return true
+	}
+	switch strings.ToLower(path.Base(name)) {
+	case "readme.md", "readme.markdown":
+		return true
+	}
+	return strings.HasSuffix(name, ".go") || strings.HasPrefix(name, targetsDir+"/")
+}
+
+// lastProject returns the stored state of the project of the job.
+func (j *Job) lastProject() (*Project, error) {
+	var p Project
+	query := j.db.Where("owner = ? AND repo = ?", j.Owner, j.Repo).First(&p)
+	switch {
+	case query.RecordNotFound():
+		return &p, nil
+	case query.Error != nil:
+		return nil, errors.Wrap(query.Error, "failed getting project")
+	}
+	return &p, nil
+}
+
+// pushPaths returns the push path patterns of the project settings.
+func pushPaths(settings map[string]string) []string {
+	return strings.FieldsFunc(settings[settingPushPaths], func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// pushChangesDocs returns whether a push changed any file that matches the given patterns.
+// If no patterns are given, doc relevant files are matched.
+func pushChangesDocs(e *github.PushEvent, patterns []string) bool {
+	if len(e.Commits) == 0 || len(e.Commits) >= maxPushCommits {
+		return true
+	}
+	for _, c := range e.Commits {
+		for _, files := range [][]string{c.Added, c.Removed, c.Modified} {
+			for _, name := range files {
+				if matchPath(name, patterns) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchPath(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return docRelevant(name, config{})
+	}
+	for _, pattern := range patterns {
+		target := name
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/githubapp"
+)
+
+// check validates the configuration, the database connectivity and the Github credentials,
+// and prints a report. It returns the exit code of the process, which is non-zero if any
+// of the checks failed. Checks that depend on a failed check are skipped.
+func check(ctx context.Context) int {
+	var (
+		app *githubapp.App
+		db  *gorm.DB
+	)
+	defer func() {
+		if db != nil {
+			db.Close()
+		}
+	}()
+
+	checks := []struct {
+		name string
+		// needs is the name of a check that must pass before this check can run.
+		needs string
+		run   func() error
+	}{
+		{name: "config", run: loadConfig},
+		{name: "hook secret", needs: "config", run: checkGithubConfig},
+		{name: "github key", needs: "config", run: func() (err error) {
+			ghCfg := githubapp.Config{AppID: strconv.Itoa(cfg.GithubAppID), LoadKey: githubKey}
+			app, err = ghCfg.NewApp(ctx)
+			return err
+		}},
+		{name: "github app", needs: "github key", run: func() error {
+			return checkGithubApp(ctx, app.Client)
+		}},
+		{name: "database", needs: "config", run: func() (err error) {
+			db, err = gorm.Open("postgres", cfg.DatabaseURL)
+			if err != nil {
+				return errors.Wrap(err, "failed connecting")
+			}
+			return errors.Wrap(db.DB().PingContext(ctx), "failed pinging")
+		}},
+		{name: "database schema", needs: "database", run: func() error {
+			return checkSchema(db)
+		}},
+	}
+
+	passed := make(map[string]bool)
+	code := 0
+	for _, c := range checks {
+		if c.needs != "" && !passed[c.needs] {
+			fmt.Printf("SKIP %s: %s check failed\n", c.name, c.needs)
+			continue
+		}
+		if err := c.run(); err != nil {
+			fmt.Printf("FAIL %s: %s\n", c.name, err)
+			code = 1
+			continue
+		}
+		passed[c.name] = true
+		fmt.Printf("OK   %s\n", c.name)
+	}
+	return code
+}
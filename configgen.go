@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+// configGenerator shows a form that generates a goreadme.json file.
+func (h *handler) configGenerator(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	// nil user is valid here, the config can be downloaded without login.
+
+	if data.InstallID != 0 {
+		c, err := h.github.Installation(r.Context(), data.Account)
+		if err != nil {
+			h.doError(w, r, errors.Wrap(err, "get installation client"))
+			return
+		}
+		data.ConfigRepos, _, err = c.Github.Apps.ListRepos(r.Context(), nil)
+		if err != nil {
+			h.doError(w, r, errors.Wrap(err, "failed getting repos"))
+			return
+		}
+	}
+
+	err := templates.ConfigGenerator.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+// configGeneratorAction downloads the generated goreadme.json file, or opens a PR that
+// adds it to a repository.
+func (h *handler) configGeneratorAction(w http.ResponseWriter, r *http.Request) {
+	content, err := json.MarshalIndent(configFromForm(r), "", "\t")
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed marshaling config"))
+		return
+	}
+	content = append(content, '\n')
+
+	if r.FormValue("action") != "pr" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", "attachment; filename="+configPath)
+		w.Write(content)
+		return
+	}
+
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusFound)
+		return
+	}
+	if !requireRole(w, data, roles.Operator) {
+		return
+	}
+	// The repository is selected by its full name.
+	owner, repo := splitFullName(r.FormValue("repo"))
+	installed, err := h.installedRepo(r, owner, repo)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if !installed {
+		redirectError(w, r, "/config", fmt.Sprintf("Repository %s/%s is not installed", owner, repo))
+		return
+	}
+
+	j, err := h.newJob(r.Context(), &Project{Owner: owner, Repo: repo}, trigger{Name: "Config"})
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if len(j.missingPermissions) > 0 {
+		redirectError(w, r, "/config", fmt.Sprintf("Goreadme can't open a PR without write permission for: %s",
+			strings.Join(j.missingPermissions, ", ")))
+		return
+	}
+	prNum, err := j.commitConfig(r.Context(), content)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	logrus.Infof("User %s opened config PR %s/%s#%d", data.User.GetLogin(), owner, repo, prNum)
+	http.Redirect(w, r, fmt.Sprintf("https://github.com/%s/%s/pull/%d", owner, repo, prNum), http.StatusFound)
+}
+
+// configFromForm returns the options that were set in the generator form, by their
+// goreadme.json names. Options that were not set are omitted, so they keep their defaults
+// and don't override the account configuration.
+func configFromForm(r *http.Request) map[string]interface{} {
+	c := make(map[string]interface{})
+	for _, name := range []string{"functions", "skip_examples", "skip_sub_packages", "recursive_sub_packages", "normalize_readme", "ignore_whitespace"} {
+		if r.FormValue(name) == "on" {
+			c[name] = true
+		}
+	}
+	badges := make(map[string]bool)
+	for _, name := range []string{"goreadme", "travis_ci", "code_cov", "golang_ci", "go_doc", "go_report_card"} {
+		if r.FormValue("badges."+name) == "on" {
+			badges[name] = true
+		}
+	}
+	if len(badges) > 0 {
+		c["badges"] = badges
+	}
+	for _, name := range []string{"header_file", "footer_file", "package_dir", "pr_strategy"} {
+		if v := r.FormValue(name); v != "" {
+			c[name] = v
+		}
+	}
+	for _, name := range []string{"min_change", "closes_issue"} {
+		if v, _ := strconv.Atoi(r.FormValue(name)); v != 0 {
+			c[name] = v
+		}
+	}
+	sections := strings.FieldsFunc(r.FormValue("sections"), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(sections) > 0 {
+		c["sections"] = sections
+	}
+	return c
+}
+
+// splitFullName splits a repository full name to its owner and name.
+func splitFullName(fullName string) (owner, repo string) {
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) != 2 {
+		return "", fullName
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigFromForm(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+		want string
+	}{
+		{
+			name: "empty",
+			form: url.Values{"min_change": {"0"}, "closes_issue": {"0"}, "pr_strategy": {""}},
+			want: `{}`,
+		},
+		{
+			name: "options",
+			form: url.Values{
+				"functions":        {"on"},
+				"badges.go_doc":    {"on"},
+				"header_file":      {"docs/header.md"},
+				"min_change":       {"10"},
+				"closes_issue":     {"0"},
+				"sections":         {"badges, description,usage"},
+				"pr_strategy":      {"amend"},
+				"normalize_readme": {""},
+			},
+			want: `{"badges":{"go_doc":true},"functions":true,"header_file":"docs/header.md","min_change":10,"pr_strategy":"amend","sections":["badges","description","usage"]}`,
+		},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/config", strings.NewReader(tt.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		got, err := json.Marshal(configFromForm(r))
+		if err != nil {
+			t.Fatalf("%s: Marshal: %s", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+		// The generated configuration is a valid goreadme.json.
+		var c config
+		if err := json.Unmarshal(got, &c); err != nil {
+			t.Errorf("%s: Unmarshal: %s", tt.name, err)
+		}
+	}
+}
+
+func TestConfigFromFormOptions(t *testing.T) {
+	r := httptest.NewRequest("POST", "/config", nil)
+	r.Form = url.Values{
+		"functions": {"on"}, "badges.travis_ci": {"on"}, "footer_file": {"f.md"}, "package_dir": {"src"},
+		"ignore_whitespace": {"on"}, "closes_issue": {"3"},
+	}
+	content, err := json.Marshal(configFromForm(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got config
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatal(err)
+	}
+	var want config
+	want.Functions = true
+	want.Badges.TravicCI = true
+	want.FooterFile = "f.md"
+	want.PackageDir = "src"
+	want.IgnoreWhitespace = true
+	want.ClosesIssue = 3
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got config %+v, want %+v", got, want)
+	}
+}
+
+func TestCommitConfig(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+
+	j := &Job{github: gh.Client(), log: logrus.New()}
+	j.Owner, j.Repo, j.DefaultBranch = "posener", "hello", "master"
+	j.HeadSHA = gh.HeadSHA("posener", "hello", "master")
+
+	content := []byte("{\"functions\": true}\n")
+	num, err := j.commitConfig(context.Background(), content)
+	if err != nil {
+		t.Fatalf("commitConfig: %s", err)
+	}
+	prs := gh.Pulls("posener", "hello")
+	if len(prs) != 1 || prs[0].GetNumber() != num {
+		t.Fatalf("Got PRs %+v, want #%d", prs, num)
+	}
+	if got := prs[0].GetHead().GetRef(); got != configBranch {
+		t.Errorf("Config PR is from branch %s, want %s", got, configBranch)
+	}
+	if got := prs[0].GetTitle(); got != "goreadme: Add configuration" {
+		t.Errorf("Config PR title = %q", got)
+	}
+	if got, _ := gh.File("posener", "hello", configBranch, configPath); got != string(content) {
+		t.Errorf("Config file = %q, want %q", got, content)
+	}
+	if _, ok := gh.File("posener", "hello", goreadmeBranch, configPath); ok {
+		t.Error("Config was committed to the goreadme branch")
+	}
+
+	// Committing the config again updates the open pull request.
+	j.branch = ""
+	again, err := j.commitConfig(context.Background(), []byte("{}\n"))
+	if err != nil {
+		t.Fatalf("commitConfig: %s", err)
+	}
+	if again != num || len(gh.Pulls("posener", "hello")) != 1 {
+		t.Errorf("Got PR #%d of %d PRs, want the existing #%d", again, len(gh.Pulls("posener", "hello")), num)
+	}
+	if got, _ := gh.File("posener", "hello", configBranch, configPath); got != "{}\n" {
+		t.Errorf("Config file = %q, want the updated config", got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// debug runs the server flows locally, for development, and returns the process exit code.
+// Run a job on a repository with:
+//
+// 	go run . debug -repo owner/repo [-head SHA]
+//
+// Or, replay a saved Github webhook payload on the hook handler, and wait for the jobs that
+// it triggered, with:
+//
+// 	go run . debug [-repo owner/test-repo] payload.json
+//
+// When replaying a payload, the -repo flag replaces the repository of the payload, so real
+// payloads can be tested against a test repository.
+func (h *handler) debug(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("debug", flag.ExitOnError)
+	repo := fs.String("repo", "", "Repository full name, owner/repo.")
+	head := fs.String("head", "", "Head SHA of a job run. Defaults to the default branch head.")
+	fs.Parse(args)
+
+	var owner, name string
+	if *repo != "" {
+		parts := strings.Split(*repo, "/")
+		if len(parts) != 2 {
+			logrus.Errorf("Invalid repository %q, expected owner/repo", *repo)
+			return 2
+		}
+		owner, name = parts[0], parts[1]
+	}
+
+	logrus.Warnf("Debugging mode!")
+	switch fs.NArg() {
+	case 0:
+		if *repo == "" {
+			logrus.Errorf("Either -repo or a payload file must be given")
+			return 2
+		}
+		done, _, err := h.runJob(ctx, &Project{Owner: owner, Repo: name, HeadSHA: *head}, trigger{Name: "Debug", Manual: true})
+		if err != nil {
+			logrus.Errorf("Failed job: %s", err)
+			return 1
+		}
+		<-done
+	case 1:
+		err := h.replayHook(fs.Arg(0), owner, name)
+		if err != nil {
+			logrus.Errorf("Failed replaying hook: %s", err)
+			return 1
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
+
+// replayHook sends a saved webhook payload to the hook handler and waits for the jobs that
+// it triggered. If owner and repo are given, they replace the repository of the payload.
+func (h *handler) replayHook(path string, owner, repo string) error {
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if owner != "" {
+		payload, err = replaceRepo(payload, owner, repo)
+		if err != nil {
+			return errors.Wrap(err, "failed replacing payload repository")
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/github/hook", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hub-Signature", signPayload(payload, cfg.GithubHookSecret))
+	rec := httptest.NewRecorder()
+	h.hook(rec, req)
+	logrus.Infof("Hook responded with status %d %s", rec.Code, rec.Body.String())
+
+	h.queue.wait()
+	return nil
+}
+
+// signPayload returns the signature header value that Github sends with a payload.
+func signPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(payload)
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// replaceRepo replaces the repository in a webhook payload.
+func replaceRepo(payload []byte, owner, repo string) ([]byte, error) {
+	var m map[string]interface{}
+	err := json.Unmarshal(payload, &m)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := m["repository"].(map[string]interface{}); ok {
+		setRepo(r, owner, repo)
+	}
+	if repos, ok := m["repositories_added"].([]interface{}); ok {
+		for _, r := range repos {
+			if r, ok := r.(map[string]interface{}); ok {
+				setRepo(r, owner, repo)
+			}
+		}
+	}
+	return json.Marshal(m)
+}
+
+func setRepo(r map[string]interface{}, owner, repo string) {
+	r["name"] = repo
+	r["full_name"] = fmt.Sprintf("%s/%s", owner, repo)
+	if o, ok := r["owner"].(map[string]interface{}); ok {
+		o["login"] = owner
+		o["name"] = owner
+	}
+}
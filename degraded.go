@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// dbPingTimeout is the timeout of checking that the database is available.
+	dbPingTimeout = 2 * time.Second
+	// hookReplayInterval is the interval between replays of webhook payloads that were
+	// buffered while the database was unavailable.
+	hookReplayInterval = 30 * time.Second
+	// lastKnownExpiry is the duration that the last known projects of the public endpoints
+	// are kept, for serving them while the database is unavailable.
+	lastKnownExpiry = 24 * time.Hour
+	// degradedCacheControl prevents caching of responses that were served while the
+	// database was unavailable.
+	degradedCacheControl = "no-cache"
+)
+
+// hookBufferDir returns the directory of the buffered webhook payloads.
+func hookBufferDir() string {
+	if cfg.HookBufferDir != "" {
+		return cfg.HookBufferDir
+	}
+	return filepath.Join(os.TempDir(), "goreadme-hooks")
+}
+
+// dbAvailable returns whether the database responds.
+func (h *handler) dbAvailable(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+	if err := h.db.DB().PingContext(ctx); err != nil {
+		logrus.Warnf("Database is unavailable: %s", err)
+		return false
+	}
+	return true
+}
+
+// bufferHook stores a validated webhook payload while the database is unavailable, so it is
+// handled once the database is available again.
+func (h *handler) bufferHook(w http.ResponseWriter, r *http.Request, payload []byte) {
+	if h.hookBuffer == nil {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if err := h.hookBuffer.Save(github.DeliveryID(r), payload); err != nil {
+		logrus.Errorf("Failed buffering hook payload: %s", err)
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	logrus.Infof("Buffered hook delivery %s until the database is available", github.DeliveryID(r))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// replayHooksLoop handles the buffered webhook payloads once the database is available,
+// until the context is done.
+func (h *handler) replayHooksLoop(ctx context.Context) {
+	if h.hookBuffer == nil {
+		return
+	}
+	t := time.NewTicker(hookReplayInterval)
+	defer t.Stop()
+	for {
+		h.replayHooks(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// replayHooks handles the buffered webhook payloads if the database is available.
+func (h *handler) replayHooks(ctx context.Context) {
+	n, err := h.hookBuffer.Len()
+	if err != nil {
+		logrus.Errorf("Failed checking buffered hooks: %s", err)
+		return
+	}
+	if n == 0 || !h.dbAvailable(ctx) {
+		return
+	}
+	logrus.Infof("Replaying %d buffered hook payloads", n)
+	replayed, err := h.hookBuffer.Replay(func(payload []byte) error {
+		return h.handleHook(ctx, payload)
+	})
+	if err != nil {
+		logrus.Errorf("Failed replaying buffered hooks, %d were replayed: %s", replayed, err)
+	}
+}
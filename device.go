@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/auth"
+	"github.com/posener/goreadme-server/internal/plans"
+	"github.com/sirupsen/logrus"
+)
+
+// deviceCode starts a Github device authorization flow for a CLI client.
+func (h *handler) deviceCode(w http.ResponseWriter, r *http.Request) {
+	code, err := h.auth.StartDevice(r.Context())
+	if err != nil {
+		logrus.Errorf("Failed starting device flow: %s", err)
+		apiError(w, http.StatusBadGateway, "failed starting device flow")
+		return
+	}
+	writeJSON(w, http.StatusOK, code)
+}
+
+// deviceToken completes a Github device authorization flow, and issues an API token to
+// the user. Until the user authorizes the device, it responds with the device flow error,
+// such as "authorization_pending", which the client should poll again on.
+func (h *handler) deviceToken(w http.ResponseWriter, r *http.Request) {
+	deviceCode := r.FormValue("device_code")
+	if deviceCode == "" {
+		apiError(w, http.StatusBadRequest, "missing device_code")
+		return
+	}
+	user, err := h.auth.PollDevice(r.Context(), deviceCode)
+	if err != nil {
+		if derr, ok := errors.Cause(err).(*auth.DeviceError); ok {
+			writeJSON(w, http.StatusBadRequest, derr)
+			return
+		}
+		logrus.Errorf("Failed polling device flow: %s", err)
+		apiError(w, http.StatusBadGateway, "failed polling device flow")
+		return
+	}
+	login := user.GetLogin()
+
+	// Check the API tokens limit of the plan of the user installation.
+	plan := plans.Free
+	if install, err := h.github.Installation(r.Context(), login); err == nil {
+		plan, err = h.plans.Of(int64(install.ID))
+		if err != nil {
+			logrus.Errorf("Failed getting plan of %s: %s", login, err)
+			apiError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+	used, err := h.tokens.Count(login)
+	if err != nil {
+		logrus.Errorf("Failed counting tokens of %s: %s", login, err)
+		apiError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if err := plan.CheckAPITokens(used); err != nil {
+		apiError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		name = "CLI"
+	}
+	token, err := h.tokens.Issue(login, name)
+	if err != nil {
+		logrus.Errorf("Failed issuing token: %s", err)
+		apiError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	logrus.Infof("Issued API token to %s", login)
+	writeJSON(w, http.StatusOK, map[string]string{"token": token, "login": login})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+// settingForkRuns is the project setting key that enables automatic runs on a fork when set
+// to "on". Forks usually keep the readme of their parent repository, so they are skipped by
+// default.
+const settingForkRuns = "fork_runs"
+
+// skipFork skips a job that was not triggered by a user on a fork, unless automatic runs
+// were enabled for it. It returns whether the job was skipped.
+func (h *handler) skipFork(j *Job, t trigger) (bool, error) {
+	if j.ForkOf == "" || t.Manual {
+		return false, nil
+	}
+	prefs, err := h.settings.Project(j.Owner, j.Repo)
+	if err != nil {
+		j.log.Errorf("Failed getting settings, assuming fork runs are off: %s", err)
+	}
+	if prefs[settingForkRuns] == "on" {
+		return false, nil
+	}
+	err = j.skip(status.Skipped, "Repository is a fork of %s, automatic runs are off for forks. "+
+		"Run goreadme manually, or turn on fork runs in the project settings", j.ForkOf)
+	return true, err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/posener/goreadme-server/internal/flags"
+)
+
+// goDir returns the directory of the package that the root readme is generated from, and
+// whether it was detected. The package_dir option sets the directory explicitly. Otherwise,
+// if the root directory has no Go module and no Go files, such as in repositories that are
+// mostly in other languages, and the module_detection flag is enabled for the installation,
+// the directory of the shallowest Go module is detected. The root directory is returned as
+// an empty string.
+func (j *Job) goDir(ctx context.Context, cfg config) (dir string, detected bool) {
+	if cfg.PackageDir != "" {
+		return strings.Trim(path.Clean("/"+cfg.PackageDir), "/"), false
+	}
+	paths, err := j.repoPaths(ctx)
+	if err != nil {
+		j.log.Warnf("Failed getting repository paths, using the root package: %s", err)
+		return "", false
+	}
+	for p := range paths {
+		if !strings.Contains(p, "/") && (p == "go.mod" || path.Ext(p) == ".go") {
+			return "", false
+		}
+	}
+	if !j.flags.Enabled(flags.ModuleDetection, j.Install) {
+		return "", false
+	}
+	for p := range paths {
+		if path.Base(p) != "go.mod" || ignoredModuleDir(p) {
+			continue
+		}
+		d := path.Dir(p)
+		if !detected || depth(d) < depth(dir) || (depth(d) == depth(dir) && d < dir) {
+			dir, detected = d, true
+		}
+	}
+	if detected {
+		j.log.Infof("Detected Go module in %s", dir)
+	}
+	return dir, detected
+}
+
+// ignoredModuleDir returns whether a go.mod path is in a directory that the go tool
+// ignores.
+func ignoredModuleDir(p string) bool {
+	for _, part := range strings.Split(path.Dir(p), "/") {
+		if part == "vendor" || part == "testdata" || strings.HasPrefix(part, ".") || strings.HasPrefix(part, "_") {
+			return true
+		}
+	}
+	return false
+}
+
+// depth returns the number of directories in a path.
+func depth(dir string) int {
+	return strings.Count(dir, "/")
+}
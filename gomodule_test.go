@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/posener/goreadme-server/internal/flags"
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestGoDir(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	f := flags.New(db)
+	if err := f.Set(flags.ModuleDetection, testInstall, true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		paths        []string
+		packageDir   string
+		wantDir      string
+		wantDetected bool
+	}{
+		{name: "root module", paths: []string{"go.mod", "sub/go.mod"}},
+		{name: "root go file", paths: []string{"main.go", "sub/go.mod"}},
+		{name: "sub module", paths: []string{"README.md", "go/b/go.mod", "go/a/go.mod", "js/x/y/go.mod"}, wantDir: "go/a", wantDetected: true},
+		{name: "ignored module", paths: []string{"vendor/go.mod", "x/testdata/go.mod", ".git/go.mod", "src/go.mod"}, wantDir: "src", wantDetected: true},
+		{name: "no module", paths: []string{"README.md", "src/main.js"}},
+		{name: "package dir", paths: []string{"src/go.mod"}, packageDir: "/cmd/tool/", wantDir: "cmd/tool"},
+	}
+	for _, tt := range tests {
+		j := &Job{flags: f, log: logrus.New(), paths: make(map[string]bool)}
+		j.Install = testInstall
+		for _, p := range tt.paths {
+			j.paths[p] = true
+		}
+		dir, detected := j.goDir(context.Background(), config{PackageDir: tt.packageDir})
+		if dir != tt.wantDir || detected != tt.wantDetected {
+			t.Errorf("%s: goDir() = %q, %v, want %q, %v", tt.name, dir, detected, tt.wantDir, tt.wantDetected)
+		}
+	}
+}
+
+func TestGoDirFlagDisabled(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	f := flags.New(db)
+	// The flag is enabled for another installation only.
+	if err := f.Set(flags.ModuleDetection, testInstall+1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	j := &Job{flags: f, log: logrus.New(), paths: map[string]bool{"README.md": true, "src/go.mod": true}}
+	j.Install = testInstall
+	if dir, detected := j.goDir(context.Background(), config{}); dir != "" || detected {
+		t.Errorf("goDir() = %q, %v, want the root directory", dir, detected)
+	}
+}
@@ -5,23 +5,74 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/gorilla/mux"
+	"github.com/hako/durafmt"
 	"github.com/jinzhu/gorm"
+	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/artifacts"
 	"github.com/posener/goreadme-server/internal/auth"
-	"github.com/posener/githubapp"
+	"github.com/posener/goreadme-server/internal/breaker"
+	"github.com/posener/goreadme-server/internal/events"
+	"github.com/posener/goreadme-server/internal/flags"
+	"github.com/posener/goreadme-server/internal/githubapp"
+	"github.com/posener/goreadme-server/internal/hookbuffer"
+	"github.com/posener/goreadme-server/internal/linkcheck"
+	"github.com/posener/goreadme-server/internal/notify"
+	"github.com/posener/goreadme-server/internal/plans"
+	"github.com/posener/goreadme-server/internal/repohooks"
+	"github.com/posener/goreadme-server/internal/report"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/settings"
+	"github.com/posener/goreadme-server/internal/status"
 	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/posener/goreadme-server/internal/tokens"
+	"github.com/posener/goreadme-server/internal/usage"
 	"github.com/sirupsen/logrus"
 )
 
+// manualRunCooldown is the minimal duration between manual runs of a user on the same
+// repository.
+const manualRunCooldown = 2 * time.Minute
+
 type handler struct {
-	auth   *auth.Auth
-	db     *gorm.DB
-	github *githubapp.App
+	auth      *auth.Auth
+	db        *gorm.DB
+	replica   *gorm.DB // Read-only queries of the dashboard.
+	github    *githubapp.App
+	events    *events.Hub
+	flags     *flags.Flags
+	cooldowns *cache.Cache // Recent manual runs of users.
+	apiLimits *cache.Cache // API requests of clients in the current rate limit window.
+	// orgConfigs caches the account goreadme.json of installations, projects caches the
+	// projects of the public endpoints, and lastKnown keeps them for longer, for serving
+	// them while the database is unavailable.
+	orgConfigs *cache.Cache
+	projects   *cache.Cache
+	lastKnown  *cache.Cache
+	queue      *queue
+	settings   *settings.Settings
+	notify     *notify.Registry
+	report     report.Reporter
+	plans      *plans.Plans
+	artifacts  *artifacts.Store
+	tokens     *tokens.Tokens
+	breaker    *breaker.Breaker
+	links      *linkcheck.Checker
+	roles      *roles.Resolver
+	repoHooks  *repohooks.Hooks
+	// mode is the process mode, jobs are left for workers in modeWeb, and hookBuffer keeps
+	// hook payloads that arrived while the database was unavailable.
+	mode       string
+	hookBuffer *hookbuffer.Buffer
+	// backfilling is set while a backfill that was started by an admin runs, and
+	// apiRateLimit is the current limit of API requests of a client in a window.
+	backfilling  int32
+	apiRateLimit int32
 }
 
 type templateData struct {
@@ -31,16 +82,53 @@ type templateData struct {
 	Projects  []Project
 	Jobs      []Job
 	Stats     stats
+	Flags     []flags.Flag
+	// Project is the project of the project settings page.
+	Project *Project
+	// Settings are the current project settings.
+	Settings  map[string]string
+	Notifiers []notifierSetting
+	// Badges are the badge snippets of the project, shown when scripts are disabled.
+	Badges []badgeSnippet
+	// ConfigOptions are the goreadme options of the project settings page.
+	ConfigOptions []configOption
+	// ConfigRepos are the repositories that a generated config can be added to.
+	ConfigRepos []*github.Repository
+	// Usage is the daily usage report of the installation.
+	Usage []usage.Day
+	// Plans are the stored installation plans, and AvailablePlans are all the plans, for
+	// the admin plans page.
+	Plans          []plans.Install
+	AvailablePlans []plans.Plan
+	// RuntimeSettings are the settings of the admin runtime page, and Build is the build of
+	// the server.
+	RuntimeSettings []runtimeSetting
+	Build           buildInfo
+	// Timezone is the preferred timezone of the user. The browser timezone is used if it is
+	// empty.
+	Timezone string
+	// Admin is true if the user is a server admin.
+	Admin bool
+	// Account is the installation account that the user acts on, and Role is the role of
+	// the user in its installation.
+	Account string
+	Role    roles.Role
+	// TeamRoles are the team roles of the installation settings page, one "team=role" in a
+	// line.
+	TeamRoles string
+	// MissingPermissions are required permissions that were not granted to the user
+	// installation.
+	MissingPermissions []string
+	// RepoHooks are the repository webhooks of the user, and RepoHookURL is the payload URL
+	// that they are sent to.
+	RepoHooks   []repoHookRow
+	RepoHookURL string
+	// Import is the repository of the import page, if it is not installed.
+	Import *importResult
 	// Holds an error that happened to show to the user
 	Error string
 }
 
-type stats struct {
-	// TopProjects will contain top open source projects
-	TopProjects   []Project
-	TotalProjects int
-}
-
 type contextKey string
 
 const contextClient contextKey = "client"
@@ -52,11 +140,29 @@ func (h *handler) dataFromRequest(w http.ResponseWriter, r *http.Request) *templ
 	}
 	if data.User != nil {
 		login := data.User.GetLogin()
-		userClient, err := h.github.Installation(r.Context(), login)
+		data.Admin = isAdmin(login)
+		data.Account = login
+		if c, err := r.Cookie(accountCookie); err == nil && c.Value != "" {
+			data.Account = c.Value
+		}
+		prefs, err := h.settings.Installation(data.Account)
 		if err != nil {
-			logrus.Warnf("Failed getting install ID for login %s: %s", login, err)
+			logrus.Warnf("Failed getting settings of %s: %s", data.Account, err)
 		} else {
+			data.Timezone = prefs[settingTimezone]
+		}
+		userClient, err := h.github.Installation(r.Context(), data.Account)
+		if err != nil {
+			logrus.Warnf("Failed getting install ID for account %s: %s", data.Account, err)
+			return &data
+		}
+		data.Role, err = h.roles.Role(r.Context(), userClient.Github, data.Account, login, roles.Teams(prefs))
+		if err != nil {
+			logrus.Warnf("Failed getting role of %s in %s: %s", login, data.Account, err)
+		}
+		if data.Role.Allows(roles.Viewer) {
 			data.InstallID = userClient.ID
+			data.MissingPermissions = missingPermissions(userClient)
 			*r = *r.WithContext(context.WithValue(r.Context(), contextClient, userClient))
 		}
 	}
@@ -67,16 +173,10 @@ func (h *handler) home(w http.ResponseWriter, r *http.Request) {
 	data := h.dataFromRequest(w, r)
 	// nil user is valid here.
 
-	err := h.db.Model(&Project{}).Where("private = FALSE").Order("stars DESC").Limit(10).Scan(&data.Stats.TopProjects).Error
+	var err error
+	data.Stats, err = loadStats(h.replica)
 	if err != nil {
-		logrus.Errorf("Failed scanning open source projects: %s", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	err = h.db.Model(&Project{}).Count(&data.Stats.TotalProjects).Error
-	if err != nil {
-		logrus.Errorf("Failed counting projects: %s", err)
+		logrus.Errorf("Failed loading stats: %s", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -95,9 +195,9 @@ func (h *handler) projectsList(w http.ResponseWriter, r *http.Request) {
 
 	var wh where
 	wh.AddValues(r.URL.Query(), "owner", "repo", "id")
-	wh.Add("install", data.InstallID)
+	wh.AddIn("install", data.installs())
 
-	err := wh.Apply(h.db.Model(&Project{}).Order("updated_at DESC")).Scan(&data.Projects).Error
+	err := wh.Apply(h.replica.Model(&Project{}).Order("updated_at DESC")).Scan(&data.Projects).Error
 	if err != nil {
 		h.doError(w, r, errors.Wrap(err, "failed scanning projects"))
 		return
@@ -117,26 +217,53 @@ func (h *handler) jobsList(w http.ResponseWriter, r *http.Request) {
 
 	var wh where
 	wh.AddValues(r.URL.Query(), "owner", "repo", "id")
-	wh.Add("install", data.InstallID)
+	wh.AddIn("install", data.installs())
 
-	err := wh.Apply(h.db.Model(&Job{}).Order("updated_at DESC")).Scan(&data.Jobs).Error
+	err := wh.Apply(h.replica.Model(&Job{}).Order("updated_at DESC")).Scan(&data.Jobs).Error
 	if err != nil {
 		h.doError(w, r, errors.Wrap(err, "failed scanning jobs"))
 		return
 	}
+	for i := range data.Jobs {
+		j := &data.Jobs[i]
+		if j.Status == status.Queued {
+			j.QueuePosition, j.EstimatedStart = h.queue.position(j.Owner, j.Repo, j.Num)
+		}
+	}
 	err = templates.JobsList.Execute(w, data)
 	if err != nil {
 		h.doError(w, r, errors.Wrap(err, "failed executing template"))
 	}
 }
 
-func (h *handler) addRepo(w http.ResponseWriter, r *http.Request) {
+// usageReportDays is the number of days in the usage report.
+const usageReportDays = 30
+
+func (h *handler) usageReport(w http.ResponseWriter, r *http.Request) {
 	data := h.dataFromRequest(w, r)
 	if data.User == nil {
 		return
 	}
 
-	c, err := h.github.Installation(r.Context(), data.User.GetLogin())
+	var err error
+	data.Usage, err = usage.Report(h.replica, int64(data.InstallID), usageReportDays)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	err = templates.Usage.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+func (h *handler) addRepo(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil || !requireRole(w, data, roles.Operator) {
+		return
+	}
+
+	c, err := h.github.Installation(r.Context(), data.Account)
 	if err != nil {
 		h.doError(w, r, errors.Wrap(err, "get installation client"))
 		return
@@ -155,7 +282,7 @@ func (h *handler) addRepo(w http.ResponseWriter, r *http.Request) {
 
 func (h *handler) addRepoAction(w http.ResponseWriter, r *http.Request) {
 	data := h.dataFromRequest(w, r)
-	if data.User == nil {
+	if data.User == nil || !requireRole(w, data, roles.Operator) {
 		return
 	}
 
@@ -164,28 +291,81 @@ func (h *handler) addRepoAction(w http.ResponseWriter, r *http.Request) {
 		repo  = r.FormValue("repo")
 	)
 
+	installed, err := h.installedRepo(r, owner, repo)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if !installed {
+		logrus.Warnf("User %s tried to run on not installed repository %s/%s", data.User.GetLogin(), owner, repo)
+		redirectError(w, r, "/add", fmt.Sprintf("Repository %s/%s is not installed", owner, repo))
+		return
+	}
+	h.manualRun(w, r, data, owner, repo, trigger{Name: "Manual", Manual: true})
+}
+
+// manualRun runs a job that a user triggered on an installed repository, and redirects to
+// the job.
+func (h *handler) manualRun(w http.ResponseWriter, r *http.Request, data *templateData, owner, repo string, t trigger) {
+	// Allow only one manual run of a user on a repository in a cooldown period.
+	key := data.User.GetLogin() + ":" + owner + "/" + repo
+	if err := h.cooldowns.Add(key, true, manualRunCooldown); err != nil {
+		_, expires, _ := h.cooldowns.GetWithExpiration(key)
+		redirectError(w, r, "/projects", fmt.Sprintf(
+			"Goreadme was recently run on %s/%s, please try again in %s",
+			owner, repo, durafmt.ParseShort(time.Until(expires).Round(time.Second))))
+		return
+	}
+
 	logrus.Info("Running goreadme in background...")
 	_, jobNum, err := h.runJob(r.Context(), &Project{
 		Owner:   owner,
 		Repo:    repo,
 		Install: int64(data.InstallID),
-	}, "Manual")
+	}, t)
 	if err != nil {
+		// The job did not run, so the user can try again.
+		h.cooldowns.Delete(key)
 		h.doError(w, r, err)
 		return
 	}
 	http.Redirect(w, r, fmt.Sprintf("/jobs?owner=%s&repo=%s&num=%d", owner, repo, jobNum), http.StatusFound)
 }
 
+// installedRepo returns whether a repository is accessible by the installation of the
+// user that sent the request.
+func (h *handler) installedRepo(r *http.Request, owner, repo string) (bool, error) {
+	install, ok := r.Context().Value(contextClient).(*githubapp.Installation)
+	if !ok {
+		return false, nil
+	}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		repos, resp, err := install.Github.Apps.ListRepos(r.Context(), opt)
+		if err != nil {
+			return false, errors.Wrap(err, "failed listing installation repos")
+		}
+		for _, r := range repos {
+			if r.GetOwner().GetLogin() == owner && r.GetName() == repo {
+				return true, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
 func (h *handler) badge(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	owner := vars["owner"]
 	repo := vars["repo"]
 
-	var p Project
-	err := h.db.Model(&p).Where("owner = ? AND repo = ?", owner, repo).First(&p).Error
+	p, _, err := h.cachedProject(owner, repo)
 	if err != nil {
-		logrus.Errorf("Failed getting project %s/%s", owner, repo)
+		logrus.Error(err)
+		w.Header().Set("Cache-Control", degradedCacheControl)
 	}
 
 	w.Header().Add("Content-Type", "image/svg+xml")
@@ -196,33 +376,47 @@ func (h *handler) badge(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *handler) doError(w http.ResponseWriter, r *http.Request, err error) {
-	logrus.Error(err)
-	http.Redirect(w, r, "/?error=internal%20server%error", http.StatusFound)
+func (h *handler) qualityBadge(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	owner := vars["owner"]
+	repo := vars["repo"]
+
+	p, _, err := h.cachedProject(owner, repo)
+	if err != nil {
+		logrus.Error(err)
+		w.Header().Set("Cache-Control", degradedCacheControl)
+	}
+
+	w.Header().Add("Content-Type", "image/svg+xml")
+
+	err = templates.QualityBadge.Execute(w, &p)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
 }
 
-// debugPR runs in debug mode provide the required environment variables.
-// Run with:
-//
-// 		DEBUG_HOOK=1 REPO=repo OWNER=$USER HEAD=$(git rev-parse HEAD) go run .
-//
-func (h *handler) debugPR() {
-	if os.Getenv("DEBUG_HOOK") != "1" {
+// liveEvents streams job state changes of the user installation.
+func (h *handler) liveEvents(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
 		return
 	}
-	logrus.Warnf("Debugging hook mode!")
-	done, _, err := h.runJob(context.Background(), &Project{
-		Owner:         os.Getenv("OWNER"),
-		Repo:          os.Getenv("REPO"),
-		HeadSHA:       os.Getenv("HEAD"),
-		DefaultBranch: "master",
-	}, "Debug")
-	if err != nil {
-		logrus.Errorf("Failed job: %s", err)
-		os.Exit(1)
+	if data.InstallID == 0 {
+		http.Error(w, "No installation", http.StatusNotFound)
+		return
 	}
-	<-done
-	os.Exit(0)
+	h.events.Serve(w, r, int64(data.InstallID))
+}
+
+// redirectError redirects to a path and shows an error message to the user.
+func redirectError(w http.ResponseWriter, r *http.Request, path string, msg string) {
+	http.Redirect(w, r, path+"?"+url.Values{"error": {msg}}.Encode(), http.StatusFound)
+}
+
+func (h *handler) doError(w http.ResponseWriter, r *http.Request, err error) {
+	logrus.Error(err)
+	h.report.Report(err, map[string]string{"path": r.URL.Path})
+	http.Redirect(w, r, "/?error=internal%20server%error", http.StatusFound)
 }
 
 func branchOfRef(ref string) string {
@@ -249,6 +443,13 @@ func (w *where) Add(key string, val interface{}) *where {
 	return w
 }
 
+// AddIn adds a condition that the key is one of the values.
+func (w *where) AddIn(key string, vals interface{}) *where {
+	w.strs = append(w.strs, fmt.Sprintf("%s IN (?)", key))
+	w.args = append(w.args, vals)
+	return w
+}
+
 func (w *where) Apply(db *gorm.DB) *gorm.DB {
 	return db.Where(strings.Join(w.strs, " AND "), w.args...)
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestManualRunCooldown(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+	h.report = &reports{}
+
+	data := &templateData{User: &github.User{Login: github.String("posener")}, InstallID: testInstall}
+	run := func(repo string) string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/add", nil)
+		h.manualRun(w, r, data, "posener", repo, trigger{Name: "Manual", Manual: true})
+		h.queue.wait()
+		return w.Header().Get("Location")
+	}
+
+	// A failed run does not start the cooldown.
+	for i := 0; i < 2; i++ {
+		if got := run("missing"); strings.Contains(got, "recently") {
+			t.Fatalf("Run %d of a failing job got cooldown redirect %s", i, got)
+		}
+	}
+
+	if got := run("hello"); !strings.HasPrefix(got, "/jobs?") {
+		t.Fatalf("Got redirect %s, want the job", got)
+	}
+	if got := run("hello"); !strings.Contains(got, "recently") {
+		t.Errorf("Got redirect %s, want the cooldown error", got)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/posener/goreadme-server/internal/quality"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+// projectHealth is a summary of the state of a project, for external dashboards.
+type projectHealth struct {
+	Owner   string        `json:"owner"`
+	Repo    string        `json:"repo"`
+	Status  status.Status `json:"status"`
+	LastJob *healthJob    `json:"last_job"`
+	// Docs are the readme quality checks of the last generated readme.
+	Docs     healthDocs `json:"docs"`
+	PR       *healthPR  `json:"pr"`
+	Warnings int        `json:"warnings"`
+}
+
+type healthJob struct {
+	Num       int           `json:"num"`
+	Status    status.Status `json:"status"`
+	Trigger   string        `json:"trigger"`
+	Message   string        `json:"message"`
+	HeadSHA   string        `json:"head_sha"`
+	Duration  float64       `json:"duration_seconds"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type healthDocs struct {
+	Quality int             `json:"quality"`
+	Checks  map[string]bool `json:"checks,omitempty"`
+}
+
+type healthPR struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	// State is the state of the pull request in Github, "open" or "closed", or empty if it
+	// could not be fetched.
+	State     string     `json:"state,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Stale     bool       `json:"stale"`
+}
+
+// projectHealthAPI returns the health of a project. The user of the API token must have
+// access to the installation of the project.
+func (h *handler) projectHealthAPI(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	owner, repo := vars["owner"], vars["repo"]
+	login := r.Context().Value(contextLogin).(string)
+
+	var p Project
+	query := h.replica.Where("owner = ? AND repo = ?", owner, repo).First(&p)
+	switch {
+	case query.RecordNotFound():
+		apiError(w, http.StatusNotFound, "project not found")
+		return
+	case query.Error != nil:
+		logrus.Errorf("Failed getting project %s/%s: %s", owner, repo, query.Error)
+		apiError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	install, err := h.github.Installation(r.Context(), owner)
+	if err != nil {
+		logrus.Errorf("Failed getting installation of %s: %s", owner, err)
+		apiError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	prefs, err := h.settings.Installation(owner)
+	if err != nil {
+		logrus.Errorf("Failed getting settings of %s: %s", owner, err)
+		apiError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	role, err := h.roles.Role(r.Context(), install.Github, owner, login, roles.Teams(prefs))
+	if err != nil {
+		logrus.Errorf("Failed getting role of %s in %s: %s", login, owner, err)
+		apiError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if !role.Allows(roles.Viewer) {
+		// Don't reveal that the project exists.
+		apiError(w, http.StatusNotFound, "project not found")
+		return
+	}
+
+	health := projectHealth{
+		Owner:  p.Owner,
+		Repo:   p.Repo,
+		Status: p.Status,
+		Docs:   healthDocs{Quality: p.Quality},
+	}
+
+	var j Job
+	query = h.replica.Where("owner = ? AND repo = ?", owner, repo).Order("num DESC").First(&j)
+	switch {
+	case query.RecordNotFound():
+	case query.Error != nil:
+		logrus.Errorf("Failed getting last job of %s/%s: %s", owner, repo, query.Error)
+		apiError(w, http.StatusInternalServerError, "internal server error")
+		return
+	default:
+		health.LastJob = &healthJob{
+			Num:       j.Num,
+			Status:    j.Status,
+			Trigger:   j.Trigger,
+			Message:   j.Message,
+			HeadSHA:   j.HeadSHA,
+			Duration:  j.Duration.Seconds(),
+			CreatedAt: j.CreatedAt,
+		}
+		if j.Warnings != "" {
+			health.Warnings = len(strings.Split(j.Warnings, "\n"))
+		}
+	}
+
+	if content, found, err := h.artifacts.Latest(owner, repo); err != nil {
+		logrus.Warnf("Failed getting latest readme of %s/%s: %s", owner, repo, err)
+	} else if found {
+		_, checks := quality.Score(content)
+		health.Docs.Checks = make(map[string]bool, len(checks))
+		for _, c := range checks {
+			health.Docs.Checks[c.Name] = c.Passed
+		}
+	}
+
+	if p.PR != 0 {
+		health.PR = &healthPR{
+			Number:    p.PR,
+			URL:       "https://github.com/" + owner + "/" + repo + "/pull/" + strconv.Itoa(p.PR),
+			CreatedAt: p.PRCreatedAt,
+			Stale:     p.StalePR(),
+		}
+		pr, _, err := install.Github.PullRequests.Get(r.Context(), owner, repo, p.PR)
+		if err != nil {
+			logrus.Warnf("Failed getting PR %s/%s#%d: %s", owner, repo, p.PR, err)
+		} else {
+			health.PR.State = pr.GetState()
+			health.PR.Stale = health.PR.Stale && pr.GetState() == "open"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, health)
+}
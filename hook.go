@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/google/go-github/github"
 	"github.com/pkg/errors"
 	"github.com/posener/goreadme"
+	"github.com/posener/goreadme-server/internal/metrics"
+	"github.com/posener/goreadme-server/internal/plans"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/posener/goreadme-server/internal/usage"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,56 +26,124 @@ func (h *handler) hook(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if !h.dbAvailable(r.Context()) {
+		h.bufferHook(w, r, payload)
+		return
+	}
+	if err := h.handleHook(r.Context(), payload); err != nil {
+		logrus.Error(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
 
-	// Handle different events
+// handleHook handles a validated payload of the Github app webhook.
+func (h *handler) handleHook(ctx context.Context, payload []byte) error {
 	if e := tryPush(payload); e != nil {
 		logrus.Info("Push hook triggered")
-		branch := branchOfRef(e.GetRef())
-		if branch != e.GetRepo().GetDefaultBranch() {
-			logrus.Infof("Skipping push to non default branch %q", branch)
-			return
-		}
 		if e.GetInstallation().GetAppID() == int64(cfg.GithubAppID) {
 			logrus.Infof("Skipping self push")
-			return
+			return nil
 		}
-		h.runJob(r.Context(), &Project{
-			Install: e.GetInstallation().GetID(),
-			Owner:   e.GetRepo().GetOwner().GetName(),
-			Repo:    e.GetRepo().GetName(),
-			HeadSHA: e.GetHeadCommit().GetID(),
-		}, fmt.Sprintf("Push to %s", branch))
+		return h.push(ctx, e, e.GetInstallation().GetID())
 	} else if e := tryInstall(payload); e != nil {
 		logrus.Infof("Install hook triggered added=%d removed=%d", len(e.RepositoriesAdded), len(e.RepositoriesRemoved))
 		for _, repo := range e.RepositoriesRemoved {
 			logrus.Infof("Removed of %s", repo.GetFullName())
 		}
+		// A failure of one repository does not prevent running the jobs of the others.
+		var failed []string
 		for _, repo := range e.RepositoriesAdded {
 			parts := strings.Split(repo.GetFullName(), "/")
-			h.runJob(r.Context(), &Project{
+			if !h.hookRepoAllowed(parts[0], parts[1]) {
+				continue
+			}
+			_, _, err := h.runJob(ctx, &Project{
 				Install: e.GetInstallation().GetID(),
 				Owner:   parts[0],
 				Repo:    parts[1],
-			}, "New Install")
+			}, trigger{Name: "New Install"})
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", repo.GetFullName(), err))
+			}
+		}
+		if len(failed) > 0 {
+			return errors.Errorf("failed running jobs of new install: %s", strings.Join(failed, "; "))
 		}
 	} else if e := tryPullRequest(payload); e != nil {
 		if e.GetAction() != "closed" || !e.GetPullRequest().GetMerged() {
 			logrus.Info("Skipping non-merge PR")
-			return
+			return nil
 		}
 		if ref := e.GetPullRequest().GetBase().GetRef(); ref != e.GetRepo().GetDefaultBranch() {
 			logrus.Infof("Skipping merge to non-default branch: %s", ref)
-			return
+			return nil
 		}
-		h.runJob(r.Context(), &Project{
+		if !h.hookRepoAllowed(e.GetRepo().GetOwner().GetLogin(), e.GetRepo().GetName()) {
+			return nil
+		}
+		_, _, err := h.runJob(ctx, &Project{
 			Install:       e.GetInstallation().GetID(),
 			Owner:         e.GetRepo().GetOwner().GetLogin(),
 			Repo:          e.GetRepo().GetName(),
 			DefaultBranch: e.GetRepo().GetDefaultBranch(),
-		}, fmt.Sprintf("PR#%d", e.GetPullRequest().GetNumber()))
+		}, trigger{Name: fmt.Sprintf("PR#%d", e.GetPullRequest().GetNumber())})
+		if err != nil {
+			return errors.Wrapf(err, "failed running job of merged PR of %s", e.GetRepo().GetFullName())
+		}
+	} else if e := trySuspend(payload); e != nil {
+		id := e.GetInstallation().GetID()
+		suspended := e.GetAction() == "suspend"
+		logrus.Infof("Install %d suspended=%v", id, suspended)
+		err := setSuspended(h.db, id, e.GetInstallation().GetAccount().GetLogin(), suspended)
+		if err != nil {
+			return errors.Wrapf(err, "failed updating install %d suspension", id)
+		}
+		if !suspended {
+			h.resumeInstallation(ctx, id)
+		}
+	} else if e := tryPing(payload); e != nil {
+		logrus.Infof("Ping hook: %s", e.GetZen())
 	} else {
 		logrus.Warnf("Got unexpected payload: %s", string(payload))
 	}
+	return nil
+}
+
+// push runs a job for a push to the default branch of a repository of an installation.
+func (h *handler) push(ctx context.Context, e *github.PushEvent, install int64) error {
+	branch := branchOfRef(e.GetRef())
+	if branch != e.GetRepo().GetDefaultBranch() {
+		logrus.Infof("Skipping push to non default branch %q", branch)
+		return nil
+	}
+	owner, repo := e.GetRepo().GetOwner().GetName(), e.GetRepo().GetName()
+	if repo == orgConfigRepo {
+		// The account configuration might have changed.
+		h.orgConfigs.Delete(strconv.FormatInt(install, 10))
+	}
+	if !h.hookRepoAllowed(owner, repo) {
+		return nil
+	}
+	prefs, err := h.settings.Project(owner, repo)
+	if err != nil {
+		logrus.Errorf("Failed getting settings of %s/%s: %s", owner, repo, err)
+	}
+	if !pushChangesDocs(e, pushPaths(prefs)) {
+		logrus.Infof("Skipping push without doc changes to %s/%s", owner, repo)
+		return nil
+	}
+	_, _, err = h.runJob(ctx, &Project{
+		Install: install,
+		Owner:   owner,
+		Repo:    repo,
+		HeadSHA: e.GetHeadCommit().GetID(),
+	}, trigger{
+		Name:    fmt.Sprintf("Push to %s", branch),
+		Message: e.GetHeadCommit().GetMessage(),
+		Author:  e.GetHeadCommit().GetAuthor().GetName(),
+		Push:    true,
+	})
+	return errors.Wrapf(err, "failed running job of push to %s/%s", owner, repo)
 }
 
 func tryPush(payload []byte) *github.PushEvent {
@@ -80,7 +153,9 @@ func tryPush(payload []byte) *github.PushEvent {
 		logrus.Errorf("Failed decoding push event: %s", err)
 		return nil
 	}
-	if e.Repo == nil {
+	// Other events, such as pull request events, also have a repository, but only push
+	// events have the commit that the ref was pushed to.
+	if e.Repo == nil || e.After == nil {
 		return nil
 	}
 	return &e
@@ -112,36 +187,154 @@ func tryPullRequest(payload []byte) *github.PullRequestEvent {
 	return &e
 }
 
-func (h *handler) runJob(ctx context.Context, p *Project, trigger string) (done <-chan struct{}, jobNum int, err error) {
-	install, err := h.github.Installation(ctx, p.Owner)
+func trySuspend(payload []byte) *github.InstallationEvent {
+	var e github.InstallationEvent
+	err := json.Unmarshal(payload, &e)
 	if err != nil {
-		return nil, 0, errors.Wrap(err, "failed getting user client: %s")
+		logrus.Errorf("Failed decoding installation event: %s", err)
+		return nil
+	}
+	if action := e.GetAction(); action != "suspend" && action != "unsuspend" {
+		return nil
+	}
+	return &e
+}
+
+func tryPing(payload []byte) *github.PingEvent {
+	var e github.PingEvent
+	err := json.Unmarshal(payload, &e)
+	if err != nil {
+		logrus.Errorf("Failed decoding ping event: %s", err)
+		return nil
+	}
+	if e.Zen == nil {
+		return nil
+	}
+	return &e
+}
+
+// trigger describes the event that triggered a job.
+type trigger struct {
+	Name string
+	// Message and Author of the commit that triggered the job, if it was triggered by a push.
+	Message string
+	Author  string
+	// Push is true if the job was triggered by a push to the default branch.
+	Push bool
+	// Manual is true if the job was triggered by a user, and not by a change in the
+	// repository.
+	Manual bool
+}
+
+func (h *handler) runJob(ctx context.Context, p *Project, t trigger) (done <-chan struct{}, jobNum int, err error) {
+	suspended, err := isSuspended(h.db, p.Install)
+	if err != nil {
+		return nil, 0, err
+	}
+	if suspended {
+		// The installation credentials can't be used, record the job without running it.
+		j := &Job{
+			Project:        *p,
+			Trigger:        t.Name,
+			TriggerMessage: t.Message,
+			TriggerAuthor:  t.Author,
+			db:             h.db,
+			events:         h.events,
+			settings:       h.settings,
+			notify:         h.notify,
+			log:            logrus.WithField("repo", p.Owner+"/"+p.Repo),
+		}
+		if err := j.skip(status.Suspended, "Installation is suspended, the job will run once it is unsuspended"); err != nil {
+			return nil, 0, err
+		}
+		ch := make(chan struct{})
+		close(ch)
+		return ch, j.Num, nil
+	}
+
+	j, err := h.newJob(ctx, p, t)
+	if err != nil {
+		return nil, 0, err
+	}
+	if skipped, err := h.skipFork(j, t); err != nil {
+		return nil, 0, err
+	} else if skipped {
+		ch := make(chan struct{})
+		close(ch)
+		return ch, j.Num, nil
+	}
+	err = h.checkPlan(&j.Project)
+	switch err.(type) {
+	case nil:
+	case *plans.LimitError:
+		if err := j.skip(status.PlanLimit, "Upgrade the installation plan to run goreadme on this repository: %s", err); err != nil {
+			return nil, 0, err
+		}
+		ch := make(chan struct{})
+		close(ch)
+		return ch, j.Num, nil
+	default:
+		return nil, 0, err
+	}
+	if h.mode == modeWeb {
+		return j.enqueue()
+	}
+	return j.Run(h.queue)
+}
+
+// newJob returns a job for a project, with updated repository data.
+func (h *handler) newJob(ctx context.Context, p *Project, t trigger) (*Job, error) {
+	install, err := h.installation(ctx, p)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting user client")
 	}
 
 	repo, _, err := install.Github.Repositories.Get(ctx, p.Owner, p.Repo)
 	if err != nil {
-		return nil, 0, errors.Wrap(err, "failed getting repo data")
+		return nil, errors.Wrap(err, "failed getting repo data")
 	}
 	p.DefaultBranch = repo.GetDefaultBranch()
 	p.Private = repo.GetPrivate()
 	p.Stars = repo.GetStargazersCount()
+	p.ForkOf = repo.GetParent().GetFullName()
 
 	// Update Head SHA if was not given.
 	if p.HeadSHA == "" {
 		gitData, _, err := install.Github.Git.GetRef(ctx, p.Owner, p.Repo, "refs/heads/"+p.DefaultBranch)
 		if err != nil {
-			return nil, 0, errors.Wrap(err, "failed getting git data")
+			return nil, errors.Wrap(err, "failed getting git data")
 		}
 		p.HeadSHA = gitData.GetObject().GetSHA()
 	}
 
-	j := &Job{
-		Project:  *p,
-		Trigger:  trigger,
-		db:       h.db,
-		github:   install.Github,
-		goreadme: goreadme.New(install.Client),
-	}
-	done, jobNum = j.Run()
-	return done, jobNum, nil
+	// Count the API calls of the job, and record their latency.
+	apiCalls := &usage.Counter{Transport: metrics.Transport(h.breaker.Transport(install.Client.Transport))}
+	client := &http.Client{Transport: apiCalls}
+
+	return &Job{
+		Project:            *p,
+		Trigger:            t.Name,
+		TriggerMessage:     t.Message,
+		TriggerAuthor:      t.Author,
+		db:                 h.db,
+		github:             github.NewClient(client),
+		goreadme:           goreadme.New(client),
+		apiCalls:           apiCalls,
+		events:             h.events,
+		flags:              h.flags,
+		settings:           h.settings,
+		notify:             h.notify,
+		report:             h.report,
+		missingPermissions: missingPermissions(install),
+		artifacts:          h.artifacts,
+		orgConfigs:         h.orgConfigs,
+		push:               t.Push,
+		breaker:            h.breaker,
+		queue:              h.queue,
+		links:              h.links,
+		log: logrus.WithFields(logrus.Fields{
+			"sha":  shortSHA(p.HeadSHA),
+			"repo": p.Owner + "/" + p.Repo,
+		}),
+	}, nil
 }
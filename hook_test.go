@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/posener/goreadme-server/internal/artifacts"
+	"github.com/posener/goreadme-server/internal/breaker"
+	"github.com/posener/goreadme-server/internal/events"
+	"github.com/posener/goreadme-server/internal/flags"
+	"github.com/posener/goreadme-server/internal/githubapp"
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/hookbuffer"
+	"github.com/posener/goreadme-server/internal/linkcheck"
+	"github.com/posener/goreadme-server/internal/notify"
+	"github.com/posener/goreadme-server/internal/plans"
+	"github.com/posener/goreadme-server/internal/repohooks"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/settings"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/posener/goreadme-server/internal/tokens"
+)
+
+const (
+	testHookSecret = "hook-secret"
+	testAppID      = 1
+	testInstall    = 10
+)
+
+// testFiles are the files of the test repository.
+var testFiles = map[string]string{
+	"README.md":  "# hello\n",
+	"hello.go":   "// Package hello says hello to the world.\n//\n// It is used in the hook tests.\npackage hello\n\n// Hello returns a greeting.\nfunc Hello() string { return \"hello\" }\n",
+	"sub/sub.go": "// Package sub is a sub package of hello.\npackage sub\n",
+}
+
+// newTestHandler returns a handler that uses the test database and sends the Github requests
+// to the fake server. The test is skipped if there is no test database. The returned
+// function should be called when the test is done.
+func newTestHandler(t *testing.T, gh *githubtest.Server) (*handler, func()) {
+	t.Helper()
+	db := githubtest.DB(t)
+
+	oldCfg := cfg
+	cfg.GithubHookSecret = testHookSecret
+	cfg.GithubAppID = testAppID
+	cfg.Domain = "http://goreadme.test"
+
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(pk)})
+	ghCfg := githubapp.Config{AppID: "1", PrivateKey: key}
+	app, err := ghCfg.NewApp(context.Background(), githubapp.OptWithTransport(gh.HTTPClient().Transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &handler{
+		db:         db,
+		replica:    db,
+		github:     app,
+		events:     events.New(),
+		flags:      flags.New(db),
+		cooldowns:  gocache.New(manualRunCooldown, 10*time.Minute),
+		apiLimits:  gocache.New(apiRateWindow, 10*time.Minute),
+		orgConfigs: gocache.New(orgConfigExpiry, 10*time.Minute),
+		projects:   gocache.New(projectCacheExpiry, 10*time.Minute),
+		lastKnown:  gocache.New(lastKnownExpiry, time.Hour),
+		queue:      newQueue(1),
+		settings:   settings.New(db),
+		notify:     &notify.Registry{},
+		plans:      plans.New(db),
+		artifacts:  artifacts.New(db),
+		tokens:     tokens.New(db),
+		breaker:    breaker.New(breakerThreshold, breakerCooldown),
+		links:      linkcheck.New(),
+		roles:      roles.NewResolver(),
+		repoHooks:  repohooks.New(db, "secret", testHookSecret),
+		mode:       modeAll,
+	}
+	h.apiRateLimit = defaultAPIRateLimit
+	return h, func() {
+		h.queue.wait()
+		db.Close()
+		cfg = oldCfg
+	}
+}
+
+// newTestServer returns a fake Github server with the test repository.
+func newTestServer() *githubtest.Server {
+	gh := githubtest.New()
+	gh.AddRepo(&githubtest.Repo{Owner: "posener", Name: "hello", Install: testInstall, Files: testFiles})
+	return gh
+}
+
+// deliver delivers a hook to the handler, waits for the jobs that it queued and returns the
+// response status code.
+func deliver(h *handler, event string, payload interface{}) int {
+	rec := githubtest.Deliver(http.HandlerFunc(h.hook), event, payload, testHookSecret)
+	h.queue.wait()
+	return rec.Code
+}
+
+func pushEvent(gh *githubtest.Server, repo string) *github.PushEvent {
+	sha := gh.HeadSHA("posener", repo, "master")
+	return &github.PushEvent{
+		Ref:   github.String("refs/heads/master"),
+		After: github.String(sha),
+		Repo: &github.PushEventRepository{
+			Name:          github.String(repo),
+			FullName:      github.String("posener/" + repo),
+			Owner:         &github.PushEventRepoOwner{Name: github.String("posener")},
+			DefaultBranch: github.String("master"),
+		},
+		HeadCommit: &github.PushEventCommit{
+			ID:      github.String(sha),
+			Message: github.String("Update docs"),
+			Author:  &github.CommitAuthor{Name: github.String("Eyal")},
+		},
+		Installation: &github.Installation{ID: github.Int64(testInstall), AppID: github.Int64(testAppID + 1)},
+	}
+}
+
+func mergedPREvent(number int, merged bool) *github.PullRequestEvent {
+	return &github.PullRequestEvent{
+		Action: github.String("closed"),
+		Number: github.Int(number),
+		PullRequest: &github.PullRequest{
+			Number: github.Int(number),
+			Merged: github.Bool(merged),
+			Base:   &github.PullRequestBranch{Ref: github.String("master")},
+		},
+		Repo: &github.Repository{
+			Name:          github.String("hello"),
+			FullName:      github.String("posener/hello"),
+			Owner:         &github.User{Login: github.String("posener")},
+			DefaultBranch: github.String("master"),
+		},
+		Installation: &github.Installation{ID: github.Int64(testInstall)},
+	}
+}
+
+// jobs returns the jobs of the test repository, from the first.
+func jobs(t *testing.T, h *handler) []Job {
+	t.Helper()
+	var js []Job
+	if err := h.db.Where("owner = ? AND repo = ?", "posener", "hello").Order("num").Find(&js).Error; err != nil {
+		t.Fatalf("Get jobs: %s", err)
+	}
+	return js
+}
+
+// assertReadmePR checks that the test repository has a single open goreadme pull request
+// with the generated readme.
+func assertReadmePR(t *testing.T, gh *githubtest.Server, num int) {
+	t.Helper()
+	var open []*github.PullRequest
+	for _, pr := range gh.Pulls("posener", "hello") {
+		if pr.GetState() == "open" {
+			open = append(open, pr)
+		}
+	}
+	if len(open) != 1 || open[0].GetNumber() != num || open[0].GetHead().GetRef() != goreadmeBranch {
+		t.Fatalf("Got open PRs %+v, want #%d from %s", open, num, goreadmeBranch)
+	}
+	readme, ok := gh.File("posener", "hello", goreadmeBranch, "README.md")
+	if !ok {
+		t.Fatalf("No README.md in branch %s", goreadmeBranch)
+	}
+	if !strings.Contains(readme, "Package hello says hello to the world.") {
+		t.Errorf("Readme was not generated from the package doc:\n%s", readme)
+	}
+}
+
+func TestHookPush(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, done := newTestHandler(t, gh)
+	defer done()
+
+	if code := deliver(h, "push", pushEvent(gh, "hello")); code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d", code, http.StatusOK)
+	}
+	js := jobs(t, h)
+	if len(js) != 1 {
+		t.Fatalf("Got %d jobs, want 1", len(js))
+	}
+	j := js[0]
+	if j.Status != status.Success || j.PR != 1 || j.Trigger != "Push to master" || j.TriggerAuthor != "Eyal" {
+		t.Errorf("Got job %s (%s): trigger %q by %q, PR #%d, want a successful push job with PR #1", j.Status, j.Message, j.Trigger, j.TriggerAuthor, j.PR)
+	}
+	if j.HeadSHA != gh.HeadSHA("posener", "hello", "master") {
+		t.Errorf("Job head SHA %s, want the pushed commit", j.HeadSHA)
+	}
+	assertReadmePR(t, gh, 1)
+
+	// Another push updates the same pull request.
+	if code := deliver(h, "push", pushEvent(gh, "hello")); code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d", code, http.StatusOK)
+	}
+	js = jobs(t, h)
+	if len(js) != 2 {
+		t.Fatalf("Got %d jobs, want 2", len(js))
+	}
+	if j := js[1]; j.Status != status.Success || j.PR != 1 {
+		t.Errorf("Got job %s (%s) with PR #%d, want success with PR #1", j.Status, j.Message, j.PR)
+	}
+	assertReadmePR(t, gh, 1)
+
+	var p Project
+	if err := h.db.Where("owner = ? AND repo = ?", "posener", "hello").First(&p).Error; err != nil {
+		t.Fatalf("Get project: %s", err)
+	}
+	if p.LastJob != 2 || p.Status != status.Success || p.PR != 1 {
+		t.Errorf("Got project with last job %d, %s, PR #%d, want job 2, success, PR #1", p.LastJob, p.Status, p.PR)
+	}
+}
+
+func TestHookPushNonDefaultBranch(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, done := newTestHandler(t, gh)
+	defer done()
+
+	e := pushEvent(gh, "hello")
+	e.Ref = github.String("refs/heads/feature")
+	if code := deliver(h, "push", e); code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d", code, http.StatusOK)
+	}
+	if js := jobs(t, h); len(js) != 0 {
+		t.Errorf("Got %d jobs, want none", len(js))
+	}
+}
+
+func TestHookInstall(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, done := newTestHandler(t, gh)
+	defer done()
+
+	e := &github.InstallationRepositoriesEvent{
+		Action:            github.String("added"),
+		RepositoriesAdded: []*github.Repository{{FullName: github.String("posener/hello")}},
+		Installation:      &github.Installation{ID: github.Int64(testInstall)},
+	}
+	if code := deliver(h, "installation_repositories", e); code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d", code, http.StatusOK)
+	}
+	js := jobs(t, h)
+	if len(js) != 1 {
+		t.Fatalf("Got %d jobs, want 1", len(js))
+	}
+	j := js[0]
+	if j.Status != status.Success || j.Trigger != "New Install" || j.Install != testInstall {
+		t.Errorf("Got job %s (%s): trigger %q of install %d, want a successful new install job", j.Status, j.Message, j.Trigger, j.Install)
+	}
+	// The head SHA is taken from the default branch.
+	if j.HeadSHA != gh.HeadSHA("posener", "hello", "master") {
+		t.Errorf("Job head SHA %s, want the head of master", j.HeadSHA)
+	}
+	assertReadmePR(t, gh, 1)
+}
+
+func TestHookInstallFailure(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, done := newTestHandler(t, gh)
+	defer done()
+
+	// The job of the first repository can't be created, the job of the second still runs.
+	e := &github.InstallationRepositoriesEvent{
+		Action: github.String("added"),
+		RepositoriesAdded: []*github.Repository{
+			{FullName: github.String("posener/missing")},
+			{FullName: github.String("posener/hello")},
+		},
+		Installation: &github.Installation{ID: github.Int64(testInstall)},
+	}
+	if code := deliver(h, "installation_repositories", e); code != http.StatusInternalServerError {
+		t.Fatalf("Got status %d, want %d", code, http.StatusInternalServerError)
+	}
+	js := jobs(t, h)
+	if len(js) != 1 || js[0].Status != status.Success {
+		t.Fatalf("Got jobs %+v, want a successful job", js)
+	}
+	assertReadmePR(t, gh, 1)
+}
+
+func TestHookMergedPR(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, done := newTestHandler(t, gh)
+	defer done()
+
+	// Closed pull requests that were not merged don't change the default branch.
+	if code := deliver(h, "pull_request", mergedPREvent(3, false)); code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d", code, http.StatusOK)
+	}
+	if js := jobs(t, h); len(js) != 0 {
+		t.Fatalf("Got %d jobs of unmerged PR, want none", len(js))
+	}
+
+	if code := deliver(h, "pull_request", mergedPREvent(3, true)); code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d", code, http.StatusOK)
+	}
+	js := jobs(t, h)
+	if len(js) != 1 {
+		t.Fatalf("Got %d jobs, want 1", len(js))
+	}
+	if j := js[0]; j.Status != status.Success || j.Trigger != "PR#3" {
+		t.Errorf("Got job %s (%s): trigger %q, want a successful PR#3 job", j.Status, j.Message, j.Trigger)
+	}
+	assertReadmePR(t, gh, 1)
+}
+
+func TestHookPayloadTypes(t *testing.T) {
+	repo := &github.Repository{Name: github.String("hello"), Owner: &github.User{Login: github.String("posener")}}
+	tests := []struct {
+		name    string
+		payload interface{}
+		push    bool
+		install bool
+		pr      bool
+	}{
+		{
+			name: "push",
+			payload: &github.PushEvent{
+				Ref:   github.String("refs/heads/master"),
+				After: github.String("abc"),
+				Repo:  &github.PushEventRepository{Name: github.String("hello")},
+			},
+			push: true,
+		},
+		{
+			name: "merged pull request",
+			payload: &github.PullRequestEvent{
+				Action:      github.String("closed"),
+				PullRequest: &github.PullRequest{Merged: github.Bool(true)},
+				Repo:        repo,
+			},
+			pr: true,
+		},
+		{
+			name: "install",
+			payload: &github.InstallationRepositoriesEvent{
+				RepositoriesAdded: []*github.Repository{repo},
+			},
+			install: true,
+		},
+	}
+	for _, tt := range tests {
+		payload, err := json.Marshal(tt.payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tryPush(payload) != nil; got != tt.push {
+			t.Errorf("%s: tryPush = %v, want %v", tt.name, got, tt.push)
+		}
+		if got := tryInstall(payload) != nil; got != tt.install {
+			t.Errorf("%s: tryInstall = %v, want %v", tt.name, got, tt.install)
+		}
+		if got := tryPullRequest(payload) != nil; got != tt.pr {
+			t.Errorf("%s: tryPullRequest = %v, want %v", tt.name, got, tt.pr)
+		}
+	}
+}
+
+func TestHookReplayKeepsFailed(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, done := newTestHandler(t, gh)
+	defer done()
+
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	h.hookBuffer, err = hookbuffer.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The repository does not exist, so its job can't be created.
+	e := pushEvent(gh, "missing")
+	if code := deliver(h, "push", e); code != http.StatusInternalServerError {
+		t.Fatalf("Got status %d, want %d", code, http.StatusInternalServerError)
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.hookBuffer.Save("delivery", payload); err != nil {
+		t.Fatal(err)
+	}
+	h.replayHooks(context.Background())
+	h.queue.wait()
+	if n, err := h.hookBuffer.Len(); err != nil || n != 1 {
+		t.Errorf("Got %d buffered hooks (%v) after a failed replay, want 1", n, err)
+	}
+}
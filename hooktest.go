@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// hookTestTimeout is the timeout of the requests that the hook test sends to the server.
+const hookTestTimeout = 10 * time.Second
+
+// hookTest sends a signed sample payload to the webhook endpoint of the server, and reports
+// whether the webhook secret and the routing of the hook are configured correctly.
+func (h *handler) hookTest(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+
+	payload, err := json.Marshal(&github.PingEvent{
+		Zen:    github.String("Testing the webhook configuration."),
+		HookID: github.Int64(0),
+	})
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed creating payload"))
+		return
+	}
+	signature := signPayload(payload, cfg.GithubHookSecret)
+	hookURL := cfg.Domain + "/github/hook"
+	client := &http.Client{Timeout: hookTestTimeout}
+
+	checks := []struct {
+		name string
+		run  func() error
+	}{
+		{name: "hook secret", run: checkGithubConfig},
+		{name: "signature", run: func() error {
+			req := httptest.NewRequest("POST", "/github/hook", bytes.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Hub-Signature", signature)
+			_, err := github.ValidatePayload(req, []byte(cfg.GithubHookSecret))
+			return err
+		}},
+		{name: "route " + hookURL, run: func() error {
+			return sendTestHook(client, hookURL, payload, signature, http.StatusOK)
+		}},
+		{name: "invalid signature rejected", run: func() error {
+			return sendTestHook(client, hookURL, payload, signPayload(payload, "invalid"), http.StatusUnauthorized)
+		}},
+	}
+
+	logrus.Infof("Admin %s tests the webhook configuration", data.User.GetLogin())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	code := http.StatusOK
+	var report bytes.Buffer
+	for _, c := range checks {
+		if err := c.run(); err != nil {
+			fmt.Fprintf(&report, "FAIL %s: %s\n", c.name, err)
+			code = http.StatusInternalServerError
+			continue
+		}
+		fmt.Fprintf(&report, "OK   %s\n", c.name)
+	}
+	w.WriteHeader(code)
+	report.WriteTo(w)
+}
+
+// sendTestHook sends a ping payload to the hook URL and checks the response status code.
+func sendTestHook(client *http.Client, url string, payload []byte, signature string, wantCode int) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "ping")
+	req.Header.Set("X-Hub-Signature", signature)
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed sending payload")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != wantCode {
+		return errors.Errorf("got status %s, expected %d", resp.Status, wantCode)
+	}
+	return nil
+}
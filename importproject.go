@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/importpath"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+// importResult is a repository that was resolved from an import path, but is not installed.
+type importResult struct {
+	// Input is the URL or import path that the user entered, and Path is its import path.
+	Input string
+	Path  string
+	Owner string
+	Repo  string
+}
+
+// importProject shows the form of importing a project by its import path.
+func (h *handler) importProject(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+	err := templates.Import.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+// importProjectAction resolves the repository of a godoc.org or pkg.go.dev URL, or of an
+// import path. If the repository is installed, the first job runs on it, otherwise the user
+// is guided to install it.
+func (h *handler) importProjectAction(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+
+	input := r.FormValue("url")
+	path, err := importpath.Parse(input)
+	if err != nil {
+		redirectError(w, r, "/import", err.Error())
+		return
+	}
+	owner, repo, err := importpath.Repo(r.Context(), http.DefaultClient, path)
+	if err != nil {
+		logrus.Infof("Failed resolving repository of %s: %s", path, err)
+		redirectError(w, r, "/import", "Failed finding the Github repository of "+path+": "+err.Error())
+		return
+	}
+
+	installed, err := h.installedRepo(r, owner, repo)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if installed {
+		if !requireRole(w, data, roles.Operator) {
+			return
+		}
+		h.manualRun(w, r, data, owner, repo, trigger{Name: "Import", Manual: true})
+		return
+	}
+
+	data.Import = &importResult{Input: input, Path: path, Owner: owner, Repo: repo}
+	err = templates.Import.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
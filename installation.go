@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+// Installation holds the state of a Github app installation.
+type Installation struct {
+	ID          int64 `gorm:"primary_key;auto_increment:false"`
+	Account     string
+	Suspended   bool
+	SuspendedAt *time.Time
+	UpdatedAt   time.Time
+}
+
+// setSuspended updates the suspension state of an installation.
+func setSuspended(db *gorm.DB, id int64, account string, suspended bool) error {
+	inst := Installation{ID: id, Account: account, Suspended: suspended}
+	if suspended {
+		now := time.Now()
+		inst.SuspendedAt = &now
+	}
+	return errors.Wrap(db.Save(&inst).Error, "saving installation")
+}
+
+// isSuspended returns whether an installation is suspended.
+func isSuspended(db *gorm.DB, id int64) (bool, error) {
+	var inst Installation
+	query := db.Where("id = ?", id).First(&inst)
+	switch {
+	case query.RecordNotFound():
+		return false, nil
+	case query.Error != nil:
+		return false, errors.Wrap(query.Error, "getting installation")
+	}
+	return inst.Suspended, nil
+}
+
+// resumeInstallation runs jobs for all the projects of an installation that were
+// skipped while it was suspended.
+func (h *handler) resumeInstallation(ctx context.Context, id int64) {
+	var projects []Project
+	err := h.db.Where("install = ? AND status = ?", id, status.Suspended).Find(&projects).Error
+	if err != nil {
+		logrus.Errorf("Failed getting suspended projects of install %d: %s", id, err)
+		return
+	}
+	for i := range projects {
+		// Run the job on the current head, the saved head may have changed while the
+		// installation was suspended.
+		projects[i].HeadSHA = ""
+		_, _, err := h.runJob(ctx, &projects[i], trigger{Name: "Unsuspended"})
+		if err != nil {
+			logrus.Errorf("Failed resuming %s/%s: %s", projects[i].Owner, projects[i].Repo, err)
+		}
+	}
+}
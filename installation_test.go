@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+func TestResumeInstallation(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+
+	// The project was skipped on a commit that is no longer the head.
+	githubtest.Fixtures(t, h.db, &Project{
+		Owner:   "posener",
+		Repo:    "hello",
+		Install: testInstall,
+		HeadSHA: "0000000000000000000000000000000000000000",
+		Status:  status.Suspended,
+	})
+	h.resumeInstallation(context.Background(), testInstall)
+	h.queue.wait()
+
+	js := jobs(t, h)
+	if len(js) != 1 || js[0].Trigger != "Unsuspended" {
+		t.Fatalf("Got jobs %+v, want a single unsuspended job", js)
+	}
+	if got, want := js[0].HeadSHA, gh.HeadSHA("posener", "hello", "master"); got != want {
+		t.Errorf("Job ran on %s, want the current head %s", got, want)
+	}
+}
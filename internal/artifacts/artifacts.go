@@ -0,0 +1,91 @@
+// Package artifacts stores generated readme content, so jobs that run again on the same
+// head commit with the same configuration can reuse it instead of generating it again.
+//
+// Artifacts are keyed by the repository, the head commit SHA and a hash of the
+// configuration. Only the latest artifacts of each repository are kept.
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// keep is the number of artifacts that are kept for each repository.
+const keep = 5
+
+// Artifact is a generated readme content.
+type Artifact struct {
+	Owner      string `gorm:"primary_key"`
+	Repo       string `gorm:"primary_key"`
+	HeadSHA    string `gorm:"primary_key"`
+	ConfigHash string `gorm:"primary_key"`
+	Content    string
+	CreatedAt  time.Time
+}
+
+// Store is a database backed artifacts store.
+type Store struct {
+	db *gorm.DB
+}
+
+// New returns an artifacts store.
+func New(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Hash returns the configuration hash of a configuration value.
+func Hash(cfg interface{}) (string, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling config")
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Get returns the content of an artifact, and whether it exists.
+func (s *Store) Get(owner, repo, headSHA, configHash string) (content string, found bool, err error) {
+	var a Artifact
+	query := s.db.Where("owner = ? AND repo = ? AND head_sha = ? AND config_hash = ?", owner, repo, headSHA, configHash).First(&a)
+	switch {
+	case query.RecordNotFound():
+		return "", false, nil
+	case query.Error != nil:
+		return "", false, errors.Wrapf(query.Error, "getting artifact of %s/%s", owner, repo)
+	}
+	return a.Content, true, nil
+}
+
+// Latest returns the content of the latest artifact of a repository, and whether it
+// exists.
+func (s *Store) Latest(owner, repo string) (content string, found bool, err error) {
+	var a Artifact
+	query := s.db.Where("owner = ? AND repo = ?", owner, repo).Order("created_at DESC").First(&a)
+	switch {
+	case query.RecordNotFound():
+		return "", false, nil
+	case query.Error != nil:
+		return "", false, errors.Wrapf(query.Error, "getting latest artifact of %s/%s", owner, repo)
+	}
+	return a.Content, true, nil
+}
+
+// Put stores the content of an artifact, and removes old artifacts of the repository.
+func (s *Store) Put(owner, repo, headSHA, configHash, content string) error {
+	err := s.db.Save(&Artifact{Owner: owner, Repo: repo, HeadSHA: headSHA, ConfigHash: configHash, Content: content}).Error
+	if err != nil {
+		return errors.Wrapf(err, "saving artifact of %s/%s", owner, repo)
+	}
+	err = s.db.Exec(`
+DELETE FROM artifacts WHERE owner = ? AND repo = ? AND created_at < (
+	SELECT min(created_at) FROM (
+		SELECT created_at FROM artifacts WHERE owner = ? AND repo = ? ORDER BY created_at DESC LIMIT ?
+	) AS latest
+)`, owner, repo, owner, repo, keep).Error
+	return errors.Wrapf(err, "removing old artifacts of %s/%s", owner, repo)
+}
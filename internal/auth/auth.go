@@ -1,25 +1,52 @@
+// Package auth handles login of users with Github OAuth.
+//
+// Login requests only the Scopes of Auth, which are empty by default, to read the user's
+// public profile. Features that need additional scopes are wrapped with RequireScopes,
+// which sends the user to the Github consent page for the missing scopes, and back to the
+// feature page once they were granted. Only the scopes in RequestableScopes can be
+// requested.
 package auth
 
 import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/dghubble/gologin"
 	"github.com/dghubble/gologin/github"
+	oauth2Login "github.com/dghubble/gologin/oauth2"
 	"github.com/dghubble/sessions"
 	gogithub "github.com/google/go-github/github"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 	githuboauth2 "golang.org/x/oauth2/github"
 )
 
 const (
-	sessionName    = "goreadme"
-	sessionUserKey = "user"
+	sessionName      = "goreadme"
+	sessionUserKey   = "user"
+	sessionScopesKey = "scopes"
+	sessionLoginKey  = "login"
+	sessionOrgsKey   = "orgs"
+	// sessionCreatedKey holds the creation time of the session, for revoking sessions.
+	sessionCreatedKey = "created"
+
+	// nextCookie holds the path to redirect to after login.
+	nextCookie = "goreadme-next"
 )
 
+// ScopeReadOrg allows reading the organizations of the user.
+const ScopeReadOrg = "read:org"
+
+// RequestableScopes are the scopes that features may request in addition to the Scopes of
+// Auth.
+var RequestableScopes = []string{ScopeReadOrg}
+
 type Auth struct {
 	SessionSecret string
 	GithubID      string
@@ -28,13 +55,31 @@ type Auth struct {
 	RedirectPath  string
 	LoginPath     string
 	HomePath      string
-	Scopes        []string
+	// Scopes are requested on every login. Additional scopes should be requested with
+	// RequireScopes only by the features that need them.
+	Scopes []string
+	// Store stores the sessions. Sessions are stored in signed cookies if it is nil, but
+	// then they can't be revoked.
+	Store sessions.Store
 
-	sessionStore *sessions.CookieStore
+	sessionStore sessions.Store
 }
 
 func (a *Auth) Init() {
-	a.sessionStore = sessions.NewCookieStore([]byte(a.SessionSecret), nil)
+	a.sessionStore = a.Store
+	if a.sessionStore == nil {
+		a.sessionStore = sessions.NewCookieStore([]byte(a.SessionSecret), nil)
+	}
+}
+
+// Revoke logs out a user from all sessions. It is supported by the DBStore and the
+// CookieStore.
+func (a *Auth) Revoke(login string) error {
+	s, ok := a.sessionStore.(interface{ Revoke(login string) error })
+	if !ok {
+		return errors.New("session store does not support revocation")
+	}
+	return s.Revoke(login)
 }
 
 func (a *Auth) CallbackHandler() http.Handler {
@@ -42,17 +87,108 @@ func (a *Auth) CallbackHandler() http.Handler {
 		github.CallbackHandler(a.config(), http.HandlerFunc(a.loginSuccess), http.HandlerFunc(a.loginFailed)))
 }
 
+// LoginHandler redirects to the Github login page. Additional scopes of RequestableScopes
+// can be requested with the "scope" query parameter, and the path to return to after login
+// with the "next" query parameter.
 func (a *Auth) LoginHandler() http.Handler {
-	return github.StateHandler(a.cookieConfig(), github.LoginHandler(a.config(), nil))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopes := append(a.grantedScopes(r), a.Scopes...)
+		if extra := r.URL.Query().Get("scope"); extra != "" {
+			for _, scope := range strings.Split(extra, ",") {
+				if !contains(RequestableScopes, scope) {
+					logrus.Warnf("Ignoring request for scope %q", scope)
+					continue
+				}
+				scopes = append(scopes, scope)
+			}
+		}
+		if next := r.URL.Query().Get("next"); isLocalPath(next) {
+			c := a.cookieConfig()
+			http.SetCookie(w, &http.Cookie{Name: nextCookie, Value: next, Path: "/", HttpOnly: true, Secure: c.Secure})
+		}
+		github.StateHandler(a.cookieConfig(), github.LoginHandler(a.config(scopes...), nil)).ServeHTTP(w, r)
+	})
+}
+
+// RequireScopes redirects users that did not grant the given scopes to the Github consent
+// page, and back to the requested page afterwards. Form submissions can't be repeated by a
+// redirect, so they return to the page of the form. It should wrap a RequireLogin handler.
+func (a *Auth) RequireScopes(next http.Handler, scopes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.HasScopes(r, scopes...) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		back := r.URL.RequestURI()
+		if r.Method != http.MethodGet {
+			back = a.HomePath
+			if ref, err := url.Parse(r.Referer()); err == nil && isLocalPath(ref.Path) {
+				back = ref.RequestURI()
+			}
+		}
+		logrus.Infof("Requesting additional scopes: %s", strings.Join(scopes, ","))
+		http.Redirect(w, r, "/auth/login?"+url.Values{
+			"scope": {strings.Join(scopes, ",")},
+			"next":  {back},
+		}.Encode(), http.StatusFound)
+	})
+}
+
+// HasScopes returns whether the logged in user granted all the given scopes.
+func (a *Auth) HasScopes(r *http.Request, scopes ...string) bool {
+	granted := a.grantedScopes(r)
+	for _, scope := range scopes {
+		if !contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Orgs returns the logins of the organizations of the logged in user. They are known only
+// if the user granted ScopeReadOrg.
+func (a *Auth) Orgs(r *http.Request) []string {
+	s, err := a.sessionStore.Get(r, sessionName)
+	if err != nil {
+		return nil
+	}
+	orgs, _ := s.Values[sessionOrgsKey].(string)
+	if orgs == "" {
+		return nil
+	}
+	return strings.Split(orgs, ",")
+}
+
+// grantedScopes returns the scopes that the logged in user granted.
+func (a *Auth) grantedScopes(r *http.Request) []string {
+	s, err := a.sessionStore.Get(r, sessionName)
+	if err != nil {
+		return nil
+	}
+	scopes, _ := s.Values[sessionScopesKey].(string)
+	if scopes == "" {
+		return nil
+	}
+	return strings.Split(scopes, ",")
 }
 
 func (a *Auth) LogoutHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		a.sessionStore.Destroy(w, sessionName)
+		a.Logout(w, r)
 		http.Redirect(w, r, a.LoginPath, http.StatusFound)
 	})
 }
 
+// Logout ends the session of the request.
+func (a *Auth) Logout(w http.ResponseWriter, r *http.Request) {
+	if s, ok := a.sessionStore.(*DBStore); ok {
+		if err := s.DestroyRequest(r, sessionName); err != nil {
+			logrus.Errorf("Failed deleting session: %s", err)
+		}
+	}
+	a.sessionStore.Destroy(w, sessionName)
+}
+
 // loginSuccess issues a cookie session after successful Github login
 func (a *Auth) loginSuccess(w http.ResponseWriter, r *http.Request) {
 	logrus.Infof("Login succeeded")
@@ -72,8 +208,52 @@ func (a *Auth) loginSuccess(w http.ResponseWriter, r *http.Request) {
 
 	session := a.sessionStore.New(sessionName)
 	session.Values[sessionUserKey] = string(b)
-	session.Save(w)
-	http.Redirect(w, r, a.HomePath, http.StatusFound)
+	session.Values[sessionLoginKey] = u.GetLogin()
+	session.Values[sessionCreatedKey] = time.Now().Format(time.RFC3339Nano)
+	if token, err := oauth2Login.TokenFromContext(r.Context()); err == nil {
+		scopes := normalizeScopes(token.Extra("scope"))
+		session.Values[sessionScopesKey] = scopes
+		if contains(strings.Split(scopes, ","), ScopeReadOrg) {
+			orgs, err := a.orgs(r.Context(), token)
+			if err != nil {
+				logrus.Errorf("Failed getting organizations of %s: %s", u.GetLogin(), err)
+			}
+			session.Values[sessionOrgsKey] = strings.Join(orgs, ",")
+		}
+	}
+	if err := session.Save(w); err != nil {
+		logrus.Errorf("Saving session: %s", err)
+		http.Error(w, "Failed", http.StatusInternalServerError)
+		return
+	}
+
+	next := a.HomePath
+	if c, err := r.Cookie(nextCookie); err == nil && isLocalPath(c.Value) {
+		next = c.Value
+		http.SetCookie(w, &http.Cookie{Name: nextCookie, Path: "/", MaxAge: -1})
+	}
+	http.Redirect(w, r, next, http.StatusFound)
+}
+
+// orgs returns the logins of the organizations of the user of a token, including the
+// organizations with a private membership.
+func (a *Auth) orgs(ctx context.Context, token *oauth2.Token) ([]string, error) {
+	client := gogithub.NewClient(a.config().Client(ctx, token))
+	var orgs []string
+	opt := &gogithub.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := client.Organizations.List(ctx, "", opt)
+		if err != nil {
+			return orgs, errors.Wrap(err, "listing organizations")
+		}
+		for _, org := range list {
+			orgs = append(orgs, org.GetLogin())
+		}
+		if resp.NextPage == 0 {
+			return orgs, nil
+		}
+		opt.Page = resp.NextPage
+	}
 }
 
 func (a *Auth) loginFailed(w http.ResponseWriter, r *http.Request) {
@@ -82,12 +262,12 @@ func (a *Auth) loginFailed(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, a.LoginPath+"?error=unauthorized", http.StatusFound)
 }
 
-func (a *Auth) config() *oauth2.Config {
+func (a *Auth) config(scopes ...string) *oauth2.Config {
 	return &oauth2.Config{
 		ClientID:     a.GithubID,
 		ClientSecret: a.GithubSecret,
 		RedirectURL:  a.Domain + a.RedirectPath,
-		Scopes:       a.Scopes,
+		Scopes:       uniqueScopes(append(scopes, a.Scopes...)),
 		Endpoint:     githuboauth2.Endpoint,
 	}
 }
@@ -145,7 +325,7 @@ func (a *Auth) User(r *http.Request) *gogithub.User {
 	return u.(*gogithub.User)
 }
 
-// IsAuthenticated returns true if the user has a signed session cookie.
+// IsAuthenticated returns true if the user has a valid session.
 func (a *Auth) IsAuthenticated(r *http.Request) bool {
 	_, err := a.sessionStore.Get(r, sessionName)
 	return err == nil
@@ -172,3 +352,36 @@ func (a *Auth) user(r *http.Request) *gogithub.User {
 
 	return &u
 }
+
+// normalizeScopes returns the comma separated scopes of a token response scope value.
+func normalizeScopes(v interface{}) string {
+	s, _ := v.(string)
+	return strings.Join(uniqueScopes(strings.Split(s, ",")), ",")
+}
+
+// uniqueScopes returns the sorted non-empty unique scopes.
+func uniqueScopes(scopes []string) []string {
+	var unique []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope != "" && !contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalPath returns whether a redirect path stays on the server.
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "/\\")
+}
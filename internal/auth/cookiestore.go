@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dghubble/sessions"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// revocation marks that the sessions of a user that were created before it are revoked.
+type revocation struct {
+	Login     string `gorm:"primary_key"`
+	RevokedAt time.Time
+}
+
+// TableName is the database table of session revocations.
+func (revocation) TableName() string { return "session_revocations" }
+
+// CookieStore stores sessions in signed cookies. The sessions in cookies can't be deleted,
+// so the sessions of a user are revoked by recording the revocation time in the database,
+// and sessions that were created before it are rejected.
+type CookieStore struct {
+	db     *gorm.DB
+	cookie *sessions.CookieStore
+}
+
+// NewCookieStore returns a cookie session store that signs session cookies with the given
+// secret.
+func NewCookieStore(db *gorm.DB, secret string) *CookieStore {
+	return &CookieStore{db: db, cookie: sessions.NewCookieStore([]byte(secret), nil)}
+}
+
+// New returns a new session.
+func (s *CookieStore) New(name string) *sessions.Session {
+	return s.cookie.New(name)
+}
+
+// Get returns the session of the session cookie of the request. It returns an error if
+// the cookie is not valid, or the sessions of the user were revoked after it was created.
+func (s *CookieStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	session, err := s.cookie.Get(r, name)
+	if err != nil {
+		return nil, err
+	}
+	login, _ := session.Values[sessionLoginKey].(string)
+	var rev revocation
+	query := s.db.Where("login = ?", login).First(&rev)
+	switch {
+	case query.RecordNotFound():
+		return session, nil
+	case query.Error != nil:
+		return nil, errors.Wrap(query.Error, "getting session revocation")
+	}
+	created, _ := session.Values[sessionCreatedKey].(string)
+	if t, err := time.Parse(time.RFC3339Nano, created); err != nil || !t.After(rev.RevokedAt) {
+		return nil, errors.Errorf("session of %s was revoked", login)
+	}
+	return session, nil
+}
+
+// Save sets the session in the session cookie.
+func (s *CookieStore) Save(w http.ResponseWriter, session *sessions.Session) error {
+	return s.cookie.Save(w, session)
+}
+
+// Destroy deletes the session cookie.
+func (s *CookieStore) Destroy(w http.ResponseWriter, name string) {
+	s.cookie.Destroy(w, name)
+}
+
+// Revoke revokes all the current sessions of a user.
+func (s *CookieStore) Revoke(login string) error {
+	err := s.db.Where(revocation{Login: login}).
+		Assign(revocation{RevokedAt: time.Now()}).
+		FirstOrCreate(&revocation{}).Error
+	return errors.Wrapf(err, "revoking sessions of %s", login)
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dghubble/sessions"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// sessionIDKey is the key of the session ID in the values of sessions of the DBStore.
+// It is not stored with the other values.
+const sessionIDKey = "id"
+
+// storedSession is a session of the DBStore.
+type storedSession struct {
+	ID        string `gorm:"primary_key"`
+	Login     string
+	Data      string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// TableName is the database table of stored sessions.
+func (storedSession) TableName() string { return "sessions" }
+
+// DBStore stores sessions in the database, and only a signed session ID in the session
+// cookie. Unlike sessions that are stored in cookies, stored sessions can be revoked.
+type DBStore struct {
+	db     *gorm.DB
+	cookie *sessions.CookieStore
+}
+
+// NewDBStore returns a database session store that signs session cookies with the
+// given secret.
+func NewDBStore(db *gorm.DB, secret string) *DBStore {
+	return &DBStore{db: db, cookie: sessions.NewCookieStore([]byte(secret), nil)}
+}
+
+// New returns a new session.
+func (s *DBStore) New(name string) *sessions.Session {
+	session := sessions.NewSession(s, name)
+	config := *s.cookie.Config
+	session.Config = &config
+	return session
+}
+
+// Get returns the stored session of the session cookie of the request. It returns an
+// error if the cookie is not valid, or the session does not exist or expired.
+func (s *DBStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	c, err := s.cookie.Get(r, name)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := c.Values[sessionIDKey].(string)
+	var stored storedSession
+	err = s.db.Where("id = ? AND expires_at > ?", id, time.Now()).First(&stored).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "getting session")
+	}
+	session := s.New(name)
+	if err := json.Unmarshal([]byte(stored.Data), &session.Values); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling session values")
+	}
+	session.Values[sessionIDKey] = id
+	return session, nil
+}
+
+// Save stores the session and sets its ID in the session cookie.
+func (s *DBStore) Save(w http.ResponseWriter, session *sessions.Session) error {
+	values := make(map[string]interface{}, len(session.Values))
+	for k, v := range session.Values {
+		if k != sessionIDKey {
+			values[k] = v
+		}
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return errors.Wrap(err, "marshaling session values")
+	}
+	id, _ := session.Values[sessionIDKey].(string)
+	if id == "" {
+		id, err = newSessionID()
+		if err != nil {
+			return err
+		}
+	}
+	login, _ := session.Values[sessionLoginKey].(string)
+	stored := storedSession{
+		ID:        id,
+		Login:     login,
+		Data:      string(b),
+		ExpiresAt: time.Now().Add(time.Duration(session.Config.MaxAge) * time.Second),
+	}
+	if err := s.db.Save(&stored).Error; err != nil {
+		return errors.Wrap(err, "saving session")
+	}
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(storedSession{}).Error; err != nil {
+		return errors.Wrap(err, "deleting expired sessions")
+	}
+
+	c := s.cookie.New(session.Name())
+	c.Config = session.Config
+	c.Values[sessionIDKey] = id
+	return s.cookie.Save(w, c)
+}
+
+// Destroy deletes the session of the response and its session cookie. The stored
+// session is deleted by DestroyRequest, since Destroy has no access to the request.
+func (s *DBStore) Destroy(w http.ResponseWriter, name string) {
+	s.cookie.Destroy(w, name)
+}
+
+// DestroyRequest deletes the stored session of the session cookie of the request.
+func (s *DBStore) DestroyRequest(r *http.Request, name string) error {
+	c, err := s.cookie.Get(r, name)
+	if err != nil {
+		return nil
+	}
+	id, _ := c.Values[sessionIDKey].(string)
+	return errors.Wrap(s.db.Where("id = ?", id).Delete(storedSession{}).Error, "deleting session")
+}
+
+// Revoke deletes all the sessions of a user.
+func (s *DBStore) Revoke(login string) error {
+	err := s.db.Where("login = ?", login).Delete(storedSession{}).Error
+	return errors.Wrapf(err, "revoking sessions of %s", login)
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating session ID")
+	}
+	return hex.EncodeToString(b), nil
+}
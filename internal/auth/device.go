@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	gogithub "github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// Github device authorization flow endpoints.
+const (
+	deviceCodeURL   = "https://github.com/login/device/code"
+	deviceTokenURL  = "https://github.com/login/oauth/access_token"
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// DeviceCode is the response of starting a device authorization flow. The user should
+// enter the user code in the verification URI, while the client polls for the result
+// with the device code.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// DeviceError is an error of the device authorization flow, such as
+// "authorization_pending" or "slow_down", which the client should handle.
+type DeviceError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *DeviceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// StartDevice starts a device authorization flow.
+func (a *Auth) StartDevice(ctx context.Context) (*DeviceCode, error) {
+	var code DeviceCode
+	err := a.devicePost(ctx, deviceCodeURL, url.Values{
+		"client_id": {a.GithubID},
+		"scope":     {strings.Join(a.Scopes, " ")},
+	}, &code)
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// PollDevice checks whether the user authorized a device code, and returns the user if
+// it was authorized. It returns a *DeviceError if the flow was not completed.
+func (a *Auth) PollDevice(ctx context.Context, deviceCode string) (*gogithub.User, error) {
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		DeviceError
+	}
+	err := a.devicePost(ctx, deviceTokenURL, url.Values{
+		"client_id":   {a.GithubID},
+		"device_code": {deviceCode},
+		"grant_type":  {deviceGrantType},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != "" {
+		return nil, &resp.DeviceError
+	}
+
+	client := gogithub.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: resp.AccessToken})))
+	user, _, err := client.Users.Get(ctx, "")
+	return user, errors.Wrap(err, "failed getting user")
+}
+
+func (a *Auth) devicePost(ctx context.Context, endpoint string, values url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("got status %s", resp.Status)
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(v), "failed decoding response")
+}
@@ -0,0 +1,175 @@
+// Package breaker stops calling Github API endpoints that keep failing.
+//
+// Github API requests are grouped into categories by their path: repository contents, git
+// data and pull requests. When the requests of a category fail consecutively, the circuit
+// of the category opens, and its requests fail immediately with an *OpenError, instead of
+// waiting for their timeout. After a cooldown period, a single request is let through to
+// check if the endpoint recovered, and the circuit closes once a request succeeds.
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Categories are the Github API categories that are protected by the breaker.
+var Categories = []string{"contents", "git", "pulls"}
+
+// OpenError is returned for requests of a category that its circuit is open.
+type OpenError struct {
+	Category string
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("github %s API is unavailable", e.Category)
+}
+
+// IsOpen returns whether an error was caused by an open circuit.
+func IsOpen(err error) bool {
+	err = errors.Cause(err)
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	_, ok := err.(*OpenError)
+	return ok
+}
+
+// Breaker holds the circuits of the Github API categories.
+type Breaker struct {
+	// Threshold is the number of consecutive failures that opens a circuit.
+	Threshold int
+	// Cooldown is the duration that a circuit stays open before a request is let through.
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	failures int
+	openedAt time.Time
+	// probing is true while a request checks if an open circuit can be closed.
+	probing bool
+}
+
+// New returns a breaker.
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown, circuits: make(map[string]*circuit)}
+}
+
+// Transport returns an http.RoundTripper that sends requests through the breaker.
+func (b *Breaker) Transport(t http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(r *http.Request) (*http.Response, error) {
+		category := categoryOf(r.URL.Path)
+		if category == "" {
+			return t.RoundTrip(r)
+		}
+		if !b.allow(category) {
+			return nil, &OpenError{Category: category}
+		}
+		resp, err := t.RoundTrip(r)
+		b.record(r.Context(), category, resp, err)
+		return resp, err
+	})
+}
+
+// Open returns the categories that their circuit is open.
+func (b *Breaker) Open() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var open []string
+	for _, category := range Categories {
+		if c := b.circuits[category]; c != nil && !c.openedAt.IsZero() {
+			open = append(open, category)
+		}
+	}
+	return open
+}
+
+// allow returns whether a request of a category can be sent.
+func (b *Breaker) allow(category string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(category)
+	if c.openedAt.IsZero() {
+		return true
+	}
+	if c.probing || time.Since(c.openedAt) < b.Cooldown {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+// record updates the circuit of a category with the result of a request.
+func (b *Breaker) record(ctx context.Context, category string, resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(category)
+
+	if err != nil && ctx.Err() == context.Canceled {
+		// The request was cancelled by the caller, it says nothing about Github. A cancelled
+		// probe allows another probe, without waiting for another cooldown.
+		c.probing = false
+		return
+	}
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+
+	if c.probing {
+		c.probing = false
+		if failed {
+			c.openedAt = time.Now()
+			return
+		}
+	}
+	if !failed {
+		if !c.openedAt.IsZero() {
+			logrus.Infof("Github %s API recovered", category)
+		}
+		c.failures = 0
+		c.openedAt = time.Time{}
+		return
+	}
+	c.failures++
+	if c.failures >= b.Threshold && c.openedAt.IsZero() {
+		logrus.Warnf("Github %s API failed %d consecutive times, failing its requests for %s", category, c.failures, b.Cooldown)
+		c.openedAt = time.Now()
+	}
+}
+
+func (b *Breaker) circuit(category string) *circuit {
+	c := b.circuits[category]
+	if c == nil {
+		c = &circuit{}
+		b.circuits[category] = c
+	}
+	return c
+}
+
+// categoryOf returns the category of a Github API path, for example "pulls" for
+// "/repos/owner/repo/pulls/1". It returns an empty string for paths that are not in a
+// protected category.
+func categoryOf(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "repos" {
+		return ""
+	}
+	for _, category := range Categories {
+		if parts[3] == category {
+			return category
+		}
+	}
+	return ""
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
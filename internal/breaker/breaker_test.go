@@ -0,0 +1,169 @@
+package breaker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// github is a fake Github transport that counts its requests and responds with a status code.
+type github struct {
+	calls  int
+	status int
+	err    error
+}
+
+func (g *github) RoundTrip(r *http.Request) (*http.Response, error) {
+	g.calls++
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &http.Response{StatusCode: g.status, Body: http.NoBody, Request: r}, nil
+}
+
+func send(ctx context.Context, t http.RoundTripper) error {
+	r := httptest.NewRequest(http.MethodGet, "https://api.github.com/repos/posener/hello/pulls", nil)
+	_, err := t.RoundTrip(r.WithContext(ctx))
+	return err
+}
+
+func TestCategoryOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/repos/posener/hello/pulls/1", want: "pulls"},
+		{path: "/repos/posener/hello/contents/README.md", want: "contents"},
+		{path: "/repos/posener/hello/git/refs/heads/master", want: "git"},
+		{path: "/repos/posener/hello", want: ""},
+		{path: "/repos/posener/hello/branches/master", want: ""},
+		{path: "/app/installations", want: ""},
+	}
+	for _, tt := range tests {
+		if got := categoryOf(tt.path); got != tt.want {
+			t.Errorf("categoryOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	gh := &github{status: http.StatusBadGateway}
+	b := New(2, time.Hour)
+	tr := b.Transport(gh)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := send(ctx, tr); err != nil {
+			t.Fatalf("Request %d failed before the circuit opened: %s", i, err)
+		}
+	}
+	if got := b.Open(); len(got) != 1 || got[0] != "pulls" {
+		t.Fatalf("Open() = %v, want [pulls]", got)
+	}
+	err := send(ctx, tr)
+	if !IsOpen(err) {
+		t.Errorf("Got error %v, want an open circuit error", err)
+	}
+	if gh.calls != 2 {
+		t.Errorf("Github got %d requests, want 2", gh.calls)
+	}
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	gh := &github{status: http.StatusBadGateway}
+	b := New(2, time.Hour)
+	tr := b.Transport(gh)
+	ctx := context.Background()
+
+	send(ctx, tr)
+	gh.status = http.StatusOK
+	send(ctx, tr)
+	gh.status = http.StatusBadGateway
+	send(ctx, tr)
+	if got := b.Open(); len(got) != 0 {
+		t.Errorf("Open() = %v, want no open circuits", got)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	gh := &github{status: http.StatusBadGateway}
+	b := New(1, time.Hour)
+	tr := b.Transport(gh)
+	ctx := context.Background()
+
+	send(ctx, tr)
+	// Pass the cooldown.
+	b.circuits["pulls"].openedAt = time.Now().Add(-2 * time.Hour)
+
+	// A failing probe opens the circuit for another cooldown.
+	if err := send(ctx, tr); IsOpen(err) {
+		t.Fatal("Probe was not sent")
+	}
+	if err := send(ctx, tr); !IsOpen(err) {
+		t.Fatalf("Got error %v after a failed probe, want an open circuit error", err)
+	}
+
+	// A successful probe closes the circuit.
+	b.circuits["pulls"].openedAt = time.Now().Add(-2 * time.Hour)
+	gh.status = http.StatusOK
+	if err := send(ctx, tr); err != nil {
+		t.Fatalf("Probe: %s", err)
+	}
+	if got := b.Open(); len(got) != 0 {
+		t.Errorf("Open() = %v after a successful probe, want no open circuits", got)
+	}
+	if gh.calls != 3 {
+		t.Errorf("Github got %d requests, want 3", gh.calls)
+	}
+}
+
+func TestSingleProbe(t *testing.T) {
+	b := New(1, time.Hour)
+	b.record(context.Background(), "pulls", nil, errors.New("timeout"))
+	b.circuits["pulls"].openedAt = time.Now().Add(-2 * time.Hour)
+
+	if !b.allow("pulls") {
+		t.Fatal("Probe was not allowed after the cooldown")
+	}
+	if b.allow("pulls") {
+		t.Error("Another request was allowed while probing")
+	}
+}
+
+func TestCancelledProbe(t *testing.T) {
+	b := New(1, time.Hour)
+	b.record(context.Background(), "pulls", nil, errors.New("timeout"))
+	openedAt := time.Now().Add(-2 * time.Hour)
+	b.circuits["pulls"].openedAt = openedAt
+
+	if !b.allow("pulls") {
+		t.Fatal("Probe was not allowed after the cooldown")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(ctx, "pulls", nil, context.Canceled)
+
+	if !b.circuits["pulls"].openedAt.Equal(openedAt) {
+		t.Error("Cancelled probe changed the circuit open time")
+	}
+	if !b.allow("pulls") {
+		t.Error("Another probe was not allowed after a cancelled probe")
+	}
+}
+
+func TestCancelledNotCounted(t *testing.T) {
+	gh := &github{err: context.Canceled}
+	b := New(1, time.Hour)
+	tr := b.Transport(gh)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	send(ctx, tr)
+	if got := b.Open(); len(got) != 0 {
+		t.Errorf("Open() = %v after a cancelled request, want no open circuits", got)
+	}
+}
@@ -0,0 +1,75 @@
+// Package diff computes line differences between texts.
+package diff
+
+import "strings"
+
+// Lines returns the lines that were removed from a and the lines that were added
+// in b, according to the longest common subsequence of lines.
+func Lines(a, b string) (removed, added []string) {
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of al[i:] and bl[j:].
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(al) && j < len(bl) {
+		switch {
+		case al[i] == bl[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, al[i])
+			i++
+		default:
+			added = append(added, bl[j])
+			j++
+		}
+	}
+	removed = append(removed, al[i:]...)
+	added = append(added, bl[j:]...)
+	return removed, added
+}
+
+// ChangedChars returns the number of characters in the lines that differ between a and b.
+// If ignoreSpace is true, whitespace characters are not counted, and lines that differ
+// only in whitespace are considered equal.
+func ChangedChars(a, b string, ignoreSpace bool) int {
+	if ignoreSpace {
+		a, b = normalizeSpace(a), normalizeSpace(b)
+	}
+	removed, added := Lines(a, b)
+	n := 0
+	for _, l := range append(removed, added...) {
+		if ignoreSpace {
+			l = strings.Join(strings.Fields(l), "")
+		}
+		n += len(l)
+	}
+	return n
+}
+
+// normalizeSpace collapses whitespace in each line and removes empty lines.
+func normalizeSpace(s string) string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if f := strings.Fields(l); len(f) > 0 {
+			lines = append(lines, strings.Join(f, " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
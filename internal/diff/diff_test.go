@@ -0,0 +1,51 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		removed []string
+		added   []string
+	}{
+		{a: "a\nb\nc", b: "a\nb\nc"},
+		{a: "", b: "a", removed: []string{""}, added: []string{"a"}},
+		{a: "a\nb\nc", b: "a\nc", removed: []string{"b"}},
+		{a: "a\nc", b: "a\nb\nc", added: []string{"b"}},
+		{a: "a\nb\nc", b: "a\nx\nc", removed: []string{"b"}, added: []string{"x"}},
+		// Moved lines are removed and added once, the rest of the lines are common.
+		{a: "a\nb\nc\nd", b: "b\nc\nd\na", removed: []string{"a"}, added: []string{"a"}},
+		{a: "x\na\nb", b: "a\nb\ny", removed: []string{"x"}, added: []string{"y"}},
+		{a: "a\na\nb", b: "a\nb\nb", removed: []string{"a"}, added: []string{"b"}},
+	}
+	for _, tt := range tests {
+		removed, added := Lines(tt.a, tt.b)
+		if fmt.Sprint(removed) != fmt.Sprint(tt.removed) || fmt.Sprint(added) != fmt.Sprint(tt.added) {
+			t.Errorf("Lines(%q, %q) = %q, %q, want %q, %q", tt.a, tt.b, removed, added, tt.removed, tt.added)
+		}
+	}
+}
+
+func TestChangedChars(t *testing.T) {
+	tests := []struct {
+		a, b        string
+		ignoreSpace bool
+		want        int
+	}{
+		{a: "hello\nworld", b: "hello\nworld", want: 0},
+		{a: "hello\nworld", b: "hello\nthere", want: len("world") + len("there")},
+		{a: "hello\nworld", b: "hello\nworld\n!", want: 1},
+		{a: "hello  world\n", b: "hello world", want: len("hello  world") + len("hello world")},
+		{a: "hello  world\n", b: "hello world", ignoreSpace: true, want: 0},
+		{a: "hello\n\n\nworld", b: "hello\nworld", ignoreSpace: true, want: 0},
+		{a: "a b\n", b: "a c", ignoreSpace: true, want: 4},
+	}
+	for _, tt := range tests {
+		if got := ChangedChars(tt.a, tt.b, tt.ignoreSpace); got != tt.want {
+			t.Errorf("ChangedChars(%q, %q, %v) = %d, want %d", tt.a, tt.b, tt.ignoreSpace, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package events broadcasts job state changes to listening dashboard clients.
+//
+// Events are grouped by Github app installation, such that a user that is logged in to
+// the dashboard only gets notified about jobs of its own installation.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+// keepAlive is the interval of sending comments on idle connections, in order to prevent
+// proxies (such as the Heroku router) from closing them.
+const keepAlive = 30 * time.Second
+
+// Event describes a change in a job state.
+type Event struct {
+	Install int64         `json:"install"`
+	Owner   string        `json:"owner"`
+	Repo    string        `json:"repo"`
+	Num     int           `json:"num"`
+	Status  status.Status `json:"status"`
+	Message string        `json:"message"`
+	PR      int           `json:"pr"`
+}
+
+// Hub dispatches events to subscribers of an installation.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan Event]bool
+	// listeners are called with the events of all the installations.
+	listeners []func(Event)
+}
+
+// New returns a new events hub.
+func New() *Hub {
+	return &Hub{subs: make(map[int64]map[chan Event]bool)}
+}
+
+// Subscribe registers for events of a given installation. The returned function
+// must be called in order to unsubscribe.
+func (h *Hub) Subscribe(install int64) (<-chan Event, func()) {
+	ch := make(chan Event, 10)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[install] == nil {
+		h.subs[install] = make(map[chan Event]bool)
+	}
+	h.subs[install][ch] = true
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[install], ch)
+		if len(h.subs[install]) == 0 {
+			delete(h.subs, install)
+		}
+	}
+}
+
+// Listen registers a function that is called with every published event, of all the
+// installations. The function is called synchronously, and should return quickly.
+func (h *Hub) Listen(f func(Event)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners = append(h.listeners, f)
+}
+
+// Publish sends an event to all the subscribers of the event installation. Slow
+// subscribers that their buffer is full will miss the event.
+func (h *Hub) Publish(e Event) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range h.listeners {
+		f(e)
+	}
+	for ch := range h.subs[e.Install] {
+		select {
+		case ch <- e:
+		default:
+			logrus.Warnf("Dropping event of %s/%s#%d for slow subscriber", e.Owner, e.Repo, e.Num)
+		}
+	}
+}
+
+// Serve streams the events of an installation as server-sent events until the
+// request is done.
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, install int64) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch, unsubscribe := h.Subscribe(install)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case e := <-ch:
+			b, err := json.Marshal(e)
+			if err != nil {
+				logrus.Errorf("Failed marshaling event: %s", err)
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", b)
+		}
+		f.Flush()
+	}
+}
@@ -0,0 +1,86 @@
+// Package flags provides feature flags that enable new behaviors for a subset of
+// Github app installations before they are rolled out to all installations.
+//
+// A flag can be set for a specific installation, or globally with installation 0.
+// An installation specific value takes precedence over the global value. Flags that
+// were never set are disabled.
+package flags
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Global is the installation value for flags that apply to all installations.
+const Global = 0
+
+// Names of the flags that are checked by the server.
+const (
+	// ModuleDetection generates the root readme of repositories that have no Go code in the
+	// root directory from their shallowest Go module.
+	ModuleDetection = "module_detection"
+)
+
+// Flag is a feature flag value for an installation.
+type Flag struct {
+	Name      string `gorm:"primary_key"`
+	Install   int64  `gorm:"primary_key;auto_increment:false"`
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+// Flags is a database backed flags store.
+type Flags struct {
+	db *gorm.DB
+}
+
+// New returns a flags store.
+func New(db *gorm.DB) *Flags {
+	return &Flags{db: db}
+}
+
+// Enabled returns whether a flag is enabled for an installation.
+func (f *Flags) Enabled(name string, install int64) bool {
+	if f == nil {
+		return false
+	}
+	var flags []Flag
+	err := f.db.Where("name = ? AND install IN (?, ?)", name, install, Global).Find(&flags).Error
+	if err != nil {
+		logrus.Errorf("Failed getting flag %s for install %d: %s", name, install, err)
+		return false
+	}
+	enabled := false
+	for _, flag := range flags {
+		if flag.Install == install {
+			return flag.Enabled
+		}
+		enabled = flag.Enabled
+	}
+	return enabled
+}
+
+// Set sets a flag value for an installation.
+func (f *Flags) Set(name string, install int64, enabled bool) error {
+	if name == "" {
+		return errors.New("empty flag name")
+	}
+	err := f.db.Save(&Flag{Name: name, Install: install, Enabled: enabled}).Error
+	return errors.Wrapf(err, "saving flag %s for install %d", name, install)
+}
+
+// Delete removes a flag value of an installation.
+func (f *Flags) Delete(name string, install int64) error {
+	err := f.db.Where("name = ? AND install = ?", name, install).Delete(&Flag{}).Error
+	return errors.Wrapf(err, "deleting flag %s for install %d", name, install)
+}
+
+// List returns all flag values.
+func (f *Flags) List() ([]Flag, error) {
+	var flags []Flag
+	err := f.db.Order("name, install").Find(&flags).Error
+	return flags, errors.Wrap(err, "listing flags")
+}
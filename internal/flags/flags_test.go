@@ -0,0 +1,66 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+)
+
+func TestEnabled(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	f := New(db)
+
+	if f.Enabled("feature", 1) {
+		t.Error("Flag that was never set is enabled")
+	}
+
+	if err := f.Set("feature", Global, true); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+	if !f.Enabled("feature", 1) {
+		t.Error("Globally enabled flag is disabled")
+	}
+
+	// An installation value takes precedence over the global value.
+	if err := f.Set("feature", 1, false); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+	if f.Enabled("feature", 1) {
+		t.Error("Flag that was disabled for the installation is enabled")
+	}
+	if !f.Enabled("feature", 2) {
+		t.Error("Flag of another installation is disabled")
+	}
+	if f.Enabled("other", 2) {
+		t.Error("Another flag is enabled")
+	}
+
+	if err := f.Delete("feature", 1); err != nil {
+		t.Fatalf("Delete: %s", err)
+	}
+	if !f.Enabled("feature", 1) {
+		t.Error("Deleted installation value is still used")
+	}
+
+	list, err := f.List()
+	if err != nil {
+		t.Fatalf("List: %s", err)
+	}
+	if len(list) != 1 || list[0].Install != Global {
+		t.Errorf("List() = %+v, want the global value", list)
+	}
+}
+
+func TestNil(t *testing.T) {
+	var f *Flags
+	if f.Enabled("feature", 1) {
+		t.Error("Flag of nil flags is enabled")
+	}
+}
+
+func TestSetEmptyName(t *testing.T) {
+	if err := New(nil).Set("", 1, true); err == nil {
+		t.Error("Set succeeded with an empty name")
+	}
+}
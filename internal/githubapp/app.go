@@ -0,0 +1,239 @@
+package githubapp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bradleyfalzon/ghinstallation"
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// Cache is a common interface for cache.
+type Cache interface {
+	// Get returns an object from the cache by key. If the object does
+	// not exists, it should return nil.
+	Get(string) interface{}
+	// Set sets an object by a string key in the cache.
+	Set(string, interface{})
+	// Flush removes all the objects from the cache.
+	Flush()
+}
+
+// App is a struct for github application that can produce installation
+// clients.
+type App struct {
+	// Client is github API with the App credentials.
+	*github.Client
+
+	cfg   Config
+	cache Cache
+	// base is the transport that the requests are sent with.
+	base http.RoundTripper
+
+	mu     sync.RWMutex
+	key    []byte
+	source oauth2.TokenSource
+}
+
+// Installation holds installation clients and information.
+type Installation struct {
+	// Client is an http client with the installation credentials.
+	*http.Client
+	// Github is a Github API client with the installation credentials.
+	Github *github.Client
+	// ID is the installation ID.
+	ID int
+	// Permissions are the permissions that were granted to the installation, mapped to
+	// their access level, "read" or "write".
+	Permissions map[string]string
+}
+
+// HasPermission returns whether the installation was granted a permission with the given
+// access level. Write access implies read access.
+func (i *Installation) HasPermission(name, access string) bool {
+	switch granted := i.Permissions[name]; access {
+	case "read":
+		return granted == "read" || granted == "write"
+	default:
+		return granted == access
+	}
+}
+
+// Option is an option for new applications.
+type Option func(*App)
+
+// OptWithCache is an option to use cache to hold the clients.
+func OptWithCache(c Cache) Option {
+	return func(a *App) {
+		a.cache = c
+	}
+}
+
+// OptWithTransport is an option to send the requests of the app and of its installations
+// with the given transport, for example to a fake Github server in tests.
+func OptWithTransport(t http.RoundTripper) Option {
+	return func(a *App) {
+		a.base = t
+	}
+}
+
+// NewApp returns a Github app object. It fails if the private key can't be loaded.
+func (c *Config) NewApp(ctx context.Context, opts ...Option) (*App, error) {
+	a := &App{cfg: *c}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: a.transport()})
+	a.Client = github.NewClient(oauth2.NewClient(ctx, a))
+	if c.UserAgent != "" {
+		a.Client.UserAgent = c.UserAgent
+	}
+	return a, nil
+}
+
+// Reload loads the private key again, and uses it for new requests. If loading the key
+// fails, the app keeps using the current key.
+func (a *App) Reload() error {
+	pk, key, err := a.cfg.key()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.key = key
+	a.source = a.cfg.tokenSource(pk)
+	// Installation clients use the old key, remove them.
+	if a.cache != nil {
+		a.cache.Flush()
+	}
+	return nil
+}
+
+// Token implements oauth2.TokenSource, using the current private key.
+func (a *App) Token() (*oauth2.Token, error) {
+	a.mu.RLock()
+	source := a.source
+	a.mu.RUnlock()
+	return source.Token()
+}
+
+// Installation returns github installation client for a given user login.
+func (a *App) Installation(ctx context.Context, login string) (*Installation, error) {
+	inst := a.fromCache(login)
+	if inst != nil {
+		return inst, nil
+	}
+
+	// The installation is requested directly, since the github library does not parse
+	// all the installation permissions.
+	req, err := a.Client.NewRequest("GET", "users/"+login+"/installation", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.machine-man-preview+json")
+	var install struct {
+		ID          int               `json:"id"`
+		Permissions map[string]string `json:"permissions"`
+	}
+	_, err = a.Client.Do(ctx, req, &install)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting user installation")
+	}
+
+	installID := install.ID
+
+	appID, _ := strconv.Atoi(a.cfg.AppID)
+	a.mu.RLock()
+	key := a.key
+	a.mu.RUnlock()
+	tr, err := ghinstallation.New(a.transport(), appID, installID, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "get install transport")
+	}
+	cl := &http.Client{Transport: tr}
+	inst = &Installation{
+		Client:      cl,
+		Github:      github.NewClient(cl),
+		ID:          installID,
+		Permissions: install.Permissions,
+	}
+	if a.cfg.UserAgent != "" {
+		inst.Github.UserAgent = a.cfg.UserAgent
+	}
+	a.toCache(login, inst)
+	return inst, nil
+}
+
+// TokenInstallation returns an installation with the clients of a user token, for
+// repositories that the app is not installed on. The permissions of the token are unknown.
+func (a *App) TokenInstallation(token string) *Installation {
+	cl := &http.Client{Transport: &oauth2.Transport{
+		Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+		Base:   a.transport(),
+	}}
+	inst := &Installation{Client: cl, Github: github.NewClient(cl)}
+	if a.cfg.UserAgent != "" {
+		inst.Github.UserAgent = a.cfg.UserAgent
+	}
+	return inst
+}
+
+// transport returns the base transport of the app requests, which sets the user agent.
+func (a *App) transport() http.RoundTripper {
+	base := a.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if a.cfg.UserAgent == "" {
+		return base
+	}
+	return userAgent{agent: a.cfg.UserAgent, base: base}
+}
+
+// userAgent is a transport that sets the User-Agent header of requests. It also applies to
+// clients that are created on top of the installation transport, such as the goreadme
+// client.
+type userAgent struct {
+	agent string
+	base  http.RoundTripper
+}
+
+func (t userAgent) RoundTrip(r *http.Request) (*http.Response, error) {
+	// A transport should not modify the original request.
+	r = r.Clone(r.Context())
+	r.Header.Set("User-Agent", t.agent)
+	return t.base.RoundTrip(r)
+}
+
+func (a *App) fromCache(login string) *Installation {
+	if a.cache == nil {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	i := a.cache.Get(cacheKey(login))
+	if i == nil {
+		return nil
+	}
+	return i.(*Installation)
+}
+
+func (a *App) toCache(login string, i *Installation) {
+	if a.cache == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache.Set(cacheKey(login), i)
+}
+
+func cacheKey(login string) string {
+	return "installation/" + login
+}
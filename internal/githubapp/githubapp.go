@@ -0,0 +1,129 @@
+// Package githubapp provides oauth2 Github app authentication client.
+//
+// According to https://developer.github.com/apps/building-github-apps/authenticating-with-github-apps.
+//
+// Usage
+//
+// 	func main() {
+// 		ctx := context.Background()
+// 		cfg := githubapp.Config{
+// 			AppID:      "1234",
+// 			PrivateKey: []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY")),
+// 		}
+// 		app, err := cfg.NewApp(ctx)
+// 		// Check err and use app...
+// 		installation, err := app.Installation(ctx, "<github-login>")
+// 		// Check err and use installation...
+// 	}
+//
+// The installation has an authenticated http client and github API client
+// ready to be used.
+//
+// The private key can be rotated without restarting the application, by setting the
+// LoadKey field of the config and calling the Reload method of the app.
+//
+// All the requests of the app and of its installations are sent with the UserAgent of the
+// config, if it is set.
+package githubapp
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jws"
+)
+
+// maxExpires is the maximum expiration time of github app token,
+// as defined by github.
+const maxExpires = 10 * time.Minute
+
+var defaultHeader = jws.Header{Algorithm: "RS256", Typ: "JWT"}
+
+// Config of Application authentication.
+type Config struct {
+	// AppID is the application ID from github app settings.
+	AppID string
+	// PrivateKey is the bytes of the private key from github app
+	// setting. It is used if LoadKey is not set.
+	PrivateKey []byte
+	// LoadKey loads the private key, for example from a file or from a secret manager.
+	// It is called when the app is created, and again whenever the app is reloaded.
+	LoadKey func() ([]byte, error)
+	// expire is the duration that the app token expire. 10 minutes
+	// is the maximal value.
+	Expire time.Duration
+	// UserAgent is the User-Agent header of all the requests of the app and of its
+	// installations. The Github API asks to identify the app and a contact in it.
+	UserAgent string
+}
+
+// key returns the private key and its bytes.
+func (c *Config) key() (*rsa.PrivateKey, []byte, error) {
+	b := c.PrivateKey
+	if c.LoadKey != nil {
+		var err error
+		b, err = c.LoadKey()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed loading private key")
+		}
+	}
+	pk, err := parseKey(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pk, b, nil
+}
+
+// parseKey parses a PEM encoded RSA private key, in PKCS1 or PKCS8 format.
+func parseKey(key []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if pk, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return pk, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing private key")
+	}
+	pk, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return pk, nil
+}
+
+// tokenSource returns a token source for github application.
+func (c *Config) tokenSource(pk *rsa.PrivateKey) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, appSource{
+		appID:  c.AppID,
+		expire: c.Expire,
+		pk:     pk,
+	})
+}
+
+type appSource struct {
+	appID  string
+	expire time.Duration
+	pk     *rsa.PrivateKey
+}
+
+func (js appSource) Token() (*oauth2.Token, error) {
+	// Adjust expire duration to the maximum allowed.
+	if js.expire <= 0 || js.expire > maxExpires {
+		js.expire = maxExpires
+	}
+	exp := time.Now().Add(js.expire)
+	claimSet := &jws.ClaimSet{Iss: js.appID, Exp: exp.Unix()}
+	h := defaultHeader
+	payload, err := jws.Encode(&h, claimSet, js.pk)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{TokenType: "bearer", AccessToken: payload, Expiry: exp}, nil
+}
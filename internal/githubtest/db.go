@@ -0,0 +1,54 @@
+package githubtest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/posener/goreadme-server/internal/migrations"
+
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// tables are truncated between tests.
+var tables = []string{
+	"jobs", "projects", "events", "steps", "settings", "tokens", "audit", "flags", "api_calls", "installations",
+	"plans", "artifacts", "leases", "sessions", "repo_hooks", "session_revocations",
+}
+
+// DB opens a migrated and empty test database. The database URL is taken from the
+// TEST_DATABASE_URL environment variable, and the test is skipped if it is not set.
+// The returned database should be closed when the test is done.
+func DB(t testing.TB) *gorm.DB {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("Connect to test DB: %s", err)
+	}
+	if err := migrations.Up(db); err != nil {
+		db.Close()
+		t.Fatalf("Migrate test DB: %s", err)
+	}
+	for _, table := range tables {
+		if err := db.Exec("TRUNCATE TABLE " + table).Error; err != nil {
+			db.Close()
+			t.Fatalf("Truncate %s: %s", table, err)
+		}
+	}
+	return db
+}
+
+// Fixtures inserts rows to the test database. The rows should be pointers to
+// database models.
+func Fixtures(t testing.TB, db *gorm.DB, rows ...interface{}) {
+	t.Helper()
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("Insert fixture %+v: %s", row, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package githubtest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+// HookRequest returns a signed webhook delivery request of a given event type, as
+// Github sends to the hook URL. The payload is JSON encoded, and signed with the
+// given secret.
+func HookRequest(url, event string, payload interface{}, secret string) *http.Request {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+
+	r := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-GitHub-Delivery", "delivery-"+hex.EncodeToString(mac.Sum(nil))[:8])
+	r.Header.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+// Deliver sends a webhook delivery to a handler and returns the recorded response.
+func Deliver(h http.Handler, event string, payload interface{}, secret string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, HookRequest("/github/hook", event, payload, secret))
+	return rec
+}
@@ -0,0 +1,842 @@
+// Package githubtest provides a fake Github API server and database fixtures for
+// integration tests of the job flow and the hook handlers.
+//
+// The fake server holds repositories in memory and implements the subset of the Github
+// API that the server uses: repositories, contents, readmes, refs, branches, commits, trees,
+// blobs, commits comparison, pull requests, pull request comments and app installations. It
+// serves enough of the API for goreadme to generate the readme of a repository.
+//
+// Usage
+//
+// 	s := githubtest.New()
+// 	defer s.Close()
+// 	s.AddRepo(&githubtest.Repo{
+// 		Owner: "posener",
+// 		Name:  "goreadme",
+// 		Files: map[string]string{"README.md": "# goreadme"},
+// 	})
+// 	client := s.Client()
+// 	// Use client...
+package githubtest
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/gorilla/mux"
+)
+
+// Repo is a repository in the fake server.
+type Repo struct {
+	Owner         string
+	Name          string
+	DefaultBranch string
+	Private       bool
+	Stars         int
+	// Install is the installation ID that the repository belongs to.
+	Install int64
+	// Files are the files in the default branch, by path.
+	Files map[string]string
+
+	// branches holds the files of each branch.
+	branches map[string]*branch
+	pulls    []*github.PullRequest
+	comments map[int][]string
+	// snapshots holds the files of every commit SHA that the repository had.
+	snapshots map[string]map[string]string
+}
+
+type branch struct {
+	sha   string
+	files map[string]string
+	// base is the SHA that the branch was created from.
+	base string
+}
+
+// Server is a fake Github API server.
+type Server struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	repos map[string]*Repo
+	// Requests records the method and path of all the requests that the
+	// server received.
+	Requests []string
+}
+
+// New starts a new fake Github server. It should be closed when done.
+func New() *Server {
+	s := &Server{repos: make(map[string]*Repo)}
+
+	m := mux.NewRouter()
+	m.Methods("GET").Path("/repos/{owner}/{repo}").HandlerFunc(s.getRepo)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/readme").HandlerFunc(s.getReadme)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/contents").HandlerFunc(s.getContents)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/contents/{path:.*}").HandlerFunc(s.getContents)
+	m.Methods("PUT").Path("/repos/{owner}/{repo}/contents/{path:.*}").HandlerFunc(s.updateFile)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/git/refs/heads/{branch}").HandlerFunc(s.getRef)
+	m.Methods("POST").Path("/repos/{owner}/{repo}/git/refs").HandlerFunc(s.createRef)
+	m.Methods("PATCH").Path("/repos/{owner}/{repo}/git/refs/heads/{branch}").HandlerFunc(s.updateRef)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/git/commits/{sha}").HandlerFunc(s.getCommit)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/git/trees/{sha}").HandlerFunc(s.getTree)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/git/blobs/{sha}").HandlerFunc(s.getBlob)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/commits").HandlerFunc(s.listCommits)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/compare/{base}...{head}").HandlerFunc(s.compare)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/branches/{branch}").HandlerFunc(s.getBranch)
+	m.Methods("GET").Path("/repos/{owner}/{repo}/pulls").HandlerFunc(s.listPulls)
+	m.Methods("POST").Path("/repos/{owner}/{repo}/pulls").HandlerFunc(s.createPull)
+	m.Methods("PATCH").Path("/repos/{owner}/{repo}/pulls/{number}").HandlerFunc(s.editPull)
+	m.Methods("POST").Path("/repos/{owner}/{repo}/issues/{number}/comments").HandlerFunc(s.createComment)
+	m.Methods("GET").Path("/users/{owner}/installation").HandlerFunc(s.findInstallation)
+	m.Methods("POST").Path("/installations/{id}/access_tokens").HandlerFunc(s.accessToken)
+	m.Methods("GET").Path("/installation/repositories").HandlerFunc(s.listInstallRepos)
+	m.NotFoundHandler = http.HandlerFunc(notFound)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.Requests = append(s.Requests, r.Method+" "+r.URL.Path)
+		s.mu.Unlock()
+		m.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// AddRepo adds a repository to the fake server.
+func (s *Server) AddRepo(r *Repo) {
+	if r.DefaultBranch == "" {
+		r.DefaultBranch = "master"
+	}
+	files := make(map[string]string, len(r.Files))
+	for path, content := range r.Files {
+		files[path] = content
+	}
+	sha := treeSHA(files)
+	r.branches = map[string]*branch{r.DefaultBranch: {sha: sha, files: files}}
+	r.snapshots = map[string]map[string]string{sha: copyFiles(files)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[r.Owner+"/"+r.Name] = r
+}
+
+// File returns the content of a file in a branch of a repository, and whether it exists.
+func (s *Server) File(owner, repo, branchName, path string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+repo]
+	if r == nil || r.branches[branchName] == nil {
+		return "", false
+	}
+	content, ok := r.branches[branchName].files[path]
+	return content, ok
+}
+
+// HeadSHA returns the head SHA of a branch of a repository.
+func (s *Server) HeadSHA(owner, repo, branchName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+repo]
+	if r == nil || r.branches[branchName] == nil {
+		return ""
+	}
+	return r.branches[branchName].sha
+}
+
+// Pulls returns the pull requests of a repository.
+func (s *Server) Pulls(owner, repo string) []*github.PullRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+repo]
+	if r == nil {
+		return nil
+	}
+	return append([]*github.PullRequest(nil), r.pulls...)
+}
+
+// Comments returns the comments that were posted on a pull request.
+func (s *Server) Comments(owner, repo string, number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repos[owner+"/"+repo]
+	if r == nil {
+		return nil
+	}
+	return append([]string(nil), r.comments[number]...)
+}
+
+// Client returns a Github API client of the fake server.
+func (s *Server) Client() *github.Client {
+	c := github.NewClient(s.HTTPClient())
+	c.BaseURL, _ = url.Parse(s.URL + "/")
+	return c
+}
+
+// HTTPClient returns an HTTP client that sends all requests to the fake server,
+// regardless of their host. It can be used with clients that use the default Github
+// API URL.
+func (s *Server) HTTPClient() *http.Client {
+	u, _ := url.Parse(s.URL)
+	return &http.Client{Transport: rewriteHost{host: u.Host}}
+}
+
+type rewriteHost struct {
+	host string
+}
+
+func (t rewriteHost) RoundTrip(r *http.Request) (*http.Response, error) {
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Scheme = "http"
+	u.Host = t.host
+	r2.URL = &u
+	r2.Host = t.host
+	return http.DefaultTransport.RoundTrip(r2)
+}
+
+func (s *Server) repo(w http.ResponseWriter, r *http.Request) *Repo {
+	vars := mux.Vars(r)
+	repo := s.repos[vars["owner"]+"/"+vars["repo"]]
+	if repo == nil {
+		notFound(w, r)
+	}
+	return repo
+}
+
+func (s *Server) getRepo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, repo.github())
+}
+
+func (s *Server) getReadme(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	b := repo.branch(r.URL.Query().Get("ref"))
+	if b == nil {
+		notFound(w, r)
+		return
+	}
+	for _, path := range b.paths() {
+		if strings.HasPrefix(strings.ToLower(path), "readme") && !strings.Contains(path, "/") {
+			writeJSON(w, http.StatusOK, content(path, b.files[path]))
+			return
+		}
+	}
+	notFound(w, r)
+}
+
+func (s *Server) getContents(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	b := repo.branch(r.URL.Query().Get("ref"))
+	path := mux.Vars(r)["path"]
+	if b == nil {
+		notFound(w, r)
+		return
+	}
+	if c, ok := b.files[path]; ok {
+		writeJSON(w, http.StatusOK, content(path, c))
+		return
+	}
+	// Directory listing. The file entries link to their blobs, which goreadme downloads.
+	var dir []*github.RepositoryContent
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	if path == "" {
+		prefix = ""
+	}
+	subdirs := make(map[string]bool)
+	for _, p := range b.paths() {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		name := p[len(prefix):]
+		if i := strings.Index(name, "/"); i >= 0 {
+			if name = name[:i]; !subdirs[name] {
+				subdirs[name] = true
+				dir = append(dir, &github.RepositoryContent{Type: github.String("dir"), Path: github.String(prefix + name), Name: github.String(name)})
+			}
+			continue
+		}
+		sha := blobSHA(b.files[p])
+		dir = append(dir, &github.RepositoryContent{
+			Type:    github.String("file"),
+			Path:    github.String(p),
+			Name:    github.String(name),
+			SHA:     github.String(sha),
+			GitURL:  github.String(s.URL + "/repos/" + repo.Owner + "/" + repo.Name + "/git/blobs/" + sha),
+			HTMLURL: github.String("https://github.com/" + repo.Owner + "/" + repo.Name + "/blob/" + repo.DefaultBranch + "/" + p),
+		})
+	}
+	if len(dir) == 0 {
+		notFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, dir)
+}
+
+func (s *Server) updateFile(w http.ResponseWriter, r *http.Request) {
+	var opts github.RepositoryContentFileOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	b := repo.branch(opts.GetBranch())
+	if b == nil {
+		notFound(w, r)
+		return
+	}
+	path := mux.Vars(r)["path"]
+	if old, ok := b.files[path]; ok && opts.GetSHA() != blobSHA(old) {
+		writeJSON(w, http.StatusConflict, map[string]string{"message": "sha does not match"})
+		return
+	}
+	b.files[path] = string(opts.Content)
+	b.sha = treeSHA(b.files)
+	repo.snapshots[b.sha] = copyFiles(b.files)
+	writeJSON(w, http.StatusOK, &github.RepositoryContentResponse{
+		Content: content(path, b.files[path]),
+		Commit:  github.Commit{SHA: github.String(b.sha), Message: opts.Message},
+	})
+}
+
+func (s *Server) getRef(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	name := mux.Vars(r)["branch"]
+	b := repo.branches[name]
+	if b == nil {
+		notFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, &github.Reference{
+		Ref:    github.String("refs/heads/" + name),
+		Object: &github.GitObject{Type: github.String("commit"), SHA: github.String(b.sha)},
+	})
+}
+
+func (s *Server) createRef(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	name := strings.TrimPrefix(req.Ref, "refs/heads/")
+	if repo.branches[name] != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Reference already exists"})
+		return
+	}
+	// Copy files from the branch that its head is the given SHA.
+	files := make(map[string]string)
+	for _, b := range repo.branches {
+		if b.sha == req.SHA {
+			for path, c := range b.files {
+				files[path] = c
+			}
+			break
+		}
+	}
+	repo.branches[name] = &branch{sha: req.SHA, files: files, base: req.SHA}
+	writeJSON(w, http.StatusCreated, &github.Reference{
+		Ref:    github.String(req.Ref),
+		Object: &github.GitObject{Type: github.String("commit"), SHA: github.String(req.SHA)},
+	})
+}
+
+func (s *Server) updateRef(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SHA   string `json:"sha"`
+		Force bool   `json:"force"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	name := mux.Vars(r)["branch"]
+	b := repo.branches[name]
+	files, ok := repo.snapshots[req.SHA]
+	if b == nil || !ok {
+		notFound(w, r)
+		return
+	}
+	if !req.Force && b.base != req.SHA {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Update is not a fast forward"})
+		return
+	}
+	b.sha, b.files, b.base = req.SHA, copyFiles(files), req.SHA
+	writeJSON(w, http.StatusOK, &github.Reference{
+		Ref:    github.String("refs/heads/" + name),
+		Object: &github.GitObject{Type: github.String("commit"), SHA: github.String(b.sha)},
+	})
+}
+
+// listCommits returns the head commit of a branch, given by the sha parameter, or of the
+// default branch.
+func (s *Server) listCommits(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	b := repo.branch(r.URL.Query().Get("sha"))
+	if b == nil {
+		notFound(w, r)
+		return
+	}
+	now := time.Now()
+	writeJSON(w, http.StatusOK, []*github.RepositoryCommit{{
+		SHA:    github.String(b.sha),
+		Commit: &github.Commit{Committer: &github.CommitAuthor{Date: &now}},
+	}})
+}
+
+// getCommit returns a commit. Since the commit SHAs of the fake server are computed from
+// the files, the tree SHA of a commit is its SHA.
+func (s *Server) getCommit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	sha := mux.Vars(r)["sha"]
+	if _, ok := repo.snapshots[sha]; !ok {
+		notFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, &github.Commit{
+		SHA:  github.String(sha),
+		Tree: &github.Tree{SHA: github.String(sha)},
+	})
+}
+
+// getTree returns the recursive tree of a commit, given as a branch name or a SHA.
+func (s *Server) getTree(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	sha := repo.resolve(mux.Vars(r)["sha"])
+	files, ok := repo.snapshots[sha]
+	if !ok {
+		notFound(w, r)
+		return
+	}
+	var entries []github.TreeEntry
+	dirs := make(map[string]bool)
+	for _, path := range (&branch{files: files}).paths() {
+		for dir := pathDir(path); dir != "" && !dirs[dir]; dir = pathDir(dir) {
+			dirs[dir] = true
+			entries = append(entries, github.TreeEntry{Path: github.String(dir), Type: github.String("tree")})
+		}
+		entries = append(entries, github.TreeEntry{
+			Path: github.String(path),
+			Type: github.String("blob"),
+			SHA:  github.String(blobSHA(files[path])),
+			Size: github.Int(len(files[path])),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].GetPath() < entries[j].GetPath() })
+	writeJSON(w, http.StatusOK, &github.Tree{SHA: github.String(sha), Entries: entries})
+}
+
+// getBlob returns the content of a blob of any of the commits of a repository. The raw
+// content is returned if it was requested by the Accept header, as goreadme does.
+func (s *Server) getBlob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	sha := mux.Vars(r)["sha"]
+	for _, files := range repo.snapshots {
+		for _, c := range files {
+			if blobSHA(c) != sha {
+				continue
+			}
+			if strings.Contains(r.Header.Get("Accept"), "raw") {
+				w.Write([]byte(c))
+				return
+			}
+			writeJSON(w, http.StatusOK, &github.Blob{
+				SHA:      github.String(sha),
+				Content:  github.String(base64.StdEncoding.EncodeToString([]byte(c))),
+				Encoding: github.String("base64"),
+				Size:     github.Int(len(c)),
+			})
+			return
+		}
+	}
+	notFound(w, r)
+}
+
+// compare compares two commits, given as branch names or SHAs. Since the fake server
+// does not keep commits history, a branch is considered ahead of the commit it was
+// created from, and diverged from any other commit.
+func (s *Server) compare(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	vars := mux.Vars(r)
+	base, head := repo.resolve(vars["base"]), repo.resolve(vars["head"])
+	mergeBase := base
+	if b := repo.branches[vars["head"]]; b != nil && b.base != "" {
+		mergeBase = b.base
+	}
+	baseFiles, ok1 := repo.snapshots[mergeBase]
+	headFiles, ok2 := repo.snapshots[head]
+	if !ok1 || !ok2 {
+		notFound(w, r)
+		return
+	}
+	status := "diverged"
+	switch {
+	case base == head:
+		status = "identical"
+	case mergeBase == base:
+		status = "ahead"
+	}
+	var files []github.CommitFile
+	for path := range mergeFiles(baseFiles, headFiles) {
+		if baseFiles[path] != headFiles[path] {
+			files = append(files, github.CommitFile{Filename: github.String(path)})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].GetFilename() < files[j].GetFilename() })
+	writeJSON(w, http.StatusOK, &github.CommitsComparison{
+		BaseCommit:      &github.RepositoryCommit{SHA: github.String(base)},
+		MergeBaseCommit: &github.RepositoryCommit{SHA: github.String(mergeBase)},
+		Status:          github.String(status),
+		Files:           files,
+	})
+}
+
+func (s *Server) getBranch(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	name := mux.Vars(r)["branch"]
+	b := repo.branches[name]
+	if b == nil {
+		notFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, &github.Branch{
+		Name:   github.String(name),
+		Commit: &github.RepositoryCommit{SHA: github.String(b.sha)},
+	})
+}
+
+func (s *Server) listPulls(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	base := r.URL.Query().Get("base")
+	head := r.URL.Query().Get("head")
+	if i := strings.Index(head, ":"); i >= 0 {
+		head = head[i+1:]
+	}
+	prs := []*github.PullRequest{}
+	for _, pr := range repo.pulls {
+		if pr.GetState() != "open" || (base != "" && pr.GetBase().GetRef() != base) ||
+			(head != "" && pr.GetHead().GetRef() != head) {
+			continue
+		}
+		prs = append(prs, pr)
+	}
+	writeJSON(w, http.StatusOK, prs)
+}
+
+func (s *Server) createPull(w http.ResponseWriter, r *http.Request) {
+	var req github.NewPullRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	now := time.Now()
+	pr := &github.PullRequest{
+		Number:    github.Int(len(repo.pulls) + 1),
+		State:     github.String("open"),
+		Title:     req.Title,
+		Body:      req.Body,
+		Head:      &github.PullRequestBranch{Ref: req.Head, Repo: repo.github()},
+		Base:      &github.PullRequestBranch{Ref: req.Base, Repo: repo.github()},
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+	repo.pulls = append(repo.pulls, pr)
+	writeJSON(w, http.StatusCreated, pr)
+}
+
+func (s *Server) editPull(w http.ResponseWriter, r *http.Request) {
+	var req github.PullRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	pr := repo.pull(mux.Vars(r)["number"])
+	if pr == nil {
+		notFound(w, r)
+		return
+	}
+	if req.State != nil {
+		pr.State = req.State
+	}
+	if req.Title != nil {
+		pr.Title = req.Title
+	}
+	if req.Body != nil {
+		pr.Body = req.Body
+	}
+	now := time.Now()
+	pr.UpdatedAt = &now
+	writeJSON(w, http.StatusOK, pr)
+}
+
+func (s *Server) createComment(w http.ResponseWriter, r *http.Request) {
+	var req github.IssueComment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo(w, r)
+	if repo == nil {
+		return
+	}
+	pr := repo.pull(mux.Vars(r)["number"])
+	if pr == nil {
+		notFound(w, r)
+		return
+	}
+	if repo.comments == nil {
+		repo.comments = make(map[int][]string)
+	}
+	repo.comments[pr.GetNumber()] = append(repo.comments[pr.GetNumber()], req.GetBody())
+	writeJSON(w, http.StatusCreated, &req)
+}
+
+func (s *Server) findInstallation(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owner := mux.Vars(r)["owner"]
+	for _, repo := range s.repos {
+		if repo.Owner == owner {
+			writeJSON(w, http.StatusOK, &github.Installation{
+				ID:      github.Int64(repo.Install),
+				Account: &github.User{Login: github.String(owner)},
+			})
+			return
+		}
+	}
+	notFound(w, r)
+}
+
+func (s *Server) accessToken(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      "token-" + mux.Vars(r)["id"],
+		"expires_at": time.Now().Add(time.Hour),
+	})
+}
+
+func (s *Server) listInstallRepos(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The installation is identified by the token that was issued in accessToken.
+	id, _ := strconv.ParseInt(strings.TrimPrefix(r.Header.Get("Authorization"), "token token-"), 10, 64)
+	var repos []*github.Repository
+	for _, repo := range s.repos {
+		if id == 0 || repo.Install == id {
+			repos = append(repos, repo.github())
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].GetFullName() < repos[j].GetFullName() })
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_count":  len(repos),
+		"repositories": repos,
+	})
+}
+
+func (r *Repo) github() *github.Repository {
+	return &github.Repository{
+		Name:            github.String(r.Name),
+		FullName:        github.String(r.Owner + "/" + r.Name),
+		Owner:           &github.User{Login: github.String(r.Owner), Name: github.String(r.Owner)},
+		DefaultBranch:   github.String(r.DefaultBranch),
+		Private:         github.Bool(r.Private),
+		StargazersCount: github.Int(r.Stars),
+	}
+}
+
+// pull returns a pull request by its number.
+func (r *Repo) pull(number string) *github.PullRequest {
+	for _, pr := range r.pulls {
+		if strconv.Itoa(pr.GetNumber()) == number {
+			return pr
+		}
+	}
+	return nil
+}
+
+// resolve returns the SHA of a branch name, or the given string if it is not a branch.
+func (r *Repo) resolve(ref string) string {
+	if b := r.branches[ref]; b != nil {
+		return b.sha
+	}
+	return ref
+}
+
+// branch returns a branch by name, or the default branch if the name is empty.
+func (r *Repo) branch(name string) *branch {
+	if name == "" {
+		name = r.DefaultBranch
+	}
+	return r.branches[name]
+}
+
+func (b *branch) paths() []string {
+	paths := make([]string, 0, len(b.files))
+	for path := range b.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func content(path, c string) *github.RepositoryContent {
+	return &github.RepositoryContent{
+		Type:     github.String("file"),
+		Encoding: github.String("base64"),
+		Path:     github.String(path),
+		Name:     github.String(path[strings.LastIndex(path, "/")+1:]),
+		Content:  github.String(base64.StdEncoding.EncodeToString([]byte(c))),
+		SHA:      github.String(blobSHA(c)),
+	}
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// blobSHA computes the git blob SHA of a content, as Github does.
+func blobSHA(c string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(fmt.Sprintf("blob %d\x00%s", len(c), c))))
+}
+
+// copyFiles returns a copy of a files map.
+func copyFiles(files map[string]string) map[string]string {
+	cp := make(map[string]string, len(files))
+	for path, c := range files {
+		cp[path] = c
+	}
+	return cp
+}
+
+// mergeFiles returns the set of paths of both files maps.
+func mergeFiles(a, b map[string]string) map[string]bool {
+	paths := make(map[string]bool, len(a)+len(b))
+	for path := range a {
+		paths[path] = true
+	}
+	for path := range b {
+		paths[path] = true
+	}
+	return paths
+}
+
+// treeSHA computes a fake commit SHA from the files of a branch.
+func treeSHA(files map[string]string) string {
+	h := sha1.New()
+	for _, path := range (&branch{files: files}).paths() {
+		fmt.Fprintf(h, "%s:%s\n", path, blobSHA(files[path]))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// pathDir returns the directory of a path, or an empty string for a path in the root.
+func pathDir(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
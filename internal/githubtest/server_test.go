@@ -0,0 +1,51 @@
+package githubtest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/posener/goreadme"
+)
+
+var testFiles = map[string]string{
+	"README.md":  "# hello\n",
+	"hello.go":   "// Package hello says hello to the world.\n//\n// It is used in tests of the fake Github server.\npackage hello\n\n// Hello returns a greeting.\nfunc Hello() string { return \"hello\" }\n",
+	"sub/sub.go": "// Package sub is a sub package of hello.\npackage sub\n",
+}
+
+func TestGoreadme(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.AddRepo(&Repo{Owner: "posener", Name: "hello", Install: 1, Files: testFiles})
+
+	var readme bytes.Buffer
+	err := goreadme.New(s.HTTPClient()).Create(context.Background(), "github.com/posener/hello", &readme)
+	if err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+	for _, want := range []string{"# hello", "Package hello says hello to the world.", "sub"} {
+		if !strings.Contains(readme.String(), want) {
+			t.Errorf("Readme does not contain %q:\n%s", want, readme.String())
+		}
+	}
+}
+
+func TestTree(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.AddRepo(&Repo{Owner: "posener", Name: "hello", Files: testFiles})
+
+	tree, _, err := s.Client().Git.GetTree(context.Background(), "posener", "hello", "master", true)
+	if err != nil {
+		t.Fatalf("GetTree: %s", err)
+	}
+	var paths []string
+	for _, e := range tree.Entries {
+		paths = append(paths, e.GetPath())
+	}
+	if got, want := strings.Join(paths, ","), "README.md,hello.go,sub,sub/sub.go"; got != want {
+		t.Errorf("Tree paths = %s, want %s", got, want)
+	}
+}
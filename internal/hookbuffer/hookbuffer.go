@@ -0,0 +1,96 @@
+// Package hookbuffer keeps webhook payloads on disk while they can't be handled, such as
+// when the database is unavailable, and replays them later in the order that they arrived.
+//
+// Every payload is stored in its own file, which is written to a temporary file and renamed,
+// so a replay never reads a partially written payload.
+package hookbuffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// suffix of buffered payload files.
+const suffix = ".json"
+
+// unsafe matches characters that are not allowed in the file names of payloads.
+var unsafe = regexp.MustCompile(`[^a-zA-Z0-9-]`)
+
+// Buffer stores webhook payloads in a directory.
+type Buffer struct {
+	dir string
+}
+
+// New returns a buffer that stores payloads in a directory, and creates the directory if
+// it does not exist.
+func New(dir string) (*Buffer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "creating hooks buffer directory %s", dir)
+	}
+	return &Buffer{dir: dir}, nil
+}
+
+// Save stores a payload. The id, such as the Github delivery ID, is used in the file name.
+func (b *Buffer) Save(id string, payload []byte) error {
+	name := fmt.Sprintf("%020d-%s", time.Now().UnixNano(), unsafe.ReplaceAllString(id, ""))
+	tmp := filepath.Join(b.dir, "."+name)
+	if err := ioutil.WriteFile(tmp, payload, 0600); err != nil {
+		return errors.Wrap(err, "writing payload")
+	}
+	err := os.Rename(tmp, filepath.Join(b.dir, name+suffix))
+	return errors.Wrap(err, "saving payload")
+}
+
+// Len returns the number of stored payloads.
+func (b *Buffer) Len() (int, error) {
+	names, err := b.names()
+	return len(names), err
+}
+
+// Replay calls handle with the stored payloads, from the oldest, and removes the payloads
+// that were handled. It stops on the first payload that can't be handled, and keeps it for
+// the next replay. It returns the number of payloads that were handled.
+func (b *Buffer) Replay(handle func(payload []byte) error) (int, error) {
+	names, err := b.names()
+	if err != nil {
+		return 0, err
+	}
+	for i, name := range names {
+		path := filepath.Join(b.dir, name)
+		payload, err := ioutil.ReadFile(path)
+		if err != nil {
+			return i, errors.Wrapf(err, "reading %s", name)
+		}
+		if err := handle(payload); err != nil {
+			return i, errors.Wrapf(err, "handling %s", name)
+		}
+		if err := os.Remove(path); err != nil {
+			return i + 1, errors.Wrapf(err, "removing %s", name)
+		}
+	}
+	return len(names), nil
+}
+
+// names returns the file names of the stored payloads, from the oldest.
+func (b *Buffer) names() ([]string, error) {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing buffered payloads")
+	}
+	var names []string
+	for _, f := range files {
+		if name := f.Name(); !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, suffix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
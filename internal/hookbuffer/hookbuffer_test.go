@@ -0,0 +1,120 @@
+package hookbuffer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newBuffer(t *testing.T) *Buffer {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "hookbuffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(filepath.Join(dir, "hooks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func save(t *testing.T, b *Buffer, payloads ...string) {
+	t.Helper()
+	for i, p := range payloads {
+		if err := b.Save(fmt.Sprintf("delivery/%d", i), []byte(p)); err != nil {
+			t.Fatalf("Save: %s", err)
+		}
+	}
+}
+
+func assertLen(t *testing.T, b *Buffer, want int) {
+	t.Helper()
+	got, err := b.Len()
+	if err != nil {
+		t.Fatalf("Len: %s", err)
+	}
+	if got != want {
+		t.Errorf("Len() = %d, want %d", got, want)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	b := newBuffer(t)
+	defer os.RemoveAll(filepath.Dir(b.dir))
+
+	save(t, b, "1", "2", "3")
+	assertLen(t, b, 3)
+
+	var got []string
+	n, err := b.Replay(func(payload []byte) error {
+		got = append(got, string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay: %s", err)
+	}
+	if n != 3 {
+		t.Errorf("Replay() = %d, want 3", n)
+	}
+	if want := []string{"1", "2", "3"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Replayed %v, want %v", got, want)
+	}
+	assertLen(t, b, 0)
+}
+
+func TestReplayKeepsFailed(t *testing.T) {
+	b := newBuffer(t)
+	defer os.RemoveAll(filepath.Dir(b.dir))
+
+	save(t, b, "1", "2", "3")
+
+	var got []string
+	n, err := b.Replay(func(payload []byte) error {
+		if string(payload) == "2" {
+			return fmt.Errorf("database unavailable")
+		}
+		got = append(got, string(payload))
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Replay succeeded with a failing payload")
+	}
+	if n != 1 {
+		t.Errorf("Replay() = %d, want 1", n)
+	}
+	if want := []string{"1"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Replayed %v, want %v", got, want)
+	}
+	// The failed payload and the payloads after it are kept for the next replay.
+	assertLen(t, b, 2)
+
+	got = nil
+	n, err = b.Replay(func(payload []byte) error {
+		got = append(got, string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay: %s", err)
+	}
+	if n != 2 {
+		t.Errorf("Replay() = %d, want 2", n)
+	}
+	if want := []string{"2", "3"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Replayed %v, want %v", got, want)
+	}
+	assertLen(t, b, 0)
+}
+
+func TestLenIgnoresPartialWrites(t *testing.T) {
+	b := newBuffer(t)
+	defer os.RemoveAll(filepath.Dir(b.dir))
+
+	save(t, b, "1")
+	if err := ioutil.WriteFile(filepath.Join(b.dir, ".partial"), []byte("2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	assertLen(t, b, 1)
+}
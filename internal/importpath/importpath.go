@@ -0,0 +1,122 @@
+// Package importpath resolves the Github repositories of Go import paths.
+//
+// Import paths can be given as godoc.org or pkg.go.dev URLs, or as plain import paths. Paths
+// on github.com are resolved directly, and other paths, such as vanity import paths, are
+// resolved with their go-import meta tag, like the go tool does.
+package importpath
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// requestTimeout is the timeout of fetching the go-import meta tag of an import path.
+const requestTimeout = 10 * time.Second
+
+// docHosts are the hosts of Go documentation sites, which URLs have the import path as
+// their path.
+var docHosts = map[string]bool{
+	"pkg.go.dev":    true,
+	"godoc.org":     true,
+	"www.godoc.org": true,
+}
+
+var (
+	metaPattern    = regexp.MustCompile(`<meta\s+name="go-import"\s+content="([^"]+)"`)
+	versionPattern = regexp.MustCompile(`@[^/]*`)
+)
+
+// Parse returns the import path of a documentation URL or of an import path.
+func Parse(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if !strings.Contains(input, "://") {
+		input = "https://" + input
+	}
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid URL")
+	}
+	path := u.Host + u.Path
+	if docHosts[u.Host] {
+		path = strings.TrimPrefix(u.Path, "/")
+		// pkg.go.dev URLs may have a version.
+		path = versionPattern.ReplaceAllString(path, "")
+	}
+	path = strings.Trim(path, "/")
+	if !strings.Contains(path, ".") || !strings.Contains(path, "/") {
+		return "", errors.Errorf("%q is not an import path", path)
+	}
+	return path, nil
+}
+
+// Repo returns the Github repository of an import path.
+func Repo(ctx context.Context, client *http.Client, path string) (owner, repo string, err error) {
+	if owner, repo, ok := githubRepo(path); ok {
+		return owner, repo, nil
+	}
+	repoURL, err := goImport(ctx, client, path)
+	if err != nil {
+		return "", "", err
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "invalid repository URL %q", repoURL)
+	}
+	owner, repo, ok := githubRepo(u.Host + strings.TrimSuffix(u.Path, ".git"))
+	if !ok {
+		return "", "", errors.Errorf("%s is hosted on %s, which is not Github", path, u.Host)
+	}
+	return owner, repo, nil
+}
+
+// githubRepo returns the repository of an import path on github.com.
+func githubRepo(path string) (owner, repo string, ok bool) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 || parts[0] != "github.com" || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// goImport returns the repository URL of the go-import meta tag of an import path. The
+// meta tag of a sub package path is the meta tag of its module.
+func goImport(ctx context.Context, client *http.Client, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://%s?go-get=1", path), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed getting %s", path)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("getting %s returned %s", path, resp.Status)
+	}
+	// The meta tag is in the head of the page.
+	body := make([]byte, 64<<10)
+	n, err := io.ReadFull(resp.Body, body)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", errors.Wrapf(err, "failed reading %s", path)
+	}
+	for _, m := range metaPattern.FindAllStringSubmatch(string(body[:n]), -1) {
+		fields := strings.Fields(m[1])
+		if len(fields) != 3 || fields[1] != "git" {
+			continue
+		}
+		if path == fields[0] || strings.HasPrefix(path, fields[0]+"/") {
+			return fields[2], nil
+		}
+	}
+	return "", errors.Errorf("%s has no go-import meta tag of a git repository", path)
+}
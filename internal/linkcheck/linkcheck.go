@@ -0,0 +1,145 @@
+// Package linkcheck finds dead links in readme files.
+//
+// Links are checked with HEAD requests, falling back to GET requests for servers that
+// don't support HEAD. A link is dead if its server responds that it was not found, or
+// if it can't be reached. Results are cached, since the same links, such as badges,
+// appear in many readmes.
+package linkcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// concurrency is the maximal number of concurrent requests of a check.
+	concurrency = 8
+	// requestTimeout is the timeout of checking a single link.
+	requestTimeout = 10 * time.Second
+	// cacheExpiry is the duration that link results are cached.
+	cacheExpiry = time.Hour
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s()<>"'\x60\]]+`)
+
+// Dead is a dead link.
+type Dead struct {
+	URL string
+	// Reason describes why the link is dead.
+	Reason string
+}
+
+func (d Dead) String() string {
+	return fmt.Sprintf("dead link %s: %s", d.URL, d.Reason)
+}
+
+// Checker checks links.
+type Checker struct {
+	client *http.Client
+	cache  *cache.Cache
+}
+
+// New returns a link checker.
+func New() *Checker {
+	return &Checker{
+		client: &http.Client{Timeout: requestTimeout},
+		cache:  cache.New(cacheExpiry, 10*time.Minute),
+	}
+}
+
+// Check returns the dead links of a readme, in the order that they appear.
+func (c *Checker) Check(ctx context.Context, readme string) []Dead {
+	urls := URLs(readme)
+	reasons := make([]string, len(urls))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			reasons[i] = c.check(ctx, u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	var dead []Dead
+	for i, reason := range reasons {
+		if reason != "" {
+			dead = append(dead, Dead{URL: urls[i], Reason: reason})
+		}
+	}
+	return dead
+}
+
+// check returns the reason that a link is dead, or an empty string if it is alive.
+func (c *Checker) check(ctx context.Context, u string) string {
+	if v, ok := c.cache.Get(u); ok {
+		return v.(string)
+	}
+	code, err := c.request(ctx, http.MethodHead, u)
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented) {
+		code, err = c.request(ctx, http.MethodGet, u)
+	}
+	if ctx.Err() != nil {
+		// Don't cache the result of an interrupted check.
+		return ""
+	}
+	var reason string
+	switch {
+	case err != nil:
+		reason = err.Error()
+	case code == http.StatusNotFound || code == http.StatusGone:
+		reason = http.StatusText(code)
+	}
+	c.cache.Set(u, reason, cache.DefaultExpiration)
+	return reason
+}
+
+func (c *Checker) request(ctx context.Context, method, u string) (int, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "goreadme-linkcheck")
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// URLs returns the unique absolute URLs in a readme, outside of code blocks.
+func URLs(readme string) []string {
+	var (
+		urls   []string
+		seen   = map[string]bool{}
+		fenced bool
+	)
+	for _, line := range strings.Split(readme, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		for _, u := range urlPattern.FindAllString(line, -1) {
+			u = strings.TrimRight(u, ".,;:")
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	return urls
+}
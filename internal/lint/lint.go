@@ -0,0 +1,125 @@
+// Package lint checks generated readme files for common markdown issues.
+//
+// The checks are: lines that are longer than a maximal length, relative links to paths
+// that do not exist in the repository, and headings that repeat the text of a sibling
+// heading. Code blocks are not checked.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rules of findings.
+const (
+	RuleLineLength       = "line-length"
+	RuleBrokenLink       = "broken-link"
+	RuleDuplicateHeading = "duplicate-heading"
+)
+
+// MaxLineLength is the default maximal line length.
+const MaxLineLength = 120
+
+// Finding is an issue in a readme.
+type Finding struct {
+	// Line is the line number of the issue, starting at 1.
+	Line    int
+	Rule    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s (%s)", f.Line, f.Message, f.Rule)
+}
+
+// Options of the linter.
+type Options struct {
+	// MaxLineLength is the maximal line length. Zero disables the line length check.
+	MaxLineLength int
+	// Exists returns whether a path exists in the repository. Nil disables the broken links
+	// check.
+	Exists func(path string) bool
+}
+
+var (
+	heading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	link    = regexp.MustCompile(`\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+)
+
+// Lint returns the findings in a readme.
+func Lint(readme string, opts Options) []Finding {
+	var (
+		findings []Finding
+		fenced   bool
+		// parents are the texts of the current heading of each level.
+		parents [7]string
+		seen    = map[string]bool{}
+	)
+	for i, line := range strings.Split(readme, "\n") {
+		num := i + 1
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+
+		// Long lines without spaces, such as long URLs, can't be wrapped.
+		if opts.MaxLineLength > 0 && len(line) > opts.MaxLineLength && strings.Contains(strings.TrimSpace(line), " ") {
+			findings = append(findings, Finding{
+				Line:    num,
+				Rule:    RuleLineLength,
+				Message: fmt.Sprintf("line is %d characters long, longer than %d", len(line), opts.MaxLineLength),
+			})
+		}
+
+		if m := heading.FindStringSubmatch(line); m != nil {
+			level, text := len(m[1]), m[2]
+			key := fmt.Sprintf("%s/%d/%s", strings.Join(parents[:level], "/"), level, text)
+			if seen[key] {
+				findings = append(findings, Finding{
+					Line:    num,
+					Rule:    RuleDuplicateHeading,
+					Message: fmt.Sprintf("heading %q repeats a previous heading", text),
+				})
+			}
+			seen[key] = true
+			parents[level] = text
+			for l := level + 1; l < len(parents); l++ {
+				parents[l] = ""
+			}
+			continue
+		}
+
+		if opts.Exists == nil {
+			continue
+		}
+		for _, m := range link.FindAllStringSubmatch(line, -1) {
+			target := relativePath(m[1])
+			if target != "" && !opts.Exists(target) {
+				findings = append(findings, Finding{
+					Line:    num,
+					Rule:    RuleBrokenLink,
+					Message: fmt.Sprintf("link to %s, which does not exist in the repository", m[1]),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// relativePath returns the repository path of a relative link target, or an empty string
+// if it is not a relative link.
+func relativePath(target string) string {
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "mailto:") {
+		return ""
+	}
+	if i := strings.IndexAny(target, "#?"); i >= 0 {
+		target = target[:i]
+	}
+	target = strings.TrimPrefix(target, "./")
+	target = strings.Trim(target, "/")
+	return target
+}
@@ -0,0 +1,85 @@
+// Package logging configures the server logger, and redacts secrets from the log entries.
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// secretField matches JSON fields that hold secrets, such as in Github hook payloads.
+	secretField = regexp.MustCompile(`(?i)("[a-z_]*(?:token|secret|password)"\s*:\s*)"[^"]*"`)
+	// githubToken matches Github access tokens.
+	githubToken = regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{20,}|v1\.[0-9a-f]{40})\b`)
+	// urlPassword matches the password part of URLs, such as the database URL.
+	urlPassword = regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+@`)
+)
+
+// Setup configures the standard logger with a format, which is "text" or "json", and a
+// level. The given secrets, and tokens in general, are redacted from all log entries.
+func Setup(format, level string, secrets ...string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return errors.Wrap(err, "invalid log level")
+	}
+	var f logrus.Formatter
+	switch format {
+	case "text":
+		f = &logrus.TextFormatter{}
+	case "json":
+		f = &logrus.JSONFormatter{}
+	default:
+		return errors.Errorf("invalid log format %q, expected text or json", format)
+	}
+	r := &redactor{Formatter: f}
+	for _, s := range secrets {
+		if s != "" {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	logrus.SetLevel(lvl)
+	logrus.SetFormatter(r)
+	return nil
+}
+
+// redactor is a formatter that redacts secrets from the log message and fields.
+type redactor struct {
+	logrus.Formatter
+	secrets []string
+}
+
+func (r *redactor) Format(e *logrus.Entry) ([]byte, error) {
+	// Copy the entry, since its fields may be shared with other entries.
+	c := *e
+	c.Message = r.redact(e.Message)
+	c.Data = make(logrus.Fields, len(e.Data))
+	for k, v := range e.Data {
+		switch v := v.(type) {
+		case string:
+			c.Data[k] = r.redact(v)
+		case error:
+			c.Data[k] = r.redact(v.Error())
+		case fmt.Stringer:
+			c.Data[k] = r.redact(v.String())
+		default:
+			c.Data[k] = v
+		}
+	}
+	return r.Formatter.Format(&c)
+}
+
+func (r *redactor) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	s = secretField.ReplaceAllString(s, `${1}"`+redacted+`"`)
+	s = githubToken.ReplaceAllString(s, redacted)
+	s = urlPassword.ReplaceAllString(s, "${1}"+redacted+"@")
+	return s
+}
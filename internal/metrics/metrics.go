@@ -0,0 +1,170 @@
+// Package metrics exports server metrics in the Prometheus text format.
+//
+// Only histograms are supported, which is what the server needs to tell apart the time
+// that jobs spend waiting for the Github API from the time they spend generating readmes.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the default histogram buckets, in seconds.
+var DefaultBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+var (
+	// GithubRequests is the latency of Github API requests by endpoint family and by status
+	// class.
+	GithubRequests = NewHistogram("goreadme_github_request_duration_seconds",
+		"Latency of Github API requests.", DefaultBuckets, "endpoint", "status")
+	// Generations is the duration of readme generations, which excludes the Github API
+	// requests of the job, but includes the requests of goreadme itself.
+	Generations = NewHistogram("goreadme_generation_duration_seconds",
+		"Duration of readme generations.", DefaultBuckets)
+	// Jobs is the duration of jobs by their final status.
+	Jobs = NewHistogram("goreadme_job_duration_seconds",
+		"Duration of jobs.", DefaultBuckets, "status")
+)
+
+var registry struct {
+	sync.Mutex
+	histograms []*Histogram
+}
+
+// Histogram counts observations in buckets, per label values.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	labels  []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram returns a histogram with the given buckets and label names, and registers
+// it to be exported by the Handler.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, buckets: buckets, labels: labels, series: make(map[string]*series)}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.histograms = append(registry.histograms, h)
+	return h
+}
+
+// Observe records a value with the given label values, in the order of the label names.
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &series{values: values, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// Since records the duration since a start time, in seconds.
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelSet(s.values, strconv.FormatFloat(b, 'g', -1, 64)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelSet(s.values, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, h.labelSet(s.values, ""), s.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelSet(s.values, ""), s.count)
+	}
+}
+
+// labelSet formats the labels of a series, with the bucket label if le is not empty.
+func (h *Histogram) labelSet(values []string, le string) string {
+	var pairs []string
+	for i, name := range h.labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, values[i]))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=%q", le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Handler exports the registered histograms.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		registry.Lock()
+		defer registry.Unlock()
+		for _, h := range registry.histograms {
+			h.write(w)
+		}
+	})
+}
+
+// Transport returns an http.RoundTripper that records the latency of Github API requests.
+func Transport(t http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := t.RoundTrip(r)
+		status := "error"
+		if err == nil {
+			status = fmt.Sprintf("%dxx", resp.StatusCode/100)
+		}
+		GithubRequests.Since(start, endpointOf(r.URL.Path), status)
+		return resp, err
+	})
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// endpointOf returns the endpoint family of a Github API path. Repository endpoints are
+// grouped by the path part that follows the repository, such as contents or pulls, and
+// other endpoints by their first path part, such as users or installation.
+func endpointOf(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case parts[0] == "repos" && len(parts) > 3:
+		return parts[3]
+	case parts[0] == "repos":
+		return "repos"
+	default:
+		return parts[0]
+	}
+}
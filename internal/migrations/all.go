@@ -0,0 +1,465 @@
+package migrations
+
+// all is the ordered list of migrations.
+var all = []Migration{
+	{
+		Version: 1,
+		Name:    "projects and jobs",
+		// Tables are created only if they don't exist since they were
+		// previously created by gorm's AutoMigrate.
+		Up: `
+CREATE TABLE IF NOT EXISTS projects (
+	install        bigint,
+	repo           text,
+	owner          text,
+	last_job       integer,
+	head_sha       text,
+	pr             integer,
+	message        text,
+	status         text,
+	default_branch text,
+	private        boolean,
+	stars          integer,
+	created_at     timestamp with time zone,
+	updated_at     timestamp with time zone,
+	PRIMARY KEY (repo, owner)
+);
+
+CREATE TABLE IF NOT EXISTS jobs (
+	install        bigint,
+	repo           text,
+	owner          text,
+	last_job       integer,
+	head_sha       text,
+	pr             integer,
+	message        text,
+	status         text,
+	default_branch text,
+	private        boolean,
+	stars          integer,
+	created_at     timestamp with time zone,
+	updated_at     timestamp with time zone,
+	num            serial,
+	duration       bigint,
+	debug          text,
+	trigger        text,
+	PRIMARY KEY (repo, owner, num)
+);
+
+DROP TABLE IF EXISTS schema_version;
+`,
+		Down: `
+DROP TABLE jobs;
+DROP TABLE projects;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "events, steps, settings, tokens and audit",
+		Up: `
+-- Received webhook deliveries.
+CREATE TABLE events (
+	id         bigserial PRIMARY KEY,
+	delivery   text NOT NULL,
+	type       text NOT NULL,
+	install    bigint,
+	owner      text,
+	repo       text,
+	payload    text,
+	created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX events_delivery ON events (delivery);
+
+-- Steps of a job run.
+CREATE TABLE steps (
+	id         bigserial PRIMARY KEY,
+	owner      text NOT NULL,
+	repo       text NOT NULL,
+	num        integer NOT NULL,
+	name       text NOT NULL,
+	status     text NOT NULL,
+	message    text,
+	duration   bigint,
+	created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX steps_job ON steps (owner, repo, num);
+
+-- Settings of an installation or of a project. Installation settings have
+-- an empty repo.
+CREATE TABLE settings (
+	install    bigint NOT NULL,
+	owner      text NOT NULL,
+	repo       text NOT NULL DEFAULT '',
+	key        text NOT NULL,
+	value      text NOT NULL,
+	updated_at timestamp with time zone NOT NULL DEFAULT now(),
+	PRIMARY KEY (owner, repo, key)
+);
+
+-- API tokens issued to users. Only the token hash is stored.
+CREATE TABLE tokens (
+	id           bigserial PRIMARY KEY,
+	login        text NOT NULL,
+	name         text NOT NULL,
+	hash         text NOT NULL UNIQUE,
+	created_at   timestamp with time zone NOT NULL DEFAULT now(),
+	last_used_at timestamp with time zone,
+	expires_at   timestamp with time zone
+);
+CREATE INDEX tokens_login ON tokens (login);
+
+-- Audit log of user actions.
+CREATE TABLE audit (
+	id         bigserial PRIMARY KEY,
+	login      text NOT NULL,
+	install    bigint,
+	action     text NOT NULL,
+	target     text,
+	details    text,
+	created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX audit_login ON audit (login);
+`,
+		Down: `
+DROP TABLE audit;
+DROP TABLE tokens;
+DROP TABLE settings;
+DROP TABLE steps;
+DROP TABLE events;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "feature flags",
+		Up: `
+-- Feature flag values. Install 0 holds the global value.
+CREATE TABLE flags (
+	name       text NOT NULL,
+	install    bigint NOT NULL DEFAULT 0,
+	enabled    boolean NOT NULL,
+	updated_at timestamp with time zone NOT NULL DEFAULT now(),
+	PRIMARY KEY (name, install)
+);
+`,
+		Down: `
+DROP TABLE flags;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "api calls",
+		Up: `
+-- Number of Github API calls per installation per day.
+CREATE TABLE api_calls (
+	install bigint NOT NULL,
+	day     date NOT NULL,
+	calls   integer NOT NULL,
+	PRIMARY KEY (install, day)
+);
+CREATE INDEX jobs_install_created_at ON jobs (install, created_at);
+`,
+		Down: `
+DROP INDEX jobs_install_created_at;
+DROP TABLE api_calls;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "installations",
+		Up: `
+-- State of Github app installations.
+CREATE TABLE installations (
+	id           bigint PRIMARY KEY,
+	account      text NOT NULL,
+	suspended    boolean NOT NULL DEFAULT false,
+	suspended_at timestamp with time zone,
+	updated_at   timestamp with time zone
+);
+`,
+		Down: `
+DROP TABLE installations;
+`,
+	},
+	{
+		Version: 6,
+		Name:    "job trigger commit",
+		Up: `
+ALTER TABLE jobs ADD COLUMN trigger_message text;
+ALTER TABLE jobs ADD COLUMN trigger_author text;
+`,
+		Down: `
+ALTER TABLE jobs DROP COLUMN trigger_author;
+ALTER TABLE jobs DROP COLUMN trigger_message;
+`,
+	},
+	{
+		Version: 7,
+		Name:    "stats",
+		Up: `
+-- Summary of the home page stats, refreshed periodically by the server.
+CREATE MATERIALIZED VIEW stats AS SELECT
+	(SELECT count(*) FROM projects) AS total_projects,
+	(SELECT count(*) FROM jobs WHERE created_at > now() - interval '1 day') AS jobs_last_day,
+	now() AS updated_at;
+CREATE MATERIALIZED VIEW top_projects AS
+	SELECT * FROM projects WHERE private = FALSE ORDER BY stars DESC LIMIT 10;
+`,
+		Down: `
+DROP MATERIALIZED VIEW top_projects;
+DROP MATERIALIZED VIEW stats;
+`,
+	},
+	{
+		Version: 8,
+		Name:    "readme quality",
+		Up: `
+ALTER TABLE projects ADD COLUMN quality integer NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN quality integer NOT NULL DEFAULT 0;
+`,
+		Down: `
+ALTER TABLE jobs DROP COLUMN quality;
+ALTER TABLE projects DROP COLUMN quality;
+`,
+	},
+	{
+		Version: 9,
+		Name:    "stale PR reminders",
+		Up: `
+ALTER TABLE projects ADD COLUMN pr_created_at timestamp with time zone;
+ALTER TABLE projects ADD COLUMN pr_reminded_at timestamp with time zone;
+ALTER TABLE jobs ADD COLUMN pr_created_at timestamp with time zone;
+ALTER TABLE jobs ADD COLUMN pr_reminded_at timestamp with time zone;
+`,
+		Down: `
+ALTER TABLE jobs DROP COLUMN pr_reminded_at;
+ALTER TABLE jobs DROP COLUMN pr_created_at;
+ALTER TABLE projects DROP COLUMN pr_reminded_at;
+ALTER TABLE projects DROP COLUMN pr_created_at;
+`,
+	},
+	{
+		Version: 10,
+		Name:    "generation source",
+		Up: `
+ALTER TABLE projects ADD COLUMN source_sha text;
+ALTER TABLE projects ADD COLUMN source_tree text;
+ALTER TABLE jobs ADD COLUMN source_sha text;
+ALTER TABLE jobs ADD COLUMN source_tree text;
+`,
+		Down: `
+ALTER TABLE jobs DROP COLUMN source_tree;
+ALTER TABLE jobs DROP COLUMN source_sha;
+ALTER TABLE projects DROP COLUMN source_tree;
+ALTER TABLE projects DROP COLUMN source_sha;
+`,
+	},
+	{
+		Version: 11,
+		Name:    "plans",
+		Up: `
+-- Plans of installations. Installations without a plan are on the free plan.
+CREATE TABLE plans (
+	install    bigint PRIMARY KEY,
+	plan       text NOT NULL,
+	updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+`,
+		Down: `
+DROP TABLE plans;
+`,
+	},
+	{
+		Version: 12,
+		Name:    "artifacts",
+		Up: `
+-- Generated readmes, keyed by head commit and configuration hash.
+CREATE TABLE artifacts (
+	owner       text NOT NULL,
+	repo        text NOT NULL,
+	head_sha    text NOT NULL,
+	config_hash text NOT NULL,
+	content     text NOT NULL,
+	created_at  timestamp with time zone NOT NULL DEFAULT now(),
+	PRIMARY KEY (owner, repo, head_sha, config_hash)
+);
+`,
+		Down: `
+DROP TABLE artifacts;
+`,
+	},
+	{
+		Version: 13,
+		Name:    "job leases",
+		Up: `
+-- Leases of queued and running jobs by server instances.
+CREATE TABLE leases (
+	owner        text NOT NULL,
+	repo         text NOT NULL,
+	num          integer NOT NULL,
+	instance     text NOT NULL,
+	heartbeat_at timestamp with time zone NOT NULL,
+	PRIMARY KEY (owner, repo, num)
+);
+`,
+		Down: `
+DROP TABLE leases;
+`,
+	},
+	{
+		Version: 14,
+		Name:    "job status constraint",
+		Up: `
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN (
+	'Queued', 'Started', 'Retrying', 'Success', 'Failed', 'Skipped', 'Cancelled', 'Aborted', 'Suspended', 'No Permissions', 'Plan Limit'
+));
+ALTER TABLE projects ADD CONSTRAINT projects_status_check CHECK (status IN (
+	'Queued', 'Started', 'Retrying', 'Success', 'Failed', 'Skipped', 'Cancelled', 'Aborted', 'Suspended', 'No Permissions', 'Plan Limit'
+));
+`,
+		Down: `
+ALTER TABLE projects DROP CONSTRAINT projects_status_check;
+ALTER TABLE jobs DROP CONSTRAINT jobs_status_check;
+`,
+	},
+	{
+		Version: 15,
+		Name:    "sessions",
+		Up: `
+-- User sessions, when sessions are stored in the database.
+CREATE TABLE sessions (
+	id         text PRIMARY KEY,
+	login      text NOT NULL,
+	data       text NOT NULL,
+	created_at timestamp with time zone NOT NULL DEFAULT now(),
+	expires_at timestamp with time zone NOT NULL
+);
+CREATE INDEX sessions_login ON sessions (login);
+CREATE INDEX sessions_expires_at ON sessions (expires_at);
+`,
+		Down: `
+DROP TABLE sessions;
+`,
+	},
+	{
+		Version: 16,
+		Name:    "jobs per day stats",
+		Up: `
+-- Number of jobs in each of the last 30 days, for the home page stats.
+CREATE MATERIALIZED VIEW jobs_per_day AS
+	SELECT days.day, count(jobs.num) AS jobs
+	FROM generate_series(current_date - 29, current_date, interval '1 day') AS days(day)
+	LEFT JOIN jobs ON date_trunc('day', jobs.created_at) = days.day
+	GROUP BY days.day;
+`,
+		Down: `
+DROP MATERIALIZED VIEW jobs_per_day;
+`,
+	},
+	{
+		Version: 17,
+		Name:    "job warnings",
+		Up: `
+ALTER TABLE jobs ADD COLUMN warnings text NOT NULL DEFAULT '';
+`,
+		Down: `
+ALTER TABLE jobs DROP COLUMN warnings;
+`,
+	},
+	{
+		Version: 18,
+		Name:    "job notes",
+		Up: `
+-- Notes of admins about jobs, and the state of jobs that were resolved by admins.
+ALTER TABLE jobs ADD COLUMN note text NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN note_author text NOT NULL DEFAULT '';
+ALTER TABLE jobs DROP CONSTRAINT jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN (
+	'Queued', 'Started', 'Retrying', 'Success', 'Failed', 'Skipped', 'Cancelled', 'Aborted', 'Suspended', 'No Permissions', 'Plan Limit', 'Resolved'
+));
+ALTER TABLE projects DROP CONSTRAINT projects_status_check;
+ALTER TABLE projects ADD CONSTRAINT projects_status_check CHECK (status IN (
+	'Queued', 'Started', 'Retrying', 'Success', 'Failed', 'Skipped', 'Cancelled', 'Aborted', 'Suspended', 'No Permissions', 'Plan Limit', 'Resolved'
+));
+`,
+		Down: `
+UPDATE jobs SET status = 'Cancelled' WHERE status = 'Resolved';
+UPDATE projects SET status = 'Cancelled' WHERE status = 'Resolved';
+ALTER TABLE projects DROP CONSTRAINT projects_status_check;
+ALTER TABLE projects ADD CONSTRAINT projects_status_check CHECK (status IN (
+	'Queued', 'Started', 'Retrying', 'Success', 'Failed', 'Skipped', 'Cancelled', 'Aborted', 'Suspended', 'No Permissions', 'Plan Limit'
+));
+ALTER TABLE jobs DROP CONSTRAINT jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN (
+	'Queued', 'Started', 'Retrying', 'Success', 'Failed', 'Skipped', 'Cancelled', 'Aborted', 'Suspended', 'No Permissions', 'Plan Limit'
+));
+ALTER TABLE jobs DROP COLUMN note_author;
+ALTER TABLE jobs DROP COLUMN note;
+`,
+	},
+	{
+		Version: 19,
+		Name:    "repository webhooks",
+		Up: `
+-- Repository webhooks of users that don't install the Github app, with their encrypted
+-- tokens.
+CREATE TABLE repo_hooks (
+	owner      text NOT NULL,
+	repo       text NOT NULL,
+	login      text NOT NULL,
+	user_id    bigint NOT NULL,
+	token      text NOT NULL,
+	created_at timestamp with time zone NOT NULL DEFAULT now(),
+	updated_at timestamp with time zone NOT NULL DEFAULT now(),
+	PRIMARY KEY (owner, repo)
+);
+CREATE INDEX repo_hooks_login ON repo_hooks (login);
+`,
+		Down: `
+DROP TABLE repo_hooks;
+`,
+	},
+	{
+		Version: 20,
+		Name:    "forks",
+		Up: `
+-- The parent repository of projects that are forks.
+ALTER TABLE projects ADD COLUMN fork_of text NOT NULL DEFAULT '';
+ALTER TABLE jobs ADD COLUMN fork_of text NOT NULL DEFAULT '';
+`,
+		Down: `
+ALTER TABLE jobs DROP COLUMN fork_of;
+ALTER TABLE projects DROP COLUMN fork_of;
+`,
+	},
+	{
+		Version: 21,
+		Name:    "session revocations",
+		Up: `
+-- Revocations of the sessions of users. Sessions in cookies that were created before the
+-- revocation time are rejected.
+CREATE TABLE session_revocations (
+	login      text PRIMARY KEY,
+	revoked_at timestamp with time zone NOT NULL
+);
+`,
+		Down: `
+DROP TABLE session_revocations;
+`,
+	},
+	{
+		Version: 22,
+		Name:    "top projects columns",
+		Up: `
+-- The top projects view lists its columns, so it does not depend on the other columns of
+-- the projects table.
+DROP MATERIALIZED VIEW top_projects;
+CREATE MATERIALIZED VIEW top_projects AS
+	SELECT owner, repo, stars FROM projects WHERE private = FALSE ORDER BY stars DESC LIMIT 10;
+`,
+		Down: `
+DROP MATERIALIZED VIEW top_projects;
+CREATE MATERIALIZED VIEW top_projects AS
+	SELECT * FROM projects WHERE private = FALSE ORDER BY stars DESC LIMIT 10;
+`,
+	},
+}
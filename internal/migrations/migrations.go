@@ -0,0 +1,111 @@
+// Package migrations manages the versioned schema of the database.
+//
+// Each migration is a reviewable SQL statement with an inverse statement that
+// reverts it. The applied versions are recorded in the schema_migrations table.
+// Migrations should never be modified once released, any change to the schema
+// should be added as a new migration at the end of the list.
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Migration is a single schema change.
+type Migration struct {
+	// Version of the schema after applying the migration.
+	Version int
+	// Name describes the migration.
+	Name string
+	// Up is the SQL that applies the migration.
+	Up string
+	// Down is the SQL that reverts the migration.
+	Down string
+}
+
+const createTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    integer PRIMARY KEY,
+	name       text NOT NULL,
+	applied_at timestamp with time zone NOT NULL DEFAULT now()
+)`
+
+// Latest returns the schema version after applying all migrations.
+func Latest() int {
+	return all[len(all)-1].Version
+}
+
+// Version returns the current schema version of the database. It returns 0
+// if no migration was applied.
+func Version(db *gorm.DB) (int, error) {
+	if !db.HasTable("schema_migrations") {
+		return 0, nil
+	}
+	var v struct{ Version int }
+	err := db.Table("schema_migrations").Select("COALESCE(MAX(version), 0) as version").Scan(&v).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "get schema version")
+	}
+	return v.Version, nil
+}
+
+// Up applies all the migrations that were not applied yet. Each migration is applied
+// in its own transaction.
+func Up(db *gorm.DB) error {
+	if err := db.Exec(createTable).Error; err != nil {
+		return errors.Wrap(err, "creating migrations table")
+	}
+	current, err := Version(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range all {
+		if m.Version <= current {
+			continue
+		}
+		logrus.Infof("Applying migration %d: %s", m.Version, m.Name)
+		tx := db.Begin()
+		if err := tx.Exec(m.Up).Error; err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "applying migration %d", m.Version)
+		}
+		err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name).Error
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "recording migration %d", m.Version)
+		}
+		if err := tx.Commit().Error; err != nil {
+			return errors.Wrapf(err, "commit migration %d", m.Version)
+		}
+	}
+	return nil
+}
+
+// Down reverts all the applied migrations that are newer than the given version.
+func Down(db *gorm.DB, version int) error {
+	current, err := Version(db)
+	if err != nil {
+		return err
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if m.Version <= version || m.Version > current {
+			continue
+		}
+		logrus.Infof("Reverting migration %d: %s", m.Version, m.Name)
+		tx := db.Begin()
+		if err := tx.Exec(m.Down).Error; err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "reverting migration %d", m.Version)
+		}
+		if err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version).Error; err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "unrecording migration %d", m.Version)
+		}
+		if err := tx.Commit().Error; err != nil {
+			return errors.Wrapf(err, "commit revert of migration %d", m.Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Package notify sends notifications about finished jobs to pluggable sinks.
+//
+// Sinks implement the Notifier interface and are registered by name in a Registry.
+// Each project chooses the sinks that it is notified by in its settings: the setting
+// key is the sink name with the "notify." prefix, and the value is the sink target,
+// such as a URL or an email address.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+// SettingPrefix is the prefix of project settings keys that configure notification sinks.
+const SettingPrefix = "notify."
+
+// SettingOn is the project setting key that configures which jobs are notified about.
+// Possible values are "failed", which is the default, and "all".
+const SettingOn = "notify_on"
+
+// timeout for sending a notification.
+const timeout = 10 * time.Second
+
+// Notification describes a finished job.
+type Notification struct {
+	Owner   string        `json:"owner"`
+	Repo    string        `json:"repo"`
+	Num     int           `json:"num"`
+	Status  status.Status `json:"status"`
+	Message string        `json:"message"`
+	PR      int           `json:"pr,omitempty"`
+	// URL is a link to the job page.
+	URL string `json:"url"`
+	// Github is a Github API client with the project installation credentials.
+	Github *github.Client `json:"-"`
+}
+
+// Text returns a human readable text of the notification.
+func (n Notification) Text() string {
+	text := fmt.Sprintf("goreadme job %s/%s#%d: %s - %s", n.Owner, n.Repo, n.Num, n.Status, n.Message)
+	if n.PR != 0 {
+		text += fmt.Sprintf(" (https://github.com/%s/%s/pull/%d)", n.Owner, n.Repo, n.PR)
+	}
+	return text + "\n" + n.URL
+}
+
+// Notifier sends notifications to a sink.
+type Notifier interface {
+	// Notify sends a notification to a target. The target format depends on the
+	// notifier, it is the value of the project setting.
+	Notify(ctx context.Context, n Notification, target string) error
+	// Description describes the notifier and the expected target.
+	Description() string
+}
+
+// Registry holds the available notifiers by name.
+type Registry struct {
+	notifiers map[string]Notifier
+}
+
+// Register adds a notifier to the registry.
+func (r *Registry) Register(name string, n Notifier) {
+	if r.notifiers == nil {
+		r.notifiers = make(map[string]Notifier)
+	}
+	r.notifiers[name] = n
+}
+
+// Names returns the names of the registered notifiers, sorted.
+func (r *Registry) Names() []string {
+	var names []string
+	for name := range r.notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns a notifier by name, or nil if it is not registered.
+func (r *Registry) Get(name string) Notifier {
+	if r == nil {
+		return nil
+	}
+	return r.notifiers[name]
+}
+
+// Send sends a notification to all the sinks that are configured in the project
+// settings. Failures are logged.
+func (r *Registry) Send(ctx context.Context, settings map[string]string, n Notification) {
+	if r == nil {
+		return
+	}
+	if settings[SettingOn] != "all" && !n.Status.Failure() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for key, target := range settings {
+		if !strings.HasPrefix(key, SettingPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, SettingPrefix)
+		notifier := r.notifiers[name]
+		if notifier == nil {
+			logrus.Warnf("Unknown notifier %s for %s/%s", name, n.Owner, n.Repo)
+			continue
+		}
+		if err := notifier.Notify(ctx, n, target); err != nil {
+			logrus.Errorf("Failed notifying %s for %s/%s#%d: %s", name, n.Owner, n.Repo, n.Num, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// Slack posts notifications to a Slack incoming webhook URL.
+type Slack struct{}
+
+func (Slack) Description() string { return "Slack incoming webhook URL" }
+
+func (Slack) Notify(ctx context.Context, n Notification, target string) error {
+	return postJSON(ctx, target, map[string]string{"text": n.Text()})
+}
+
+// Webhook posts notifications as JSON to a URL.
+type Webhook struct{}
+
+func (Webhook) Description() string { return "URL that the notification is posted to as JSON" }
+
+func (Webhook) Notify(ctx context.Context, n Notification, target string) error {
+	return postJSON(ctx, target, n)
+}
+
+// Email sends notifications with SMTP to a comma separated list of addresses.
+type Email struct {
+	// Addr is the SMTP server address, in the form "host:port".
+	Addr string
+	// From is the sender address.
+	From string
+	// Auth is an optional SMTP authentication.
+	Auth smtp.Auth
+}
+
+func (Email) Description() string { return "Comma separated email addresses" }
+
+func (e Email) Notify(ctx context.Context, n Notification, target string) error {
+	to := strings.Split(target, ",")
+	for i := range to {
+		to[i] = strings.TrimSpace(to[i])
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: goreadme %s/%s: %s\r\n\r\n%s\r\n",
+		e.From, strings.Join(to, ", "), n.Owner, n.Repo, n.Status, n.Text())
+	err := smtp.SendMail(e.Addr, e.Auth, e.From, to, []byte(msg))
+	return errors.Wrap(err, "sending email")
+}
+
+// GithubComment comments on the goreadme pull request. The target should be "true".
+type GithubComment struct{}
+
+func (GithubComment) Description() string { return `Comment on the goreadme PR, set to "true"` }
+
+func (GithubComment) Notify(ctx context.Context, n Notification, target string) error {
+	if target != "true" || n.PR == 0 || n.Github == nil {
+		return nil
+	}
+	_, _, err := n.Github.Issues.CreateComment(ctx, n.Owner, n.Repo, n.PR, &github.IssueComment{
+		Body: github.String(n.Text()),
+	})
+	return errors.Wrap(err, "creating PR comment")
+}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
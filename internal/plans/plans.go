@@ -0,0 +1,136 @@
+// Package plans holds the plans of Github app installations and their limits.
+//
+// Billing is handled outside of the server, which only stores the plan of each
+// installation and enforces its limits. Installations without a stored plan are on the
+// free plan.
+package plans
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Unlimited is a limit value that allows any usage.
+const Unlimited = -1
+
+// Plan defines the limits of an installation.
+type Plan struct {
+	Name string
+	// PrivateRepos is the number of private repositories that goreadme runs on.
+	PrivateRepos int
+	// Schedules is the number of scheduled runs.
+	Schedules int
+	// APITokens is the number of API tokens.
+	APITokens int
+}
+
+var (
+	// Free is the default plan.
+	Free = Plan{Name: "free", PrivateRepos: 1, Schedules: 0, APITokens: 1}
+	// Pro is the plan of paying installations.
+	Pro = Plan{Name: "pro", PrivateRepos: Unlimited, Schedules: 20, APITokens: 10}
+)
+
+// All are the available plans.
+var All = []Plan{Free, Pro}
+
+// Get returns a plan by name.
+func Get(name string) (Plan, bool) {
+	for _, p := range All {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Plan{}, false
+}
+
+// LimitError is returned when an installation exceeds a limit of its plan.
+type LimitError struct {
+	Plan     string
+	Resource string
+	Limit    int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("the %s plan is limited to %d %s", e.Plan, e.Limit, e.Resource)
+}
+
+// check returns a *LimitError if adding one more of a resource, of which used are already
+// in use, exceeds a limit.
+func (p Plan) check(resource string, limit, used int) error {
+	if limit == Unlimited || used < limit {
+		return nil
+	}
+	return &LimitError{Plan: p.Name, Resource: resource, Limit: limit}
+}
+
+// CheckPrivateRepos checks whether one more private repository is allowed.
+func (p Plan) CheckPrivateRepos(used int) error {
+	return p.check("private repositories", p.PrivateRepos, used)
+}
+
+// CheckSchedules checks whether one more schedule is allowed.
+func (p Plan) CheckSchedules(used int) error {
+	return p.check("schedules", p.Schedules, used)
+}
+
+// CheckAPITokens checks whether one more API token is allowed.
+func (p Plan) CheckAPITokens(used int) error {
+	return p.check("API tokens", p.APITokens, used)
+}
+
+// Install is the stored plan of an installation.
+type Install struct {
+	Install   int64 `gorm:"primary_key;auto_increment:false"`
+	Plan      string
+	UpdatedAt time.Time
+}
+
+// TableName is the database table of installation plans.
+func (Install) TableName() string { return "plans" }
+
+// Plans is a database backed store of installation plans.
+type Plans struct {
+	db *gorm.DB
+}
+
+// New returns a plans store.
+func New(db *gorm.DB) *Plans {
+	return &Plans{db: db}
+}
+
+// Of returns the plan of an installation.
+func (p *Plans) Of(install int64) (Plan, error) {
+	var i Install
+	query := p.db.Where("install = ?", install).First(&i)
+	switch {
+	case query.RecordNotFound():
+		return Free, nil
+	case query.Error != nil:
+		return Plan{}, errors.Wrapf(query.Error, "getting plan of install %d", install)
+	}
+	plan, ok := Get(i.Plan)
+	if !ok {
+		return Plan{}, errors.Errorf("install %d has unknown plan %q", install, i.Plan)
+	}
+	return plan, nil
+}
+
+// Set sets the plan of an installation.
+func (p *Plans) Set(install int64, name string) error {
+	if _, ok := Get(name); !ok {
+		return errors.Errorf("unknown plan %q", name)
+	}
+	err := p.db.Save(&Install{Install: install, Plan: name}).Error
+	return errors.Wrapf(err, "saving plan of install %d", install)
+}
+
+// List returns the stored plans of all installations.
+func (p *Plans) List() ([]Install, error) {
+	var installs []Install
+	err := p.db.Order("install").Find(&installs).Error
+	return installs, errors.Wrap(err, "listing plans")
+}
@@ -0,0 +1,55 @@
+// Package quality scores generated readme files, to nudge users toward better documentation.
+//
+// The score is the percentage of the following checks that the readme passes: it has a
+// synopsis, it has examples, it has badges and it has a license section.
+package quality
+
+import (
+	"regexp"
+	"strings"
+)
+
+// minSynopsisLength is the minimal length of a readme first paragraph to count as a synopsis.
+const minSynopsisLength = 50
+
+// Check is a quality criterion of a readme file.
+type Check struct {
+	Name   string
+	Passed bool
+}
+
+var (
+	examplesHeading = regexp.MustCompile(`(?m)^#+ Examples?\s*$`)
+	licenseHeading  = regexp.MustCompile(`(?im)^#+\s*licen[cs]e`)
+)
+
+// Score returns the quality score of a readme, between 0 and 100, and the results of the
+// checks that it was computed from.
+func Score(readme string) (int, []Check) {
+	checks := []Check{
+		{Name: "synopsis", Passed: hasSynopsis(readme)},
+		{Name: "examples", Passed: examplesHeading.MatchString(readme)},
+		{Name: "badges", Passed: strings.Contains(readme, "[![")},
+		{Name: "license", Passed: licenseHeading.MatchString(readme)},
+	}
+	passed := 0
+	for _, c := range checks {
+		if c.Passed {
+			passed++
+		}
+	}
+	return 100 * passed / len(checks), checks
+}
+
+// hasSynopsis checks if the first text paragraph of the readme, after the title and the
+// badges, is long enough.
+func hasSynopsis(readme string) bool {
+	for _, p := range strings.Split(readme, "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") || strings.HasPrefix(p, "[![") {
+			continue
+		}
+		return len(p) >= minSynopsisLength
+	}
+	return false
+}
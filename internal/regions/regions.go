@@ -0,0 +1,56 @@
+// Package regions merges generated content into a region of an existing readme.
+//
+// A region is marked by the Begin and End comments, which are not shown when the readme is
+// rendered. Content outside the region is kept as is, so readmes of repositories that are
+// not only Go can have a generated Go section:
+//
+// 	# My Project
+//
+// 	Hand written introduction.
+//
+// 	<!-- goreadme:begin -->
+// 	The generated content.
+// 	<!-- goreadme:end -->
+package regions
+
+import "strings"
+
+// Markers of the generated region.
+const (
+	Begin = "<!-- goreadme:begin -->"
+	End   = "<!-- goreadme:end -->"
+)
+
+// Has returns whether a readme has a generated region.
+func Has(readme string) bool {
+	_, _, ok := find(readme)
+	return ok
+}
+
+// Merge replaces the generated region of a readme with the generated content. It returns
+// false if the readme has no generated region.
+func Merge(readme, generated string) (string, bool) {
+	begin, end, ok := find(readme)
+	if !ok {
+		return "", false
+	}
+	return readme[:begin] + Wrap(generated) + readme[end:], true
+}
+
+// Wrap returns the generated content in a generated region.
+func Wrap(generated string) string {
+	return Begin + "\n" + strings.Trim(generated, "\n") + "\n" + End
+}
+
+// find returns the start of the begin marker and the end of the end marker.
+func find(readme string) (begin, end int, ok bool) {
+	begin = strings.Index(readme, Begin)
+	if begin < 0 {
+		return 0, 0, false
+	}
+	i := strings.Index(readme[begin:], End)
+	if i < 0 {
+		return 0, 0, false
+	}
+	return begin, begin + i + len(End), true
+}
@@ -0,0 +1,158 @@
+// Package repohooks stores repository webhooks of users that don't install the goreadme
+// Github app.
+//
+// The user provides a Github token with access to the repository and adds a webhook to it
+// manually. The token is stored encrypted, and the webhook secret of each repository is
+// derived from the server hook secret, so it is not stored.
+//
+// Projects of repository webhooks are recorded with the negative Github ID of the user as
+// their installation ID, which can't collide with the IDs of app installations.
+package repohooks
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Hook is a webhook that a user added to a repository.
+type Hook struct {
+	Owner string `gorm:"primary_key"`
+	Repo  string `gorm:"primary_key"`
+	// Login is the user that added the webhook, and UserID is the Github ID of the user.
+	Login  string
+	UserID int64
+	// Token is the encrypted token that goreadme uses to access the repository.
+	Token     string `json:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName is the database table of repository webhooks.
+func (Hook) TableName() string { return "repo_hooks" }
+
+// Install returns the installation ID of the projects of the webhook.
+func (h *Hook) Install() int64 {
+	return Install(h.UserID)
+}
+
+// Install returns the installation ID of the projects of repository webhooks of a user.
+func Install(userID int64) int64 {
+	return -userID
+}
+
+// IsHook returns whether an installation ID is of repository webhooks.
+func IsHook(install int64) bool {
+	return install < 0
+}
+
+// Hooks is a database backed repository webhooks store.
+type Hooks struct {
+	db         *gorm.DB
+	key        []byte
+	hookSecret string
+}
+
+// New returns a repository webhooks store. Tokens are encrypted with a key that is derived
+// from secret, and the webhook secrets are derived from hookSecret.
+func New(db *gorm.DB, secret, hookSecret string) *Hooks {
+	key := sha256.Sum256([]byte(secret))
+	return &Hooks{db: db, key: key[:], hookSecret: hookSecret}
+}
+
+// Secret returns the secret of the webhook of a repository.
+func (h *Hooks) Secret(owner, repo string) string {
+	mac := hmac.New(sha256.New, []byte(h.hookSecret))
+	mac.Write([]byte(owner + "/" + repo))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Set stores the webhook of a repository with the token of a user. It replaces the
+// existing webhook of the repository.
+func (h *Hooks) Set(owner, repo, login string, userID int64, token string) error {
+	encrypted, err := h.encrypt(token)
+	if err != nil {
+		return err
+	}
+	hook := Hook{Owner: owner, Repo: repo, Login: login, UserID: userID, Token: encrypted}
+	err = h.db.Where(Hook{Owner: owner, Repo: repo}).
+		Assign(Hook{Login: login, UserID: userID, Token: encrypted}).
+		FirstOrCreate(&hook).Error
+	return errors.Wrapf(err, "saving webhook of %s/%s", owner, repo)
+}
+
+// Get returns the webhook of a repository, and its decrypted token. It returns a nil hook
+// if the repository has no webhook.
+func (h *Hooks) Get(owner, repo string) (*Hook, string, error) {
+	var hook Hook
+	query := h.db.Where("owner = ? AND repo = ?", owner, repo).First(&hook)
+	switch {
+	case query.RecordNotFound():
+		return nil, "", nil
+	case query.Error != nil:
+		return nil, "", errors.Wrapf(query.Error, "getting webhook of %s/%s", owner, repo)
+	}
+	token, err := h.decrypt(hook.Token)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "decrypting token of %s/%s", owner, repo)
+	}
+	return &hook, token, nil
+}
+
+// List returns the webhooks that a user added.
+func (h *Hooks) List(login string) ([]Hook, error) {
+	var hooks []Hook
+	err := h.db.Where("login = ?", login).Order("owner, repo").Find(&hooks).Error
+	return hooks, errors.Wrapf(err, "listing webhooks of %s", login)
+}
+
+// Delete deletes the webhook of a repository, if it was added by the user.
+func (h *Hooks) Delete(owner, repo, login string) error {
+	err := h.db.Where("owner = ? AND repo = ? AND login = ?", owner, repo, login).Delete(&Hook{}).Error
+	return errors.Wrapf(err, "deleting webhook of %s/%s", owner, repo)
+}
+
+func (h *Hooks) encrypt(token string) (string, error) {
+	gcm, err := h.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "generating nonce")
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(token), nil)), nil
+}
+
+func (h *Hooks) decrypt(encrypted string) (string, error) {
+	gcm, err := h.gcm()
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(b) < gcm.NonceSize() {
+		return "", errors.New("encrypted token is too short")
+	}
+	token, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	return string(token), err
+}
+
+func (h *Hooks) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(h.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
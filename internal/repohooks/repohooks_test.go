@@ -0,0 +1,106 @@
+package repohooks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+)
+
+func TestEncrypt(t *testing.T) {
+	h := New(nil, "secret", "hook secret")
+	encrypted, err := h.encrypt("token")
+	if err != nil {
+		t.Fatalf("encrypt: %s", err)
+	}
+	if strings.Contains(encrypted, "token") {
+		t.Errorf("Encrypted token %q contains the token", encrypted)
+	}
+	again, err := h.encrypt("token")
+	if err != nil {
+		t.Fatalf("encrypt: %s", err)
+	}
+	if again == encrypted {
+		t.Error("Encrypting twice returned the same value")
+	}
+
+	token, err := h.decrypt(encrypted)
+	if err != nil || token != "token" {
+		t.Errorf("decrypt() = %q, %v, want the token", token, err)
+	}
+	if _, err := New(nil, "other", "hook secret").decrypt(encrypted); err == nil {
+		t.Error("Decrypted with another secret")
+	}
+	if _, err := h.decrypt("c2hvcnQ="); err == nil {
+		t.Error("Decrypted a short value")
+	}
+}
+
+func TestSecret(t *testing.T) {
+	h := New(nil, "secret", "hook secret")
+	s := h.Secret("posener", "hello")
+	if s != h.Secret("posener", "hello") {
+		t.Error("Secret of a repository changed")
+	}
+	if s == h.Secret("posener", "other") {
+		t.Error("Repositories have the same secret")
+	}
+	if s == New(nil, "secret", "other").Secret("posener", "hello") {
+		t.Error("Secret does not depend on the hook secret")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	hook := &Hook{UserID: 42}
+	if got := hook.Install(); got != -42 || !IsHook(got) {
+		t.Errorf("Install() = %d, want a hook installation", got)
+	}
+	if IsHook(42) {
+		t.Error("App installation is a hook")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	h := New(db, "secret", "hook secret")
+
+	if err := h.Set("posener", "hello", "posener", 1, "token1"); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+	// Another user replaces the webhook of the repository.
+	if err := h.Set("posener", "hello", "other", 2, "token2"); err != nil {
+		t.Fatalf("Set: %s", err)
+	}
+	hook, token, err := h.Get("posener", "hello")
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if hook == nil || hook.Login != "other" || hook.UserID != 2 || token != "token2" {
+		t.Fatalf("Get() = %+v, %q, want the webhook of other", hook, token)
+	}
+	if hook, _, err := h.Get("posener", "missing"); err != nil || hook != nil {
+		t.Errorf("Get() of missing webhook = %+v, %v, want nil", hook, err)
+	}
+
+	if list, err := h.List("posener"); err != nil || len(list) != 0 {
+		t.Errorf("List(posener) = %+v, %v, want no webhooks", list, err)
+	}
+	if list, err := h.List("other"); err != nil || len(list) != 1 {
+		t.Errorf("List(other) = %+v, %v, want one webhook", list, err)
+	}
+
+	// Only the user that added the webhook can delete it.
+	if err := h.Delete("posener", "hello", "posener"); err != nil {
+		t.Fatalf("Delete: %s", err)
+	}
+	if hook, _, _ := h.Get("posener", "hello"); hook == nil {
+		t.Fatal("Webhook was deleted by another user")
+	}
+	if err := h.Delete("posener", "hello", "other"); err != nil {
+		t.Fatalf("Delete: %s", err)
+	}
+	if hook, _, _ := h.Get("posener", "hello"); hook != nil {
+		t.Error("Webhook was not deleted")
+	}
+}
@@ -0,0 +1,53 @@
+// Package report reports errors and panics to an external error tracking service.
+package report
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Reporter reports errors with their context, such as the repository and the job number.
+type Reporter interface {
+	Report(err error, tags map[string]string)
+}
+
+// New returns a reporter according to a Sentry DSN. If the DSN is empty, errors are not
+// reported.
+func New(dsn string) (Reporter, error) {
+	if dsn == "" {
+		return Nop{}, nil
+	}
+	return NewSentry(dsn)
+}
+
+// Nop is a reporter that does not report errors.
+type Nop struct{}
+
+func (Nop) Report(error, map[string]string) {}
+
+// Middleware reports panics of an HTTP handler, and then panics again, so the panic will be
+// handled by the next recovery handler.
+func Middleware(r Reporter, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			err, ok := p.(error)
+			if !ok {
+				err = fmt.Errorf("%v", p)
+			}
+			logrus.Debugf("Reporting panic: %s", err)
+			r.Report(err, map[string]string{
+				"method": req.Method,
+				"path":   req.URL.Path,
+				"panic":  "true",
+			})
+			panic(p)
+		}()
+		h.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,141 @@
+package report
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const sentryTimeout = 10 * time.Second
+
+// Sentry reports errors to Sentry using its store API.
+type Sentry struct {
+	storeURL string
+	auth     string
+	client   *http.Client
+}
+
+// NewSentry returns a Sentry reporter from a DSN of the form:
+// https://<key>@<host>/<project>.
+func NewSentry(dsn string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid sentry DSN")
+	}
+	key := u.User.Username()
+	project := strings.Trim(u.Path, "/")
+	if key == "" || project == "" {
+		return nil, errors.New("invalid sentry DSN: expected key and project")
+	}
+	auth := "Sentry sentry_version=7, sentry_client=goreadme-server/1.0, sentry_key=" + key
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	return &Sentry{
+		storeURL: fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, project),
+		auth:     auth,
+		client:   &http.Client{Timeout: sentryTimeout},
+	}, nil
+}
+
+// Report sends the error to Sentry in the background.
+func (s *Sentry) Report(err error, tags map[string]string) {
+	e := s.event(err, tags)
+	go func() {
+		if err := s.send(e); err != nil {
+			logrus.Errorf("Failed reporting error to sentry: %s", err)
+		}
+	}()
+}
+
+type sentryEvent struct {
+	EventID   string            `json:"event_id"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Platform  string            `json:"platform"`
+	Logger    string            `json:"logger"`
+	Message   string            `json:"message"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Exception []sentryException `json:"exception,omitempty"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+func (s *Sentry) event(err error, tags map[string]string) *sentryEvent {
+	id := make([]byte, 16)
+	rand.Read(id)
+	exc := sentryException{Type: fmt.Sprintf("%T", errors.Cause(err)), Value: err.Error()}
+	if st, ok := err.(stackTracer); ok {
+		trace := st.StackTrace()
+		exc.Stacktrace = &sentryStacktrace{}
+		// Sentry expects the frames ordered from the oldest call to the newest.
+		for i := len(trace) - 1; i >= 0; i-- {
+			line, _ := strconv.Atoi(fmt.Sprintf("%d", trace[i]))
+			exc.Stacktrace.Frames = append(exc.Stacktrace.Frames, sentryFrame{
+				Function: fmt.Sprintf("%n", trace[i]),
+				Filename: fmt.Sprintf("%s", trace[i]),
+				Lineno:   line,
+			})
+		}
+	}
+	return &sentryEvent{
+		EventID:   hex.EncodeToString(id),
+		Timestamp: time.Now().UTC().Format("2006-01-02T15:04:05"),
+		Level:     "error",
+		Platform:  "go",
+		Logger:    "goreadme-server",
+		Message:   err.Error(),
+		Tags:      tags,
+		Exception: []sentryException{exc},
+	}
+}
+
+func (s *Sentry) send(e *sentryEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("got status %d", resp.StatusCode)
+	}
+	return nil
+}
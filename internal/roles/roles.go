@@ -0,0 +1,141 @@
+// Package roles maps Github team membership to dashboard roles.
+//
+// The owner of a personal installation is an admin of it. Members of an organization get
+// the highest role that is mapped to one of their teams in the installation settings, and
+// members that are in none of the mapped teams can't access the installation.
+package roles
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+)
+
+// Role is the access level of a user to an installation.
+type Role string
+
+// Dashboard roles, from the lowest to the highest.
+const (
+	// None can't access the installation.
+	None Role = ""
+	// Viewer can view projects, jobs and usage.
+	Viewer Role = "viewer"
+	// Operator can also run jobs and change project settings.
+	Operator Role = "operator"
+	// Admin can also change the installation settings.
+	Admin Role = "admin"
+)
+
+// All are the roles that can be mapped to teams.
+var All = []Role{Viewer, Operator, Admin}
+
+// SettingPrefix is the prefix of installation settings that map a team slug to a role.
+const SettingPrefix = "team_role."
+
+// expiry is the duration that the role of a user is cached.
+const expiry = 5 * time.Minute
+
+// Parse returns a role by name.
+func Parse(name string) (Role, error) {
+	for _, r := range All {
+		if string(r) == name {
+			return r, nil
+		}
+	}
+	return None, errors.Errorf("unknown role %q", name)
+}
+
+// Allows returns whether the role grants the access of another role.
+func (r Role) Allows(other Role) bool {
+	return r.rank() >= other.rank()
+}
+
+func (r Role) rank() int {
+	for i, role := range All {
+		if r == role {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Teams returns the team roles from installation settings, by team slug.
+func Teams(settings map[string]string) map[string]Role {
+	teams := make(map[string]Role)
+	for key, value := range settings {
+		if !strings.HasPrefix(key, SettingPrefix) {
+			continue
+		}
+		if role, err := Parse(value); err == nil {
+			teams[strings.TrimPrefix(key, SettingPrefix)] = role
+		}
+	}
+	return teams
+}
+
+// Resolver resolves the roles of users in organizations, and caches them.
+type Resolver struct {
+	cache *cache.Cache
+}
+
+// NewResolver returns a role resolver.
+func NewResolver() *Resolver {
+	return &Resolver{cache: cache.New(expiry, 2*expiry)}
+}
+
+// Role returns the role of a user in an organization, according to the team roles of the
+// installation. The client should have the installation credentials, with read access to
+// the organization members.
+func (r *Resolver) Role(ctx context.Context, client *github.Client, org, login string, teams map[string]Role) (Role, error) {
+	if org == login {
+		return Admin, nil
+	}
+	key := org + "/" + login
+	if v, ok := r.cache.Get(key); ok {
+		return v.(Role), nil
+	}
+
+	role := None
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := client.Teams.ListTeams(ctx, org, opt)
+		if err != nil {
+			return None, errors.Wrapf(err, "list teams of %s", org)
+		}
+		for _, team := range list {
+			teamRole, ok := teams[team.GetSlug()]
+			if !ok || role.Allows(teamRole) {
+				continue
+			}
+			member, err := isMember(ctx, client, team.GetID(), login)
+			if err != nil {
+				return None, err
+			}
+			if member {
+				role = teamRole
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	r.cache.Set(key, role, cache.DefaultExpiration)
+	return role, nil
+}
+
+// isMember returns whether a user is an active member of a team.
+func isMember(ctx context.Context, client *github.Client, team int64, login string) (bool, error) {
+	m, resp, err := client.Teams.GetTeamMembership(ctx, team, login)
+	if resp != nil && resp.StatusCode == 404 {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "get membership of %s in team %d", login, team)
+	}
+	return m.GetState() == "active", nil
+}
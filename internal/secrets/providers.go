@@ -0,0 +1,110 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const gcpTimeout = 10 * time.Second
+
+// GCP provides the latest version of secrets from Google Secret Manager.
+type GCP struct {
+	Project string
+	// Client is used for the API requests. If nil, a default client is used.
+	Client *http.Client
+}
+
+const (
+	gcpTokenURL  = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpSecretURL = "https://secretmanager.googleapis.com/v1/projects/%s/secrets/%s/versions/latest:access"
+)
+
+func (g *GCP) Secret(ctx context.Context, name string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, gcpTimeout)
+	defer cancel()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	_, err := g.get(ctx, gcpTokenURL, map[string]string{"Metadata-Flavor": "Google"}, &token)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed getting access token from metadata server")
+	}
+
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	status, err := g.get(ctx, fmt.Sprintf(gcpSecretURL, g.Project, name),
+		map[string]string{"Authorization": "Bearer " + token.AccessToken}, &resp)
+	if status == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	value, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed decoding secret payload")
+	}
+	return string(value), true, nil
+}
+
+func (g *GCP) get(ctx context.Context, url string, headers map[string]string, v interface{}) (status int, err error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, errors.Errorf("got status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Command provides secrets from the output of a shell command. The command gets the name
+// of the secret in the SECRET_NAME environment variable. A command that fails with exit
+// code 2 indicates that the secret was not found.
+type Command struct {
+	Command string
+}
+
+func (c Command) Secret(ctx context.Context, name string) (string, bool, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Env = append(os.Environ(), "SECRET_NAME="+name)
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
+		return "", false, nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.Errorf("%s: %s", err, msg)
+		}
+		return "", false, errors.Wrap(err, "secrets command failed")
+	}
+	return strings.TrimRight(string(out), "\r\n"), true, nil
+}
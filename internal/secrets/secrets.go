@@ -0,0 +1,108 @@
+// Package secrets loads secret configuration values, such as the Github app private key,
+// from the environment, from files or from a secret manager.
+//
+// The Env provider follows the Docker and Kubernetes convention: a secret named NAME is read
+// from the NAME environment variable, or from the file which its path is given in the
+// NAME_FILE environment variable.
+//
+// Other providers are chosen with the SECRETS_PROVIDER environment variable:
+//
+// - gcp: Google Secret Manager, in the project given by SECRETS_GCP_PROJECT. Credentials are
+// taken from the instance metadata server.
+//
+// - command: runs the command in SECRETS_COMMAND for every secret, with the secret name in
+// the SECRET_NAME environment variable, and uses its output as the secret value. For example,
+// for AWS Secrets Manager:
+//
+// 	aws secretsmanager get-secret-value --secret-id $SECRET_NAME --query SecretString --output text || exit 2
+//
+// The command should exit with code 2 if the secret was not found.
+//
+// Secrets that a provider does not have are taken from the environment.
+package secrets
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Provider provides secret values by name.
+type Provider interface {
+	// Secret returns the value of a secret, and whether it was found.
+	Secret(ctx context.Context, name string) (value string, found bool, err error)
+}
+
+// New returns a provider by its kind: "env", "gcp" or "command". The returned provider
+// falls back to the environment for secrets that it does not have.
+func New(kind string) (Provider, error) {
+	switch kind {
+	case "", "env":
+		return Env{}, nil
+	case "gcp":
+		project := os.Getenv("SECRETS_GCP_PROJECT")
+		if project == "" {
+			return nil, errors.New("SECRETS_GCP_PROJECT must be set for the gcp secrets provider")
+		}
+		return Chain{&GCP{Project: project}, Env{}}, nil
+	case "command":
+		command := os.Getenv("SECRETS_COMMAND")
+		if command == "" {
+			return nil, errors.New("SECRETS_COMMAND must be set for the command secrets provider")
+		}
+		return Chain{Command{Command: command}, Env{}}, nil
+	default:
+		return nil, errors.Errorf("unknown secrets provider %q, expected env, gcp or command", kind)
+	}
+}
+
+// Load sets the environment variables of the given secret names from the provider, so
+// they can be processed as regular environment configuration.
+func Load(ctx context.Context, p Provider, names ...string) error {
+	for _, name := range names {
+		value, found, err := p.Secret(ctx, name)
+		if err != nil {
+			return errors.Wrapf(err, "failed loading secret %s", name)
+		}
+		if !found {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Env provides secrets from environment variables, or from files that their paths are given
+// in environment variables with the _FILE suffix.
+type Env struct{}
+
+func (Env) Secret(_ context.Context, name string) (string, bool, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", false, err
+		}
+		// Files usually end with a new line, which is not part of the secret.
+		return strings.TrimRight(string(b), "\r\n"), true, nil
+	}
+	value, ok := os.LookupEnv(name)
+	return value, ok, nil
+}
+
+// Chain provides secrets from the first provider that has them.
+type Chain []Provider
+
+func (c Chain) Secret(ctx context.Context, name string) (string, bool, error) {
+	for _, p := range c {
+		value, found, err := p.Secret(ctx, name)
+		if err != nil || found {
+			return value, found, err
+		}
+	}
+	return "", false, nil
+}
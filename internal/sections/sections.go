@@ -0,0 +1,115 @@
+// Package sections reorders the sections of generated readme files.
+//
+// A generated readme starts with the package title, followed by the badges, and the
+// description from the package doc. Second level headings of the package doc, such as
+// "Install" or "Usage", and of the generated content, such as "Functions" and "Sub
+// Packages", start new sections. The examples section starts with the examples heading
+// of the package. Section names are their heading in lower case without spaces, for
+// example "subpackages".
+//
+// Examples of the last function are not told apart from the package examples when the
+// functions section is the last section before them, and both stay in the functions
+// section.
+package sections
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Section names that are not taken from headings.
+const (
+	Badges      = "badges"
+	Description = "description"
+	Examples    = "examples"
+	Functions   = "functions"
+)
+
+// Section is a named part of a readme.
+type Section struct {
+	Name    string
+	Content string
+}
+
+// Split splits a readme to its title and sections.
+func Split(readme string) (title string, sections []Section) {
+	lines := strings.SplitAfter(readme, "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		title, lines = lines[0], lines[1:]
+	}
+
+	var (
+		current = Section{Name: Badges}
+		fenced  bool
+	)
+	start := func(name string) {
+		if current.Content != "" {
+			sections = append(sections, current)
+		}
+		current = Section{Name: name}
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			fenced = !fenced
+		}
+		switch {
+		case fenced:
+		case current.Name == Badges && trimmed != "" && !strings.HasPrefix(trimmed, "[!["):
+			start(Description)
+		case strings.HasPrefix(line, "## "):
+			start(name(strings.TrimPrefix(line, "## ")))
+		case strings.HasPrefix(line, "#### ") && name(strings.TrimPrefix(line, "#### ")) == Examples && current.Name != Functions:
+			start(Examples)
+		}
+		current.Content += line
+	}
+	start("")
+	return title, sections
+}
+
+// Order returns the readme with the given sections first, in the given order. Other
+// sections follow in their original order. Unknown section names are ignored.
+func Order(readme string, order []string) string {
+	if len(order) == 0 {
+		return readme
+	}
+	title, sections := Split(readme)
+	var (
+		b    strings.Builder
+		used = make([]bool, len(sections))
+	)
+	if title != "" {
+		b.WriteString(title + "\n")
+	}
+	write := func(i int) {
+		used[i] = true
+		if content := strings.Trim(sections[i].Content, "\n"); content != "" {
+			b.WriteString(content + "\n\n")
+		}
+	}
+	for _, want := range order {
+		want = name(want)
+		for i, s := range sections {
+			if !used[i] && s.Name == want {
+				write(i)
+			}
+		}
+	}
+	for i := range sections {
+		if !used[i] {
+			write(i)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// name returns the name of a section heading.
+func name(heading string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, heading)
+}
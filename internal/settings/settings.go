@@ -0,0 +1,70 @@
+// Package settings stores settings of installations and of projects.
+//
+// Settings are string key-value pairs. Installation settings are stored with an
+// empty repository name.
+package settings
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Setting is a single setting value.
+type Setting struct {
+	Install   int64
+	Owner     string `gorm:"primary_key"`
+	Repo      string `gorm:"primary_key"`
+	Key       string `gorm:"primary_key"`
+	Value     string
+	UpdatedAt time.Time
+}
+
+// Settings is a database backed settings store.
+type Settings struct {
+	db *gorm.DB
+}
+
+// New returns a settings store.
+func New(db *gorm.DB) *Settings {
+	return &Settings{db: db}
+}
+
+// Project returns all the settings of a project, by key.
+func (s *Settings) Project(owner, repo string) (map[string]string, error) {
+	var settings []Setting
+	err := s.db.Where("owner = ? AND repo = ?", owner, repo).Find(&settings).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "get settings of %s/%s", owner, repo)
+	}
+	values := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		values[setting.Key] = setting.Value
+	}
+	return values, nil
+}
+
+// Installation returns all the settings of an installation owner, by key.
+func (s *Settings) Installation(owner string) (map[string]string, error) {
+	return s.Project(owner, "")
+}
+
+// Set sets project settings. Settings with an empty value are deleted. Use an
+// empty repo to set installation settings.
+func (s *Settings) Set(install int64, owner, repo string, values map[string]string) error {
+	tx := s.db.Begin()
+	for key, value := range values {
+		var err error
+		if value == "" {
+			err = tx.Where("owner = ? AND repo = ? AND key = ?", owner, repo, key).Delete(&Setting{}).Error
+		} else {
+			err = tx.Save(&Setting{Install: install, Owner: owner, Repo: repo, Key: key, Value: value}).Error
+		}
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "set %s of %s/%s", key, owner, repo)
+		}
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,84 @@
+// +build ignore
+
+// Downloads the dashboard assets from the CDNs and writes them to assets.go.
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"go/format"
+	"hash"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/posener/goreadme-server/internal/static"
+)
+
+func main() {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("// Code generated by gen.go; DO NOT EDIT.\n\npackage static\n\n")
+	buf.WriteString("// assets holds the content of the embedded assets by name.\n")
+	buf.WriteString("var assets = map[string]string{\n")
+	for _, a := range static.Assets {
+		log.Printf("Downloading %s", a.CDN)
+		content, err := download(a.CDN)
+		if err != nil {
+			log.Fatalf("Failed downloading %s: %s", a.CDN, err)
+		}
+		if err := verify(content, a.Integrity); err != nil {
+			log.Fatalf("Asset %s: %s", a.Name, err)
+		}
+		fmt.Fprintf(buf, "%q: %s,\n", a.Name, strconv.Quote(string(content)))
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("Failed formatting source: %s", err)
+	}
+	if err := ioutil.WriteFile("assets.go", src, 0644); err != nil {
+		log.Fatalf("Failed writing assets.go: %s", err)
+	}
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got status %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// verify checks the content against a subresource integrity hash.
+func verify(content []byte, integrity string) error {
+	if integrity == "" {
+		return nil
+	}
+	parts := strings.SplitN(integrity, "-", 2)
+	var h hash.Hash
+	switch parts[0] {
+	case "sha256":
+		h = sha256.New()
+	case "sha384":
+		h = sha512.New384()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported integrity algorithm %q", parts[0])
+	}
+	h.Write(content)
+	if got := base64.StdEncoding.EncodeToString(h.Sum(nil)); len(parts) != 2 || got != parts[1] {
+		return fmt.Errorf("integrity mismatch: got %s-%s", parts[0], got)
+	}
+	return nil
+}
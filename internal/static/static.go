@@ -0,0 +1,149 @@
+// Package static serves the third party assets of the dashboard: bootstrap, font-awesome,
+// jquery and popper.js, so the dashboard works in environments without access to the CDNs.
+//
+// The assets are downloaded from the CDNs and embedded in assets.go by running:
+//
+// 	go generate ./internal/static
+//
+// Assets that were not embedded are loaded from the CDNs, see Check.
+package static
+
+//go:generate go run gen.go
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Prefix is the path that the assets are served from.
+const Prefix = "/static/"
+
+// Asset is a third party file that the dashboard uses.
+type Asset struct {
+	// Name is the path of the asset under Prefix.
+	Name string
+	// CDN is the URL that the asset is downloaded from.
+	CDN string
+	// Integrity is the subresource integrity hash of the asset, if known.
+	Integrity string
+}
+
+const (
+	bootstrapCDN   = "https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/"
+	fontAwesomeCDN = "https://maxcdn.bootstrapcdn.com/font-awesome/4.7.0/"
+)
+
+// Assets are all the assets that the dashboard uses. The font files are not used directly,
+// but are loaded by the font-awesome css file, relative to its path.
+var Assets = []Asset{
+	{
+		Name:      "bootstrap/css/bootstrap.min.css",
+		CDN:       bootstrapCDN + "css/bootstrap.min.css",
+		Integrity: "sha384-ggOyR0iXCbMQv3Xipma34MD+dH/1fQ784/j6cY/iJTQUOhcWr7x9JvoRxT2MZw1T",
+	},
+	{
+		Name:      "bootstrap/js/bootstrap.min.js",
+		CDN:       bootstrapCDN + "js/bootstrap.min.js",
+		Integrity: "sha384-JjSmVgyd0p3pXB1rRibZUAYoIIy6OrQ6VrjIEaFf/nJGzIxFDsf4x0xIM+B07jRM",
+	},
+	{
+		Name:      "jquery/jquery.slim.min.js",
+		CDN:       "https://code.jquery.com/jquery-3.3.1.slim.min.js",
+		Integrity: "sha384-q8i/X+965DzO0rT7abK41JStQIAqVgRVzpbzo5smXKp4YfRvH+8abtTE1Pi6jizo",
+	},
+	{
+		Name:      "popper.js/popper.min.js",
+		CDN:       "https://cdnjs.cloudflare.com/ajax/libs/popper.js/1.14.7/umd/popper.min.js",
+		Integrity: "sha384-UO2eT0CpHqdSJQ6hJty5KVphtPhzWj9WO1clHTMGa3JDZwrnQq4sF86dIHNDz0W1",
+	},
+	{Name: "font-awesome/css/font-awesome.min.css", CDN: fontAwesomeCDN + "css/font-awesome.min.css"},
+	{Name: "font-awesome/fonts/FontAwesome.otf", CDN: fontAwesomeCDN + "fonts/FontAwesome.otf"},
+	{Name: "font-awesome/fonts/fontawesome-webfont.eot", CDN: fontAwesomeCDN + "fonts/fontawesome-webfont.eot"},
+	{Name: "font-awesome/fonts/fontawesome-webfont.svg", CDN: fontAwesomeCDN + "fonts/fontawesome-webfont.svg"},
+	{Name: "font-awesome/fonts/fontawesome-webfont.ttf", CDN: fontAwesomeCDN + "fonts/fontawesome-webfont.ttf"},
+	{Name: "font-awesome/fonts/fontawesome-webfont.woff", CDN: fontAwesomeCDN + "fonts/fontawesome-webfont.woff"},
+	{Name: "font-awesome/fonts/fontawesome-webfont.woff2", CDN: fontAwesomeCDN + "fonts/fontawesome-webfont.woff2"},
+}
+
+// hashes holds a short content hash of every embedded asset, used for cache busting.
+var hashes = make(map[string]string, len(assets))
+
+// modTime is the modification time of the embedded assets, which is the server start time.
+var modTime = time.Now()
+
+func init() {
+	for name, content := range assets {
+		hashes[name] = fmt.Sprintf("%x", sha256.Sum256([]byte(content)))[:12]
+	}
+}
+
+// Check returns an error if any of the assets was not embedded, and is loaded from its CDN.
+func Check() error {
+	var missing []string
+	for _, a := range Assets {
+		if _, ok := assets[a.Name]; !ok {
+			missing = append(missing, a.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("assets were not embedded, run go generate ./internal/static: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// URL returns the URL of an asset. An embedded asset URL contains its content hash, so it
+// can be cached forever by the browser. If the asset was not embedded, the CDN URL is returned.
+func URL(name string) string {
+	if hash, ok := hashes[name]; ok {
+		return Prefix + name + "?v=" + hash
+	}
+	for _, a := range Assets {
+		if a.Name == name {
+			return a.CDN
+		}
+	}
+	return Prefix + name
+}
+
+// Handler serves the embedded assets. It should be mounted on Prefix.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, Prefix)
+		content, ok := assets[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if ct := contentType(name); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		if v := r.URL.Query().Get("v"); v != "" && v == hashes[name] {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+		}
+		http.ServeContent(w, r, name, modTime, strings.NewReader(content))
+	})
+}
+
+func contentType(name string) string {
+	switch ext := path.Ext(name); ext {
+	case ".woff2":
+		return "font/woff2"
+	case ".woff":
+		return "font/woff"
+	case ".otf":
+		return "font/otf"
+	case ".ttf":
+		return "font/ttf"
+	case ".eot":
+		return "application/vnd.ms-fontobject"
+	default:
+		return mime.TypeByExtension(ext)
+	}
+}
@@ -0,0 +1,128 @@
+// Package status defines the states of goreadme jobs and the transitions between them.
+//
+// A job is created in the Queued state, or directly in one of the final states if it
+// can't run. A queued job is started by a worker, and a started job ends in one of the
+// final states. Interrupted jobs return to the Queued state, or fail if they can't be
+// queued again. Admins can override the state of finished jobs that did not succeed, such
+// as stuck jobs that were aborted, by resolving them.
+package status
+
+import "github.com/pkg/errors"
+
+// Status is the state of a job. The status of a project is the status of its last job.
+type Status string
+
+// Job states.
+const (
+	// Queued jobs wait for a worker.
+	Queued Status = "Queued"
+	// Started jobs are running.
+	Started Status = "Started"
+	// Retrying jobs failed and wait to be queued again.
+	Retrying Status = "Retrying"
+	// Success jobs finished successfully.
+	Success Status = "Success"
+	// Failed jobs finished with an error.
+	Failed Status = "Failed"
+	// Skipped jobs had nothing to do.
+	Skipped Status = "Skipped"
+	// Cancelled jobs were stopped before they finished.
+	Cancelled Status = "Cancelled"
+	// Aborted jobs did not finish in time.
+	Aborted Status = "Aborted"
+	// Suspended jobs were not run since their installation is suspended.
+	Suspended Status = "Suspended"
+	// NoPermissions jobs could not open a PR since the installation was not granted the
+	// required permissions.
+	NoPermissions Status = "No Permissions"
+	// PlanLimit jobs were not run since they exceed a limit of the installation plan.
+	PlanLimit Status = "Plan Limit"
+	// Resolved jobs were marked as resolved by an admin.
+	Resolved Status = "Resolved"
+)
+
+// All are all the valid states.
+var All = []Status{Queued, Started, Retrying, Success, Failed, Skipped, Cancelled, Aborted, Suspended, NoPermissions, PlanLimit, Resolved}
+
+// transitions are the allowed transitions from each state. States that are not listed
+// are final.
+var transitions = map[Status][]Status{
+	Queued:   {Queued, Started, Failed, Skipped, Cancelled, Suspended, PlanLimit},
+	Started:  {Queued, Success, Failed, Skipped, Cancelled, Aborted, Retrying, NoPermissions},
+	Retrying: {Queued, Failed, Cancelled, Aborted},
+}
+
+// Valid returns whether a status is a known state.
+func (s Status) Valid() bool {
+	for _, v := range All {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Final returns whether a job in this state will not change anymore.
+func (s Status) Final() bool {
+	_, ok := transitions[s]
+	return s.Valid() && !ok
+}
+
+// Resolvable returns whether an admin can resolve a job in this state. Jobs that did not
+// finish can't be resolved, since they would still run and override the resolved state.
+func (s Status) Resolvable() bool {
+	return s.Final() && s != Success && s != Resolved
+}
+
+// Failure returns whether the state means that the job did not finish successfully.
+func (s Status) Failure() bool {
+	return s == Failed || s == Aborted
+}
+
+// Check returns an error if a job can't move from one state to another.
+func Check(from, to Status) error {
+	if !to.Valid() {
+		return errors.Errorf("invalid status %q", to)
+	}
+	for _, next := range transitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid status transition %q -> %q", from, to)
+}
+
+// Color returns the bootstrap contextual color of the state.
+func (s Status) Color() string {
+	switch s {
+	case Success:
+		return "success"
+	case Failed, Aborted:
+		return "danger"
+	case Skipped, Cancelled, Resolved:
+		return "secondary"
+	default:
+		return "warning"
+	}
+}
+
+// BadgeColor returns the color of the state in badges.
+func (s Status) BadgeColor() string {
+	switch s {
+	case Success:
+		return "#2ecc71"
+	case Failed, Aborted:
+		return "#d35400"
+	default:
+		return "#2e4053"
+	}
+}
+
+// Colors returns the bootstrap contextual colors of all the states.
+func Colors() map[Status]string {
+	colors := make(map[Status]string, len(All))
+	for _, s := range All {
+		colors[s] = s.Color()
+	}
+	return colors
+}
@@ -0,0 +1,92 @@
+package status
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		ok       bool
+	}{
+		{from: Queued, to: Started, ok: true},
+		{from: Queued, to: Queued, ok: true},
+		{from: Queued, to: Suspended, ok: true},
+		{from: Queued, to: Success},
+		{from: Started, to: Success, ok: true},
+		{from: Started, to: Retrying, ok: true},
+		{from: Started, to: Queued, ok: true},
+		{from: Started, to: Suspended},
+		{from: Retrying, to: Queued, ok: true},
+		{from: Retrying, to: Failed, ok: true},
+		{from: Retrying, to: Success},
+		{from: Success, to: Queued},
+		{from: Failed, to: Started},
+		{from: Resolved, to: Queued},
+		{from: Queued, to: "Unknown"},
+		{from: "Unknown", to: Queued},
+	}
+	for _, tt := range tests {
+		err := Check(tt.from, tt.to)
+		if got := err == nil; got != tt.ok {
+			t.Errorf("Check(%q, %q) = %v, want ok=%v", tt.from, tt.to, err, tt.ok)
+		}
+	}
+}
+
+func TestFinal(t *testing.T) {
+	for _, s := range All {
+		want := s != Queued && s != Started && s != Retrying
+		if got := s.Final(); got != want {
+			t.Errorf("%q.Final() = %v, want %v", s, got, want)
+		}
+		// A final state can't move to any other state.
+		if !s.Final() {
+			continue
+		}
+		for _, to := range All {
+			if err := Check(s, to); err == nil {
+				t.Errorf("Check(%q, %q) of final state succeeded", s, to)
+			}
+		}
+	}
+	if Status("Unknown").Final() {
+		t.Error("Unknown status is final")
+	}
+}
+
+func TestResolvable(t *testing.T) {
+	resolvable := map[Status]bool{
+		Failed:        true,
+		Skipped:       true,
+		Cancelled:     true,
+		Aborted:       true,
+		Suspended:     true,
+		NoPermissions: true,
+		PlanLimit:     true,
+	}
+	for _, s := range All {
+		if got, want := s.Resolvable(), resolvable[s]; got != want {
+			t.Errorf("%q.Resolvable() = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestReachable(t *testing.T) {
+	// Every state except Resolved, that is set by admins, is reachable from Queued.
+	reached := map[Status]bool{Queued: true}
+	next := []Status{Queued}
+	for len(next) > 0 {
+		from := next[0]
+		next = next[1:]
+		for _, to := range transitions[from] {
+			if !reached[to] {
+				reached[to] = true
+				next = append(next, to)
+			}
+		}
+	}
+	for _, s := range All {
+		if s != Resolved && !reached[s] {
+			t.Errorf("State %q is not reachable from %q", s, Queued)
+		}
+	}
+}
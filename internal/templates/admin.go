@@ -0,0 +1,160 @@
+package templates
+
+import "html/template"
+
+var AdminFlags = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Feature Flags{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Feature Flags</h4>
+<p>
+	Flags with installation 0 apply to all installations, unless a value was set
+	for a specific installation.
+</p>
+<table class="table">
+	<tr>
+		<th>Name</th>
+		<th>Installation</th>
+		<th>Enabled</th>
+		<th>Updated</th>
+		<th></th>
+	</tr>
+{{ range .Flags }}
+	<tr>
+		<td><code>{{.Name}}</code></td>
+		<td>{{.Install}}</td>
+		<td class="text-{{if .Enabled}}success{{else}}danger{{end}}">{{.Enabled}}</td>
+		<td>{{formatDate .UpdatedAt}}</td>
+		<td>
+			<form action="/admin/flags" method="post" class="float-right">
+				<input type="hidden" name="name" value="{{.Name}}">
+				<input type="hidden" name="install" value="{{.Install}}">
+				<input type="hidden" name="enabled" value="{{not .Enabled}}">
+				<button type="submit" class="btn btn-outline-primary btn-sm">
+					{{if .Enabled}}Disable{{else}}Enable{{end}}
+				</button>
+				<button type="submit" name="delete" value="1" class="btn btn-outline-danger btn-sm" aria-label="Delete flag {{.Name}}" title="Delete">
+					<i class="fa fa-trash" aria-hidden="true"></i>
+				</button>
+			</form>
+		</td>
+	</tr>
+{{ end }}
+</table>
+
+<form action="/admin/flags" method="post" class="form-inline">
+	<input type="text" name="name" class="form-control mr-2" placeholder="Flag name" required>
+	<input type="number" name="install" class="form-control mr-2" placeholder="Installation" value="0" required>
+	<input type="hidden" name="enabled" value="true">
+	<button type="submit" class="btn btn-outline-primary">Enable</button>
+</form>
+</div>
+</div>
+{{end}}
+`))
+
+var AdminPlans = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Plans{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Plans</h4>
+<p>
+	Installations that are not listed are on the free plan.
+</p>
+<table class="table">
+	<tr>
+		<th>Plan</th>
+		<th>Private repositories</th>
+		<th>Schedules</th>
+		<th>API tokens</th>
+	</tr>
+{{ range .AvailablePlans }}
+	<tr>
+		<td><code>{{.Name}}</code></td>
+		<td>{{if lt .PrivateRepos 0}}Unlimited{{else}}{{.PrivateRepos}}{{end}}</td>
+		<td>{{if lt .Schedules 0}}Unlimited{{else}}{{.Schedules}}{{end}}</td>
+		<td>{{if lt .APITokens 0}}Unlimited{{else}}{{.APITokens}}{{end}}</td>
+	</tr>
+{{ end }}
+</table>
+
+<h5 class="mt-4">Installations</h5>
+<table class="table">
+	<tr>
+		<th>Installation</th>
+		<th>Plan</th>
+		<th>Updated</th>
+	</tr>
+{{ range .Plans }}
+	<tr>
+		<td>{{.Install}}</td>
+		<td><code>{{.Plan}}</code></td>
+		<td>{{formatDate .UpdatedAt}}</td>
+	</tr>
+{{ end }}
+</table>
+
+<form action="/admin/plans" method="post" class="form-inline">
+	<input type="number" name="install" class="form-control mr-2" placeholder="Installation" required>
+	<select name="plan" class="form-control mr-2">
+	{{ range .AvailablePlans }}
+		<option value="{{.Name}}">{{.Name}}</option>
+	{{ end }}
+	</select>
+	<button type="submit" class="btn btn-outline-primary">Set</button>
+</form>
+
+<h5 class="mt-4">Backfill</h5>
+<p>
+	Start initial jobs for installed repositories that have no project, one job per interval.
+	The progress is reported in the server logs.
+</p>
+<form action="/admin/backfill" method="post" class="form-inline">
+	<label for="interval" class="mr-2">Interval</label>
+	<input type="text" id="interval" name="interval" class="form-control mr-2" value="5s" required>
+	<div class="form-check mr-2">
+		<input type="checkbox" class="form-check-input" id="dry_run" name="dry_run" value="true">
+		<label class="form-check-label" for="dry_run">Dry run</label>
+	</div>
+	<button type="submit" class="btn btn-outline-primary">Backfill</button>
+</form>
+</div>
+</div>
+{{end}}
+`))
+
+var AdminRuntime = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Runtime Settings{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Runtime Settings</h4>
+<p>
+	Runtime settings override the environment configuration without a restart. All the
+	server instances reload them every minute, and on a SIGHUP signal. Leave a setting empty
+	to use the environment configuration.
+</p>
+<form action="/admin/runtime" method="post">
+{{ range .RuntimeSettings }}
+	<div class="form-group">
+		<label for="{{.Key}}"><code>{{.Key}}</code></label>
+		<input type="text" id="{{.Key}}" name="{{.Key}}" class="form-control" value="{{.Value}}" placeholder="{{.Default}}">
+		<small class="form-text text-muted">{{.Description}}</small>
+	</div>
+{{ end }}
+	<button type="submit" class="btn btn-outline-primary">Save</button>
+</form>
+
+<h5 class="mt-4">Build</h5>
+<table class="table table-sm">
+	<tr><th>Version</th><td><code>{{.Build.Version}}</code></td></tr>
+	<tr><th>Commit</th><td>{{ if .Build.Commit }}<a href="https://github.com/posener/goreadme-server/commit/{{.Build.Commit}}"><code>{{sha .Build.Commit}}</code></a>{{ else }}Unknown{{ end }}</td></tr>
+	<tr><th>Go</th><td><code>{{.Build.GoVersion}}</code></td></tr>
+	<tr><th>Goreadme</th><td><code>{{.Build.GoreadmeVersion}}</code></td></tr>
+</table>
+</div>
+</div>
+{{end}}
+`))
@@ -0,0 +1,108 @@
+package templates
+
+import "html/template"
+
+var ConfigGenerator = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Configuration Generator{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Configuration Generator</h4>
+<p>
+	Choose the options for the generated readme file, and download the <code>goreadme.json</code>
+	file or open a PR that adds it to your repository main directory.
+</p>
+
+<form action="/config" method="post">
+	<h5 class="mt-4">Content</h5>
+	{{ template "checkbox" dict "name" "functions" "label" "Add functions documentation" }}
+	{{ template "checkbox" dict "name" "skip_examples" "label" "Omit the examples section" }}
+	{{ template "checkbox" dict "name" "skip_sub_packages" "label" "Omit the sub packages section" }}
+	{{ template "checkbox" dict "name" "recursive_sub_packages" "label" "List sub packages recursively" }}
+
+	<h5 class="mt-4">Badges</h5>
+	{{ template "checkbox" dict "name" "badges.goreadme" "label" "Goreadme" }}
+	{{ template "checkbox" dict "name" "badges.go_doc" "label" "GoDoc" }}
+	{{ template "checkbox" dict "name" "badges.travis_ci" "label" "Travis CI" }}
+	{{ template "checkbox" dict "name" "badges.code_cov" "label" "Codecov" }}
+	{{ template "checkbox" dict "name" "badges.golang_ci" "label" "GolangCI" }}
+	{{ template "checkbox" dict "name" "badges.go_report_card" "label" "Go Report Card" }}
+
+	<h5 class="mt-4">Layout</h5>
+	<div class="form-group">
+		<label for="header_file">Header file</label>
+		<input type="text" class="form-control" id="header_file" name="header_file" placeholder="docs/header.md">
+		<small class="form-text text-muted">Path of a file in the repository which is added to the top of the readme.</small>
+	</div>
+	<div class="form-group">
+		<label for="footer_file">Footer file</label>
+		<input type="text" class="form-control" id="footer_file" name="footer_file" placeholder="docs/footer.md">
+		<small class="form-text text-muted">Path of a file in the repository which is added to the bottom of the readme.</small>
+	</div>
+	<div class="form-group">
+		<label for="sections">Sections order</label>
+		<input type="text" class="form-control" id="sections" name="sections" placeholder="badges, description, install, usage, examples, subpackages">
+		<small class="form-text text-muted">Comma separated readme sections, in the order that they should appear. Other sections follow in their original order.</small>
+	</div>
+	<div class="form-group">
+		<label for="package_dir">Package directory</label>
+		<input type="text" class="form-control" id="package_dir" name="package_dir" placeholder="Detected">
+		<small class="form-text text-muted">Directory of the Go package of the readme. Repositories without Go code in the root directory use their Go module directory.</small>
+	</div>
+	{{ template "checkbox" dict "name" "normalize_readme" "label" "Rename an existing readme file to README.md" }}
+
+	<h5 class="mt-4">Changes</h5>
+	<div class="form-group">
+		<label for="min_change">Minimal change</label>
+		<input type="number" min="0" class="form-control" id="min_change" name="min_change" value="0">
+		<small class="form-text text-muted">Number of changed characters below which readme updates are ignored.</small>
+	</div>
+	{{ template "checkbox" dict "name" "ignore_whitespace" "label" "Ignore whitespace only changes" }}
+	<div class="form-group">
+		<label for="pr_strategy">Pull requests</label>
+		<select class="form-control" id="pr_strategy" name="pr_strategy">
+			<option value="">Update a single goreadme pull request</option>
+			<option value="new">Open a new pull request for every change</option>
+			<option value="amend">Reset the goreadme pull request on every change, and comment on it</option>
+		</select>
+	</div>
+	<div class="form-group">
+		<label for="closes_issue">Linked issue</label>
+		<input type="number" min="0" class="form-control" id="closes_issue" name="closes_issue" value="0">
+		<small class="form-text text-muted">Number of an issue that goreadme pull requests close when they are merged, such as a tracking issue of the readme. Zero links no issue.</small>
+	</div>
+
+	<div class="mt-4">
+		<button type="submit" name="action" value="download" class="btn btn-outline-primary">
+			<i class="fa fa-download" aria-hidden="true"></i>
+			Download
+		</button>
+	</div>
+
+	{{ if .ConfigRepos }}
+	<h5 class="mt-4">Add to Repository</h5>
+	<div class="form-inline">
+		<label for="config-repo" class="sr-only">Repository</label>
+		<select class="form-control mr-2" name="repo" id="config-repo">
+			{{ range .ConfigRepos }}
+			<option value="{{.GetFullName}}">{{.GetFullName}}</option>
+			{{ end }}
+		</select>
+		<button type="submit" name="action" value="pr" class="btn btn-outline-primary">
+			<i class="fa fa-code-fork" aria-hidden="true"></i>
+			Open PR
+		</button>
+	</div>
+	{{ end }}
+</form>
+</div>
+</div>
+{{end}}
+
+{{define "checkbox"}}
+<div class="form-check">
+	<input class="form-check-input" type="checkbox" id="{{.name}}" name="{{.name}}">
+	<label class="form-check-label" for="{{.name}}">{{.label}}</label>
+</div>
+{{end}}
+`))
@@ -0,0 +1,26 @@
+package templates
+
+import texttemplate "text/template"
+
+// PRBody is the markdown body of goreadme pull requests.
+var PRBody = texttemplate.Must(texttemplate.New("pr").Parse(`This pull request updates the readme according to the Go doc of the ` + "`{{.Branch}}`" + ` branch at {{.HeadSHA}}.
+{{if .Changes}}
+| File | Added lines | Removed lines |
+| --- | ---: | ---: |
+{{range .Changes}}| ` + "`{{.Path}}`" + ` | +{{.Added}} | -{{.Removed}} |
+{{end}}{{end}}{{if .ClosesIssue}}
+Closes #{{.ClosesIssue}}.
+{{end}}
+<details>
+<summary>Generation details</summary>
+
+- Goreadme version: ` + "`{{.Version}}`" + `
+- Configuration hash: ` + "`{{.ConfigHash}}`" + `
+- Job: [#{{.Num}}]({{.JobURL}}){{if .Trigger}}, triggered by {{.Trigger}}{{end}}
+</details>
+
+Goreadme updates this pull request whenever the Go doc changes. The readme can be customized
+with a ` + "`goreadme.json`" + ` file or in the [project settings]({{.SettingsURL}}). To stop
+goreadme from updating this repository, {{if .RepoHook}}delete its [repository webhook]({{.InstallationURL}}){{else}}remove it from the repositories of the
+[goreadme installation]({{.InstallationURL}}){{end}}.
+`))
@@ -0,0 +1,333 @@
+package templates
+
+import "html/template"
+
+var ProjectSettings = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Project Settings{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>
+	<a href="https://github.com/{{.Project.Owner}}/{{.Project.Repo}}" aria-label="{{.Project.Owner}}/{{.Project.Repo}} on Github"><i class="fa fa-github" aria-hidden="true"></i></a>
+	{{.Project.Owner}}/{{.Project.Repo}}
+</h4>
+
+<h5 class="mt-4">Badges</h5>
+<div class="form-group">
+	<label for="snippet-format" class="sr-only">Snippet format</label>
+	<select class="form-control" id="snippet-format">
+		<option value="markdown">Markdown</option>
+		<option value="html">HTML</option>
+		<option value="asciidoc">AsciiDoc</option>
+	</select>
+</div>
+<div id="snippets" aria-live="polite"></div>
+<noscript>
+	{{ range .Badges }}
+	<div class="form-group">
+		<label for="badge-{{.Name}}">{{.Name}}</label>
+		<input type="text" class="form-control" id="badge-{{.Name}}" value="{{.Markdown}}" readonly>
+	</div>
+	{{ end }}
+</noscript>
+<script>
+	(function() {
+		var badges = [];
+		var format = document.getElementById('snippet-format');
+		var container = document.getElementById('snippets');
+		function render() {
+			container.innerHTML = '';
+			badges.forEach(function(badge) {
+				var group = document.createElement('div');
+				group.className = 'input-group mb-2';
+				var input = document.createElement('input');
+				input.type = 'text';
+				input.readOnly = true;
+				input.className = 'form-control';
+				input.value = badge[format.value];
+				input.setAttribute('aria-label', badge.name + ' badge');
+				var append = document.createElement('div');
+				append.className = 'input-group-append';
+				var button = document.createElement('button');
+				button.type = 'button';
+				button.className = 'btn btn-outline-secondary';
+				button.textContent = 'Copy';
+				button.addEventListener('click', function() {
+					input.select();
+					document.execCommand('copy');
+					button.textContent = 'Copied';
+				});
+				append.appendChild(button);
+				group.appendChild(input);
+				group.appendChild(append);
+				container.appendChild(group);
+			});
+		}
+		format.addEventListener('change', render);
+		fetch('/api/v1/projects/{{.Project.Owner}}/{{.Project.Repo}}/snippet')
+			.then(function(resp) { return resp.json(); })
+			.then(function(data) { badges = data.badges; render(); });
+	})();
+</script>
+
+<form method="post">
+	<h5 class="mt-4">Notifications</h5>
+	<div class="form-group">
+		<label for="notify_on">Notify on</label>
+		<select class="form-control" id="notify_on" name="notify_on">
+			<option value="failed">Failed jobs</option>
+			<option value="all" {{if eq (index .Settings "notify_on") "all"}}selected{{end}}>All jobs</option>
+		</select>
+	</div>
+	{{ range .Notifiers }}
+	<div class="form-group">
+		<label for="notify.{{.Name}}">{{.Name}}</label>
+		<input type="text" class="form-control" id="notify.{{.Name}}" name="notify.{{.Name}}" value="{{.Target}}">
+		<small class="form-text text-muted">{{.Description}}. Leave empty to disable.</small>
+	</div>
+	{{ end }}
+
+	<h5 class="mt-4">Pull requests</h5>
+	<div class="form-group">
+		<label for="remind_stale_pr">Stale pull request reminders</label>
+		<select class="form-control" id="remind_stale_pr" name="remind_stale_pr">
+			<option value="">Comment on pull requests that are open for a long time</option>
+			<option value="off" {{if eq (index .Settings "remind_stale_pr") "off"}}selected{{end}}>Off</option>
+		</select>
+	</div>
+
+	<div class="form-group">
+		<label for="push_paths">Push paths</label>
+		<input type="text" class="form-control" id="push_paths" name="push_paths" value="{{index .Settings "push_paths"}}" placeholder="*.go, goreadme.json, go.mod, README.md">
+		<small class="form-text text-muted">
+			Pushes run goreadme only if they change files that match these comma separated patterns.
+			Patterns without a slash match file names in any directory.
+			Leave empty to match Go files and goreadme configuration files.
+		</small>
+	</div>
+
+	{{ if .Project.ForkOf }}
+	<div class="form-group">
+		<label for="fork_runs">Fork runs</label>
+		<select class="form-control" id="fork_runs" name="fork_runs">
+			<option value="">Run only manually, since the repository is a fork of {{.Project.ForkOf}}</option>
+			<option value="on" {{if eq (index .Settings "fork_runs") "on"}}selected{{end}}>Run automatically, like in other repositories</option>
+		</select>
+	</div>
+	{{ end }}
+
+	<h5 class="mt-4">Readme</h5>
+	<small class="form-text text-muted mb-2">
+		Checked options are added to the options of the <code>goreadme.json</code> file in the repository.
+	</small>
+	{{ range .ConfigOptions }}
+	<div class="form-check">
+		<input type="checkbox" class="form-check-input" id="{{.Key}}" name="{{.Key}}" value="true" {{if .Enabled}}checked{{end}}>
+		<label class="form-check-label" for="{{.Key}}">{{.Label}}</label>
+		<small class="form-text text-muted">{{.Description}}.</small>
+	</div>
+	{{ end }}
+
+	<button type="submit" class="btn btn-outline-primary">Save</button>
+</form>
+</div>
+</div>
+{{end}}
+`))
+
+var InstallSettings = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Settings{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Settings of {{.Account}}</h4>
+
+<form method="post">
+	<h5 class="mt-4">Repositories</h5>
+	<small class="form-text text-muted mb-2">
+		Goreadme runs automatically only on installed repositories that match these lists.
+		Patterns are comma separated repository names, that may contain wildcards, such as <code>go-*</code>.
+	</small>
+	<div class="form-group">
+		<label for="allow_repos">Allowed repositories</label>
+		<input type="text" class="form-control" id="allow_repos" name="allow_repos" value="{{index .Settings "allow_repos"}}" placeholder="go-*">
+		<small class="form-text text-muted">Leave empty to allow all the installed repositories.</small>
+	</div>
+	<div class="form-group">
+		<label for="deny_repos">Denied repositories</label>
+		<input type="text" class="form-control" id="deny_repos" name="deny_repos" value="{{index .Settings "deny_repos"}}" placeholder="*-deploy">
+		<small class="form-text text-muted">Denied repositories are skipped even if they are allowed.</small>
+	</div>
+
+	<h5 class="mt-4">Display</h5>
+	<div class="form-group">
+		<label for="timezone">Timezone</label>
+		<div class="input-group">
+			<input type="text" class="form-control" id="timezone" name="timezone" value="{{index .Settings "timezone"}}" placeholder="Europe/London">
+			<div class="input-group-append">
+				<button type="button" class="btn btn-outline-secondary" id="detect-timezone" hidden>Detect</button>
+			</div>
+		</div>
+		<small class="form-text text-muted">Times are shown in this timezone. Leave empty to use the browser timezone.</small>
+	</div>
+	{{ if ne .Account .User.GetLogin }}
+	<h5 class="mt-4">Team Roles</h5>
+	<div class="form-group">
+		<label for="team_roles">Roles of organization teams</label>
+		<textarea class="form-control" id="team_roles" name="team_roles" rows="3" placeholder="developers=viewer&#10;ops=operator">{{.TeamRoles}}</textarea>
+		<small class="form-text text-muted">
+			One <code>team=role</code> in a line, where team is the team slug. Viewers can see the
+			projects and jobs, operators can also run jobs and change project settings, and admins can
+			also change these settings. Members that are not in any of the teams have no access.
+		</small>
+	</div>
+	{{ end }}
+
+	<script>
+		// Detecting the timezone requires scripts, so the button is shown only when they run.
+		var detect = document.getElementById('detect-timezone');
+		detect.hidden = false;
+		detect.addEventListener('click', function() {
+			document.getElementById('timezone').value = Intl.DateTimeFormat().resolvedOptions().timeZone;
+		});
+	</script>
+
+	{{ if eq .Role "admin" }}
+	<button type="submit" class="btn btn-outline-primary">Save</button>
+	{{ else }}
+	<p class="text-muted">Only admins of {{.Account}} can change these settings.</p>
+	{{ end }}
+</form>
+
+<h5 class="mt-5">Organization</h5>
+<form method="post" action="/account/switch" class="form-inline">
+	<label for="account" class="mr-2">Act on the installation of</label>
+	<input type="text" class="form-control mr-2" id="account" name="account" value="{{if ne .Account .User.GetLogin}}{{.Account}}{{end}}" placeholder="{{.User.GetLogin}}">
+	<button type="submit" class="btn btn-outline-primary">Switch</button>
+</form>
+<small class="form-text text-muted">
+	Leave empty to return to your personal installation. Switching asks for read access to your
+	organizations on Github, to check that you are a member.
+</small>
+
+<h5 class="mt-5">Account</h5>
+<ul class="list-unstyled">
+	<li><a href="{{.User.GetHTMLURL}}">Github page</a></li>
+	<li><a href="/hooks">Repository webhooks</a></li>
+	{{ if .Admin }}
+	<li><a href="/admin/flags">Feature flags</a></li>
+	<li><a href="/admin/plans">Plans</a></li>
+	<li><a href="/admin/runtime">Runtime settings</a></li>
+	{{ end }}
+	<li><a href="/auth/logout">Logout</a></li>
+</ul>
+<p>
+	Delete the data that goreadme stores about you.
+	<a href="/account/delete" class="btn btn-outline-danger btn-sm ml-2">Delete my account</a>
+</p>
+</div>
+</div>
+{{end}}
+`))
+
+var DeleteAccount = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Delete Account{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Delete Account</h4>
+<p>
+	This deletes your API tokens, your repository webhooks, your audit log entries and the
+	installation record of your account with its plan and usage, and logs you out of all your
+	sessions. It can't be undone. To stop goreadme from running on your repositories, uninstall
+	the <a href="https://github.com/apps/goreadme">Github app</a>.
+</p>
+
+<form method="post">
+	<div class="form-check mb-3">
+		<input type="checkbox" class="form-check-input" id="projects" name="projects" value="true">
+		<label class="form-check-label" for="projects">Also delete the projects and jobs of the repositories of {{.User.GetLogin}}</label>
+	</div>
+	<div class="form-group">
+		<label for="confirm">Type <code>{{.User.GetLogin}}</code> to confirm</label>
+		<input type="text" class="form-control" id="confirm" name="confirm" autocomplete="off" required>
+	</div>
+	<button type="submit" class="btn btn-danger">Delete my account</button>
+	<a href="/settings" class="btn btn-outline-secondary">Cancel</a>
+</form>
+</div>
+</div>
+{{end}}
+`))
+
+var RepoHooks = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Repository Webhooks{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Repository Webhooks</h4>
+<p>
+	Goreadme can run on repositories without installing the Github app. Provide a Github
+	token with push access to the repository, then add a webhook in the repository settings
+	with the payload URL <code>{{.RepoHookURL}}</code>, the <code>application/json</code>
+	content type and the secret that is shown below, for the push events.
+</p>
+
+{{ if .RepoHooks }}
+<table class="table table-sm">
+	<tr>
+		<th>Repository</th>
+		<th>Secret</th>
+		<th>Status</th>
+		<th></th>
+	</tr>
+	{{ range .RepoHooks }}
+	<tr>
+		<td><a href="/jobs?owner={{.Owner}}&repo={{.Repo}}">{{.Owner}}/{{.Repo}}</a></td>
+		<td><code>{{.Secret}}</code></td>
+		<td>{{ if .Status }}<span class="text-{{ color .Status }}">{{.Status}}</span>{{ end }}</td>
+		<td>
+			<form method="post">
+				<input type="hidden" name="action" value="delete">
+				<input type="hidden" name="owner" value="{{.Owner}}">
+				<input type="hidden" name="repo" value="{{.Repo}}">
+				<button type="submit" class="btn btn-outline-danger btn-sm" aria-label="Delete the webhook of {{.Owner}}/{{.Repo}}" title="Delete">
+					<i class="fa fa-trash" aria-hidden="true"></i>
+				</button>
+			</form>
+		</td>
+	</tr>
+	{{ end }}
+</table>
+<small class="form-text text-muted mb-4">
+	Remove the webhook from the repository settings after deleting it here.
+</small>
+{{ end }}
+
+<h5 class="mt-4">Add a repository</h5>
+<form method="post">
+	<input type="hidden" name="action" value="add">
+	<div class="form-row">
+		<div class="form-group col-md-6">
+			<label for="owner">Owner</label>
+			<input type="text" class="form-control" id="owner" name="owner" placeholder="{{.User.GetLogin}}" required>
+		</div>
+		<div class="form-group col-md-6">
+			<label for="repo">Repository</label>
+			<input type="text" class="form-control" id="repo" name="repo" required>
+		</div>
+	</div>
+	<div class="form-group">
+		<label for="token">Github token</label>
+		<input type="password" class="form-control" id="token" name="token" autocomplete="off" required>
+		<small class="form-text text-muted">
+			A personal access token with the <code>repo</code> scope, or the <code>public_repo</code>
+			scope for public repositories. It is stored encrypted and used only for this repository.
+		</small>
+	</div>
+	<button type="submit" class="btn btn-outline-primary">Add</button>
+</form>
+</div>
+</div>
+{{end}}
+`))
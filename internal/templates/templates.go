@@ -1,18 +1,28 @@
 package templates
 
 import (
+	"fmt"
 	"html/template"
+	"strings"
 	"time"
 
 	prettytime "github.com/andanhm/go-prettytime"
 	"github.com/hako/durafmt"
+	"github.com/posener/goreadme-server/internal/static"
+	"github.com/posener/goreadme-server/internal/status"
 )
 
+// Version is the server version that is shown in the page footers.
+var Version string
+
 var html = template.Must(
 	template.New("html").Funcs(
 		template.FuncMap{
-			"formatDate": func(t time.Time) string {
-				return prettytime.Format(t)
+			// formatDate formats a time relatively to now. The absolute time is in the title,
+			// and is shown in the user timezone by the client.
+			"formatDate": func(t time.Time) template.HTML {
+				return template.HTML(fmt.Sprintf(`<time datetime="%s" title="%s">%s</time>`,
+					t.UTC().Format(time.RFC3339), t.UTC().Format("2006-01-02 15:04 MST"), template.HTMLEscapeString(prettytime.Format(t))))
 			},
 			"formatDuration": func(d time.Duration) string {
 				return durafmt.ParseShort(d).String()
@@ -23,16 +33,23 @@ var html = template.Must(
 				}
 				return sha[:8]
 			},
-			"color": func(status string) string {
-				switch status {
-				case "Failed":
-					return "danger"
-				case "Success":
-					return "success"
-				default:
-					return "warning"
+			"static": static.URL,
+			"firstLine": func(s string) string {
+				if i := strings.IndexByte(s, '\n'); i >= 0 {
+					return s[:i]
+				}
+				return s
+			},
+			"dict": func(kv ...string) map[string]string {
+				m := make(map[string]string, len(kv)/2)
+				for i := 0; i+1 < len(kv); i += 2 {
+					m[kv[i]] = kv[i+1]
 				}
+				return m
 			},
+			"color":        status.Status.Color,
+			"statusColors": status.Colors,
+			"version":      func() string { return Version },
 		}).Parse(`
 <html lang="en">
 <head>
@@ -40,20 +57,79 @@ var html = template.Must(
   <meta http-equiv="X-UA-Compatible" content="IE=edge">
   <meta name="viewport" content="width=device-width, initial-scale=1">
   <title>Goreadme</title>
-  <link rel="stylesheet" href="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/css/bootstrap.min.css" integrity="sha384-ggOyR0iXCbMQv3Xipma34MD+dH/1fQ784/j6cY/iJTQUOhcWr7x9JvoRxT2MZw1T" crossorigin="anonymous">
-  <link href="https://maxcdn.bootstrapcdn.com/font-awesome/4.7.0/css/font-awesome.min.css" rel="stylesheet">
+  <link rel="stylesheet" href="{{ static "bootstrap/css/bootstrap.min.css" }}" integrity="sha384-ggOyR0iXCbMQv3Xipma34MD+dH/1fQ784/j6cY/iJTQUOhcWr7x9JvoRxT2MZw1T" crossorigin="anonymous">
+  <link href="{{ static "font-awesome/css/font-awesome.min.css" }}" rel="stylesheet">
   <link rel="shortcut icon" type="image/png" href="https://raw.githubusercontent.com/posener/goreadme-server/master/media/favicon.ico"/>
+  <style>
+    /* Keep the keyboard focus visible on all the controls. */
+    a:focus, button:focus, .btn:focus, .form-control:focus, .form-check-input:focus {
+      outline: 2px solid #0056b3;
+      outline-offset: 2px;
+    }
+  </style>
+  <noscript>
+    <style>
+      /* Without scripts the navigation can't be toggled, so it is always shown. */
+      .navbar-collapse.collapse { display: flex !important; flex-wrap: wrap; }
+      .alert .close { display: none; }
+    </style>
+  </noscript>
 </head>
-<body>
+<body data-timezone="{{.Timezone}}">
+<a class="sr-only sr-only-focusable" href="#content">Skip to content</a>
 
 {{template "body" .}}
 
-  <script src="https://code.jquery.com/jquery-3.3.1.slim.min.js" integrity="sha384-q8i/X+965DzO0rT7abK41JStQIAqVgRVzpbzo5smXKp4YfRvH+8abtTE1Pi6jizo" crossorigin="anonymous"></script>
-  <script src="https://cdnjs.cloudflare.com/ajax/libs/popper.js/1.14.7/umd/popper.min.js" integrity="sha384-UO2eT0CpHqdSJQ6hJty5KVphtPhzWj9WO1clHTMGa3JDZwrnQq4sF86dIHNDz0W1" crossorigin="anonymous"></script>
-  <script src="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/js/bootstrap.min.js" integrity="sha384-JjSmVgyd0p3pXB1rRibZUAYoIIy6OrQ6VrjIEaFf/nJGzIxFDsf4x0xIM+B07jRM" crossorigin="anonymous"></script>
+  <script src="{{ static "jquery/jquery.slim.min.js" }}" integrity="sha384-q8i/X+965DzO0rT7abK41JStQIAqVgRVzpbzo5smXKp4YfRvH+8abtTE1Pi6jizo" crossorigin="anonymous"></script>
+  <script src="{{ static "popper.js/popper.min.js" }}" integrity="sha384-UO2eT0CpHqdSJQ6hJty5KVphtPhzWj9WO1clHTMGa3JDZwrnQq4sF86dIHNDz0W1" crossorigin="anonymous"></script>
+  <script src="{{ static "bootstrap/js/bootstrap.min.js" }}" integrity="sha384-JjSmVgyd0p3pXB1rRibZUAYoIIy6OrQ6VrjIEaFf/nJGzIxFDsf4x0xIM+B07jRM" crossorigin="anonymous"></script>
   {{if .Error}}
   <script>$('.alert').alert()</script>
   {{end}}
+  <script>
+    // Show absolute times in the preferred timezone of the user, or in the browser timezone.
+    (function() {
+      var timeZone = document.body.getAttribute('data-timezone') || Intl.DateTimeFormat().resolvedOptions().timeZone;
+      document.querySelectorAll('time[datetime]').forEach(function(el) {
+        try {
+          el.title = new Date(el.getAttribute('datetime')).toLocaleString(undefined, {timeZone: timeZone, timeZoneName: 'short'});
+        } catch (e) {
+          // Unknown timezone, keep the UTC time.
+        }
+      });
+    })();
+  </script>
+  {{if or .Projects .Jobs}}
+  <script>
+    // Update job rows in place according to job events from the server.
+    var colors = {{ statusColors }};
+    var source = new EventSource('/events');
+    source.onmessage = function(m) {
+      var e = JSON.parse(m.data);
+      var rows = $('.live-row').filter(function() {
+        var row = $(this);
+        var num = row.data('num');
+        return row.data('owner') == e.owner && row.data('repo') == e.repo && (!num || num == e.num);
+      });
+      {{if .Jobs}}
+      if (rows.length == 0) {
+        // A new job was started, it needs a new row.
+        location.reload();
+        return;
+      }
+      {{end}}
+      rows.find('.live-status').attr('class', 'live-status text-' + (colors[e.status] || 'warning')).text(e.status);
+      rows.find('.live-message').text(e.message);
+      if (e.status != 'Queued') {
+        rows.find('.live-queue').remove();
+      }
+      if (e.pr) {
+        var link = $('<a>').attr('href', 'https://github.com/' + e.owner + '/' + e.repo + '/pull/' + e.pr).text('PR#' + e.pr);
+        rows.find('.live-pr').empty().append($('<small>').append(link));
+      }
+    };
+  </script>
+  {{end}}
   
   <!-- Global site tag (gtag.js) - Google Analytics -->
   <script async src="/analytics/gtag/js?id=UA-119938419-2"></script>
@@ -73,33 +149,45 @@ var base = template.Must(html.Parse(`
 {{define "body"}}
 <nav class="navbar navbar-expand-md navbar-light bg-light">
 	<a class="navbar-brand abs" href="/">
-		<img src="https://raw.githubusercontent.com/posener/goreadme-server/master/media/icon.png" width="30" height="30" alt="">
+		<img src="https://raw.githubusercontent.com/posener/goreadme-server/master/media/icon.png" width="30" height="30" alt="" aria-hidden="true">
 		Goreadme
 	</a>
 	{{ if .User }}
-		<button class="navbar-toggler" type="button" data-toggle="collapse" data-target="#collapsingNavbar">
+		<button class="navbar-toggler" type="button" data-toggle="collapse" data-target="#collapsingNavbar" aria-controls="collapsingNavbar" aria-expanded="false" aria-label="Toggle navigation">
 			<span class="navbar-toggler-icon"></span>
 		</button>
 		<div class="navbar-collapse collapse" id="collapsingNavbar">
-			<ul class="navbar-nav">
+			<ul class="navbar-nav" aria-label="Main">
 				<li class="nav-item {{if .Projects}}active{{end}}">
-					<a class="nav-link" href="/projects">
+					<a class="nav-link" href="/projects"{{if .Projects}} aria-current="page"{{end}}>
 						<i class="fa fa-book" aria-hidden="true"></i>
 						Projects
 					</a>
 				</li>
 				<li class="nav-item {{if .Jobs}}active{{end}}">
-					<a class="nav-link" href="/jobs">
+					<a class="nav-link" href="/jobs"{{if .Jobs}} aria-current="page"{{end}}>
 						<i class="fa fa-history" aria-hidden="true"></i>
 						History
 					</a>
 				</li>
+				<li class="nav-item {{if .Usage}}active{{end}}">
+					<a class="nav-link" href="/usage"{{if .Usage}} aria-current="page"{{end}}>
+						<i class="fa fa-bar-chart" aria-hidden="true"></i>
+						Usage
+					</a>
+				</li>
 				<li class="nav-item {{if .Repos}}active{{end}}">
-					<a class="nav-link" href="/add">
+					<a class="nav-link" href="/add"{{if .Repos}} aria-current="page"{{end}}>
 						<i class="fa fa-play-circle" aria-hidden="true"></i>
 						Integrations
 					</a>
 				</li>
+				<li class="nav-item {{if .ConfigRepos}}active{{end}}">
+					<a class="nav-link" href="/config"{{if .ConfigRepos}} aria-current="page"{{end}}>
+						<i class="fa fa-sliders" aria-hidden="true"></i>
+						Config
+					</a>
+				</li>
 				<li class="nav-item">
 					<a class="nav-link" href="https://github.com{{if .InstallID}}/settings/installations/{{.InstallID}}{{else}}/apps/goreadme/installations/new{{end}}">
 						<i class="fa fa-wrench" aria-hidden="true"></i>
@@ -109,15 +197,37 @@ var base = template.Must(html.Parse(`
 			</ul>
 			<ul class="navbar-nav ml-auto">
 				<li class="nav-item dropdown">
-					<a class="nav-link dropdown-toggle" href="#" id="navbarDropdown" role="button" data-toggle="dropdown" aria-haspopup="true" aria-expanded="false">
-						<img src="{{.User.GetAvatarURL}}" width="30" height="30" class="d-inline-block align-top" alt="">
-						{{.User.GetLogin}}
+					<a class="nav-link dropdown-toggle" href="/settings" id="navbarDropdown" role="button" data-toggle="dropdown" aria-haspopup="true" aria-expanded="false" aria-label="Account menu of {{.User.GetLogin}}">
+						<img src="{{.User.GetAvatarURL}}" width="30" height="30" class="d-inline-block align-top" alt="" aria-hidden="true">
+						{{.User.GetLogin}}{{ if and .Account (ne .Account .User.GetLogin) }} ({{.Account}}){{ end }}
 					</a>
 					<div class="dropdown-menu" aria-labelledby="navbarDropdown">
 						<a class="dropdown-item" href="{{.User.GetHTMLURL}}">
 							<i class="fa fa-github" aria-hidden="true"></i>
 							Github page
 						</a>
+						<a class="dropdown-item" href="/settings">
+							<i class="fa fa-cog" aria-hidden="true"></i>
+							Settings
+						</a>
+						<a class="dropdown-item" href="/hooks">
+							<i class="fa fa-plug" aria-hidden="true"></i>
+							Repository webhooks
+						</a>
+						{{ if .Admin }}
+						<a class="dropdown-item" href="/admin/flags">
+							<i class="fa fa-flag" aria-hidden="true"></i>
+							Feature flags
+						</a>
+						<a class="dropdown-item" href="/admin/plans">
+							<i class="fa fa-credit-card" aria-hidden="true"></i>
+							Plans
+						</a>
+						<a class="dropdown-item" href="/admin/runtime">
+							<i class="fa fa-sliders" aria-hidden="true"></i>
+							Runtime settings
+						</a>
+						{{ end }}
 						<a class="dropdown-item" href="/auth/logout">
 							<i class="fa fa-sign-out" aria-hidden="true"></i>
 							Logout
@@ -129,7 +239,15 @@ var base = template.Must(html.Parse(`
 	{{ end }}
 </nav>
 
-	<div class="container p-4">
+	<main class="container p-4" id="content" tabindex="-1">
+
+	{{ if .MissingPermissions }}
+		<div class="alert alert-warning" role="alert">
+			Goreadme can't open pull requests, since it was not granted write permission for:
+			{{ range $i, $p := .MissingPermissions }}{{ if $i }}, {{ end }}<code>{{ $p }}</code>{{ end }}.
+			<a class="alert-link" href="https://github.com/settings/installations/{{.InstallID}}">Review permissions</a>
+		</div>
+	{{ end }}
 
 	{{ if .Error }}
 		<div class="alert alert-danger alert-dismissible fade show" role="alert">
@@ -142,7 +260,13 @@ var base = template.Must(html.Parse(`
 
 	{{template "content" .}}
 
-	</div>
+	{{ if or .Projects .Jobs }}
+	<noscript>
+		<p class="mt-3"><small>Job statuses are not updated live. <a href="">Reload</a> the page to see the latest statuses.</small></p>
+	</noscript>
+	{{ end }}
+
+	</main>
 	
 	</div>
 
@@ -164,7 +288,7 @@ var base = template.Must(html.Parse(`
 				Using free <a href="https://fontawesome.com">Font Awesome</a>
 			</li>
 		</ul>
-		<p>Designed and built by Eyal Posener / 2019</p>
+		<p>Designed and built by Eyal Posener / 2019{{ with version }} <small class="text-muted">({{ . }})</small>{{ end }}</p>
 		<ul class="list-inline">
 			<li class="list-inline-item"><a href="https://github.com/posener/goreadme">
 				<i class="fa fa-github" aria-hidden="true"></i>
@@ -229,7 +353,8 @@ var Home = template.Must(template.Must(base.Clone()).Parse(`
 		<p>
 			Adding a <code>goreadme.json</code> file to your repository main directory can enable some
 			customization to the generated readme file. The configuration is available
-			according to <a href="https://godoc.org/github.com/posener/goreadme#Config"><code>goreadme.Config</code></a>
+			according to <a href="https://godoc.org/github.com/posener/goreadme#Config"><code>goreadme.Config</code></a>.
+			Use the <a href="/config">configuration generator</a> to create this file.
 		</p>
 	</div>
 	<div class="col-lg-5 col-12">
@@ -255,18 +380,30 @@ var Home = template.Must(template.Must(base.Clone()).Parse(`
 					Stats
 				</h4>
 				<h5 class="card-subtitle p-2 text-muted">
-					<i class="fa fa-x2 fa-balance-scale"></i>
+					<i class="fa fa-x2 fa-balance-scale" aria-hidden="true"></i>
 					Total: {{.Stats.TotalProjects}}
 				</h5>
 				<h5 class="card-subtitle p-2 text-muted">
-					<i class="fa fa-x2 fa-trophy"></i>
+					<i class="fa fa-x2 fa-cogs" aria-hidden="true"></i>
+					Jobs in the last 24 hours: {{.Stats.JobsLastDay}}
+				</h5>
+				{{ with .Stats.SparklinePoints }}
+				<div class="p-2">
+					<svg width="100%" height="{{$.Stats.SparklineHeight}}" viewBox="0 0 {{$.Stats.SparklineWidth}} {{$.Stats.SparklineHeight}}" preserveAspectRatio="none" role="img" aria-label="Jobs per day in the last 30 days">
+						<polyline fill="none" stroke="#17a2b8" stroke-width="2" points="{{.}}"/>
+					</svg>
+					<small class="text-muted">Jobs per day in the last 30 days</small>
+				</div>
+				{{ end }}
+				<h5 class="card-subtitle p-2 text-muted">
+					<i class="fa fa-x2 fa-trophy" aria-hidden="true"></i>
 					Top Open Source Goreadmes
 				</h5>
 				<ul class="list-group">
 				{{ range .Stats.TopProjects }}
 					<a href="https://github.com/{{.Owner}}/{{.Repo}}" class="list-group-item d-flex justify-content-between align-items-center">
 						{{.Owner}}/{{.Repo}}
-						<span class="badge badge-info">{{.Stars}} <i class="fa fa-star"></i></span>
+						<span class="badge badge-info">{{.Stars}} <i class="fa fa-star" aria-hidden="true"></i><span class="sr-only">stars</span></span>
 					</a>
 				{{ end }}
 				</ul>
@@ -283,25 +420,28 @@ var headline = template.Must(base.Parse(`
 <div class="row row border-top rounded-sm bg-light">
 
 <div class="col-8 p-2 pl-3">
-	<a href="/jobs?owner={{.Owner}}&repo={{.Repo}}"><i class="fa fa-filter" aria-hidden="true"></i></a>
-	<a href="https://github.com/{{.Owner}}/{{.Repo}}"><i class="fa fa-github" aria-hidden="true"></i></a>
+	<a href="/jobs?owner={{.Owner}}&repo={{.Repo}}" aria-label="Jobs of {{.Owner}}/{{.Repo}}" title="Jobs"><i class="fa fa-filter" aria-hidden="true"></i></a>
+	<a href="https://github.com/{{.Owner}}/{{.Repo}}" aria-label="{{.Owner}}/{{.Repo}} on Github" title="Github"><i class="fa fa-github" aria-hidden="true"></i></a>
+	<a href="/projects/{{.Owner}}/{{.Repo}}/settings" aria-label="Settings of {{.Owner}}/{{.Repo}}" title="Settings"><i class="fa fa-cog" aria-hidden="true"></i></a>
 	{{.Owner}}/{{.Repo}}
+	{{if .ForkOf}}<small class="text-muted" title="Fork of {{.ForkOf}}"><i class="fa fa-code-fork" aria-hidden="true"></i> Fork</small>{{end}}
 </div>
 
 <div class="col-3 p-2 pl-2">
-	<div class="text-{{ color .Status }}">{{.Status}}</div>
+	<div class="live-status text-{{ color .Status }}" aria-live="polite">{{.Status}}</div>
+	<div class="live-pr">
 	{{if .PR}}
-	<div>
 		<small><a href="https://github.com/{{.Owner}}/{{.Repo}}/pull/{{.PR}}">PR#{{.PR}}</a></small>
-	</div>
+		{{if .StalePR}}<span class="badge badge-warning" title="The PR is open for a long time, consider merging it">Stale</span>{{end}}
 	{{end}}
+	</div>
 </div>
 
 <div class="col-1 p-2">
 	<form action="/add" method="post" class="float-right">
 		<input type="hidden" name="repo" value="{{.Repo}}">
 		<input type="hidden" name="owner" value="{{.Owner}}">
-		<button type="submit" class="btn btn-outline-primary btn-sm">
+		<button type="submit" class="btn btn-outline-primary btn-sm" aria-label="Run goreadme on {{.Owner}}/{{.Repo}}" title="Run">
 			<i class="fa fa-play-circle" aria-hidden="true"></i>
 		</button>
 	</form>
@@ -327,14 +467,14 @@ var message = template.Must(base.Parse(`
 {{ define "message" }}
 
 <i class="fa fa-quote-left fa-1x fa-pull-left fa-border" aria-hidden="true"></i>
-<small>{{.Message}}</small>
+<small class="live-message" aria-live="polite">{{.Message}}</small>
 
 {{ end }}
 `))
 
 var projectRow = template.Must(base.Parse(`
 {{ define "projectRow" }}
-<div class="row">
+<div class="row live-row" data-owner="{{.Owner}}" data-repo="{{.Repo}}">
 <div class="col-12">
 
 {{ template "headline" . }}
@@ -352,6 +492,10 @@ var projectRow = template.Must(base.Parse(`
 			<i class="fa fa-hashtag" aria-hidden="true"></i>
 			{{.LastJob}}
 		</small></div>	
+		<div><small title="Readme quality: synopsis, examples, badges and license section">
+			<i class="fa fa-book" aria-hidden="true"></i>
+			Quality {{.Quality}}%
+		</small></div>
 	</div>
 
 	<div class="col-md-8 col-12 p-2 pl-3 pr-3 p-lg-2">
@@ -367,7 +511,7 @@ var projectRow = template.Must(base.Parse(`
 
 var jobRow = template.Must(base.Parse(`
 {{ define "jobRow" }}
-<div class="row">
+<div class="row live-row" data-owner="{{.Owner}}" data-repo="{{.Repo}}" data-num="{{.Num}}">
 <div class="col-12">
 
 {{ template "headline" . }}
@@ -381,6 +525,12 @@ var jobRow = template.Must(base.Parse(`
 			<i aria-hidden="true" class="fa fa-key"></i>{{ .Trigger }}
 		</div>
 		{{ end }}
+		{{ if .TriggerMessage }}
+		<div class="text-truncate" title="{{ .TriggerMessage }}"><small>
+			<i aria-hidden="true" class="fa fa-commenting-o"></i>
+			{{ firstLine .TriggerMessage }}{{ if .TriggerAuthor }} ({{ .TriggerAuthor }}){{ end }}
+		</small></div>
+		{{ end }}
 	</div>
 
 	<div class="col-md-3 col-6 p-2">
@@ -399,7 +549,28 @@ var jobRow = template.Must(base.Parse(`
 	</div>
 
 	<div class="col-md-3 col-12 p-2 pl-3 pr-3 p-lg-2">
+		{{ if .QueuePosition }}
+		<div class="live-queue">
+			<i class="fa fa-hourglass-half" aria-hidden="true"></i>
+			Queued (position {{.QueuePosition}}), starts {{formatDate .EstimatedStart}}
+		</div>
+		{{ end }}
 		{{ template "message" . }}
+		{{ if .Note }}
+		<div class="alert alert-info small mt-1 mb-0 p-2" role="note">
+			<i class="fa fa-sticky-note-o" aria-hidden="true"></i>
+			{{.Note}}{{ if .NoteAuthor }} <span class="text-muted">({{.NoteAuthor}})</span>{{ end }}
+		</div>
+		{{ end }}
+		{{ if .Warnings }}
+		<details class="mt-1">
+			<summary><small class="text-warning">
+				<i class="fa fa-exclamation-triangle" aria-hidden="true"></i>
+				Readme warnings
+			</small></summary>
+			<pre class="small mb-0">{{.Warnings}}</pre>
+		</details>
+		{{ end }}
 	</div>
 
 </div>
@@ -444,7 +615,7 @@ var AddRepo = template.Must(template.Must(base.Clone()).Parse(`
 		<form action="/add" method="post">
 			<input type="hidden" name="repo" value="{{.GetName}}">
 			<input type="hidden" name="owner" value="{{.GetOwner.GetLogin}}">
-			<button type="submit" class="btn btn-outline-primary btn-sm">
+			<button type="submit" class="btn btn-outline-primary btn-sm" aria-label="Run goreadme on {{.GetFullName}}" title="Run">
 				<i class="fa fa-play-circle" aria-hidden="true"></i>
 			</button>
 		</form>
@@ -457,6 +628,50 @@ var AddRepo = template.Must(template.Must(base.Clone()).Parse(`
 {{else}}
 No installed repositories. Please <a href="/add">add a repository</a>.
 {{end}}
+<p>Or <a href="/import">import a project</a> by its pkg.go.dev URL.</p>
+{{end}}
+`))
+
+var Import = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Import Project{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Import Project</h4>
+<form method="post">
+	<div class="form-group">
+		<label for="url">Package URL or import path</label>
+		<input type="text" class="form-control" id="url" name="url" value="{{with .Import}}{{.Input}}{{end}}" placeholder="https://pkg.go.dev/github.com/posener/goreadme" required>
+		<small class="form-text text-muted">A godoc.org or pkg.go.dev URL, or an import path, such as <code>github.com/posener/goreadme</code>.</small>
+	</div>
+	<button type="submit" class="btn btn-outline-primary">Import</button>
+</form>
+
+{{ with .Import }}
+<div class="alert alert-info mt-4" role="status">
+	<p>
+		<code>{{.Path}}</code> is in the <a href="https://github.com/{{.Owner}}/{{.Repo}}">{{.Owner}}/{{.Repo}}</a>
+		repository, which is not installed{{ if ne .Owner $.Account }} in the installation of {{$.Account}}{{ end }}.
+	</p>
+	<ol class="mb-0">
+		{{ if and $.InstallID (eq .Owner $.Account) }}
+		<li>Add {{.Repo}} to the repositories of your <a href="https://github.com/settings/installations/{{$.InstallID}}">goreadme installation</a>.</li>
+		{{ else }}
+		<li><a href="https://github.com/apps/goreadme/installations/new">Install goreadme</a> on {{.Owner}}, with the {{.Repo}} repository.</li>
+		{{ if ne .Owner $.Account }}
+		<li>Act on the installation of {{.Owner}} in the <a href="/settings">settings</a>.</li>
+		{{ end }}
+		{{ end }}
+		<li>Import the project again to run goreadme on it.</li>
+	</ol>
+</div>
+<p>
+	To use goreadme without installing the app, add a <a href="/hooks">repository webhook</a>
+	instead.
+</p>
+{{ end }}
+</div>
+</div>
 {{end}}
 `))
 
@@ -470,6 +685,21 @@ var JobsList = template.Must(template.Must(base.Clone()).Parse(`
 		{{ range .Jobs }}
 
 		{{ template "jobRow" . }}
+		{{ if $.Admin }}
+		<details class="mb-3">
+			<summary><small>Admin</small></summary>
+			<form action="/admin/jobs/{{.Owner}}/{{.Repo}}/{{.Num}}" method="post">
+				<div class="form-group">
+					<label for="note-{{.Owner}}-{{.Repo}}-{{.Num}}" class="sr-only">Note</label>
+					<textarea id="note-{{.Owner}}-{{.Repo}}-{{.Num}}" name="note" class="form-control form-control-sm" rows="2" placeholder="Note, for example: Github outage">{{.Note}}</textarea>
+				</div>
+				<button type="submit" class="btn btn-outline-primary btn-sm">Save note</button>
+				{{ if .Status.Resolvable }}
+				<button type="submit" name="resolve" value="true" class="btn btn-outline-secondary btn-sm">Save and resolve</button>
+				{{ end }}
+			</form>
+		</details>
+		{{ end }}
 
 		{{ end }}
 {{ else }}
@@ -482,14 +712,44 @@ var JobsList = template.Must(template.Must(base.Clone()).Parse(`
 
 var Badge = template.Must(template.New("svg").Funcs(
 	template.FuncMap{
-		"statusColor": func(s string) string {
-			switch s {
-			case "Success":
+		"statusColor": status.Status.BadgeColor,
+	}).Parse(`
+<svg xmlns="http://www.w3.org/2000/svg" width="115" height="20">
+	<linearGradient id="a" x2="0" y2="100%">
+		<stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+		<stop offset="1" stop-opacity=".1"/>
+	</linearGradient>
+	<rect rx="3" width="115" height="20" fill="#555"/>
+	<rect rx="3" x="63" width="53" height="20" fill="{{statusColor .Status}}"/>
+	<rect rx="3" width="115" height="20" fill="url(#a)"/>
+	<g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
+		<text x="32" y="15" fill="#010101" fill-opacity=".3">
+			goreadme
+		</text>
+		<text x="32" y="14">
+			goreadme
+		</text>
+		<text x="87" y="15" fill="#010101" fill-opacity=".3">
+			{{with .Status}}{{.}}{{else}}unknown{{end}}
+		</text>
+		<text x="87" y="14">
+			{{with .Status}}{{.}}{{else}}unknown{{end}}
+		</text>
+	</g>
+</svg>
+`))
+
+// QualityBadge shows the readme quality score of a project.
+var QualityBadge = template.Must(template.New("svg").Funcs(
+	template.FuncMap{
+		"qualityColor": func(score int) string {
+			switch {
+			case score >= 75:
 				return "#2ecc71"
-			case "Failed":
-				return "#d35400"
+			case score >= 50:
+				return "#dfb317"
 			default:
-				return "#2e4053"
+				return "#d35400"
 			}
 		},
 	}).Parse(`
@@ -499,20 +759,20 @@ var Badge = template.Must(template.New("svg").Funcs(
 		<stop offset="1" stop-opacity=".1"/>
 	</linearGradient>
 	<rect rx="3" width="115" height="20" fill="#555"/>
-	<rect rx="3" x="63" width="53" height="20" fill="{{statusColor .Status}}"/>
+	<rect rx="3" x="63" width="53" height="20" fill="{{if .Status}}{{qualityColor .Quality}}{{else}}#2e4053{{end}}"/>
 	<rect rx="3" width="115" height="20" fill="url(#a)"/>
 	<g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
 		<text x="32" y="15" fill="#010101" fill-opacity=".3">
-			goreadme
+			readme
 		</text>
 		<text x="32" y="14">
-			goreadme
+			readme
 		</text>
 		<text x="87" y="15" fill="#010101" fill-opacity=".3">
-			{{.Status}}
+			{{if .Status}}{{.Quality}}%{{else}}unknown{{end}}
 		</text>
 		<text x="87" y="14">
-			{{.Status}}
+			{{if .Status}}{{.Quality}}%{{else}}unknown{{end}}
 		</text>
 	</g>
 </svg>
@@ -0,0 +1,42 @@
+package templates
+
+import "html/template"
+
+var Usage = template.Must(template.Must(base.Clone()).Parse(`
+{{define "title"}}Usage{{end}}
+{{define "content"}}
+<div class="row m-md-2 justify-content-md-center">
+<div class="col-xl-8 col-lg-10 col-12">
+<h4>Usage</h4>
+<p>
+	Daily usage of your installation in the last 30 days. Github API calls are counted
+	for jobs, and are limited by Github per installation.
+</p>
+{{ if .Usage }}
+<table class="table table-sm">
+	<tr>
+		<th>Day</th>
+		<th>Jobs</th>
+		<th>Failed</th>
+		<th>Error Rate</th>
+		<th>Average Duration</th>
+		<th>API Calls</th>
+	</tr>
+	{{ range .Usage }}
+	<tr>
+		<td>{{.Day.Format "2006-01-02"}}</td>
+		<td>{{.Jobs}}</td>
+		<td>{{.Failed}}</td>
+		<td class="text-{{if gt .Failed 0}}danger{{else}}success{{end}}">{{printf "%.0f" .ErrorRate}}%</td>
+		<td>{{formatDuration .AvgDuration}}</td>
+		<td>{{.APICalls}}</td>
+	</tr>
+	{{ end }}
+</table>
+{{ else }}
+	No usage in the last 30 days.
+{{ end }}
+</div>
+</div>
+{{end}}
+`))
@@ -0,0 +1,96 @@
+// Package tokens issues and validates API tokens of users.
+//
+// Only a hash of each token is stored, the token itself is returned once when it is
+// issued.
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// prefix of issued tokens, which makes them recognizable.
+const prefix = "grs_"
+
+// Token is an API token of a user.
+type Token struct {
+	ID         int64 `gorm:"primary_key"`
+	Login      string
+	Name       string
+	Hash       string `json:"-"`
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	// ExpiresAt is the expiration time of the token. Tokens without it don't expire.
+	ExpiresAt *time.Time
+}
+
+// Tokens is a database backed API tokens store.
+type Tokens struct {
+	db *gorm.DB
+}
+
+// New returns an API tokens store.
+func New(db *gorm.DB) *Tokens {
+	return &Tokens{db: db}
+}
+
+// Issue creates a new token for a user and returns it.
+func (t *Tokens) Issue(login, name string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating token")
+	}
+	secret := prefix + hex.EncodeToString(b)
+	err := t.db.Create(&Token{Login: login, Name: name, Hash: hash(secret)}).Error
+	if err != nil {
+		return "", errors.Wrapf(err, "saving token of %s", login)
+	}
+	return secret, nil
+}
+
+// Authenticate returns the token of a secret. It returns a nil token if the secret is not
+// valid or the token expired.
+func (t *Tokens) Authenticate(secret string) (*Token, error) {
+	var token Token
+	query := t.db.Where("hash = ? AND (expires_at IS NULL OR expires_at > ?)", hash(secret), time.Now()).First(&token)
+	switch {
+	case query.RecordNotFound():
+		return nil, nil
+	case query.Error != nil:
+		return nil, errors.Wrap(query.Error, "getting token")
+	}
+	if err := t.db.Model(&token).UpdateColumn("last_used_at", time.Now()).Error; err != nil {
+		return nil, errors.Wrap(err, "updating token usage")
+	}
+	return &token, nil
+}
+
+// Count returns the number of tokens of a user.
+func (t *Tokens) Count(login string) (int, error) {
+	var n int
+	err := t.db.Model(&Token{}).Where("login = ?", login).Count(&n).Error
+	return n, errors.Wrapf(err, "counting tokens of %s", login)
+}
+
+// List returns the tokens of a user.
+func (t *Tokens) List(login string) ([]Token, error) {
+	var tokens []Token
+	err := t.db.Where("login = ?", login).Order("created_at DESC").Find(&tokens).Error
+	return tokens, errors.Wrapf(err, "listing tokens of %s", login)
+}
+
+// Revoke deletes a token of a user.
+func (t *Tokens) Revoke(login string, id int64) error {
+	err := t.db.Where("login = ? AND id = ?", login, id).Delete(&Token{}).Error
+	return errors.Wrapf(err, "revoking token %d of %s", id, login)
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+)
+
+func TestIssueAuthenticate(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	tokens := New(db)
+
+	secret, err := tokens.Issue("posener", "ci")
+	if err != nil {
+		t.Fatalf("Issue: %s", err)
+	}
+	if !strings.HasPrefix(secret, prefix) {
+		t.Errorf("Issued token %q without prefix %q", secret, prefix)
+	}
+
+	token, err := tokens.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %s", err)
+	}
+	if token == nil || token.Login != "posener" || token.Name != "ci" {
+		t.Fatalf("Authenticate() = %+v, want token ci of posener", token)
+	}
+	var stored Token
+	if err := db.First(&stored, token.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("Token usage was not recorded")
+	}
+	if strings.Contains(stored.Hash, secret) {
+		t.Error("Token secret was stored")
+	}
+
+	for _, invalid := range []string{"", prefix, secret + "0", strings.ToUpper(secret)} {
+		token, err := tokens.Authenticate(invalid)
+		if err != nil {
+			t.Fatalf("Authenticate(%q): %s", invalid, err)
+		}
+		if token != nil {
+			t.Errorf("Authenticate(%q) = %+v, want nil", invalid, token)
+		}
+	}
+}
+
+func TestAuthenticateExpired(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	tokens := New(db)
+
+	secret, err := tokens.Issue("posener", "ci")
+	if err != nil {
+		t.Fatalf("Issue: %s", err)
+	}
+	expired := time.Now().Add(-time.Minute)
+	if err := db.Model(&Token{}).Where("login = ?", "posener").UpdateColumn("expires_at", expired).Error; err != nil {
+		t.Fatal(err)
+	}
+	token, err := tokens.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %s", err)
+	}
+	if token != nil {
+		t.Errorf("Authenticate() of expired token = %+v, want nil", token)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	tokens := New(db)
+
+	secret, err := tokens.Issue("posener", "ci")
+	if err != nil {
+		t.Fatalf("Issue: %s", err)
+	}
+	if _, err := tokens.Issue("other", "ci"); err != nil {
+		t.Fatalf("Issue: %s", err)
+	}
+	list, err := tokens.List("posener")
+	if err != nil {
+		t.Fatalf("List: %s", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("List() returned %d tokens, want 1", len(list))
+	}
+
+	// Tokens of other users can't be revoked.
+	if err := tokens.Revoke("other", list[0].ID); err != nil {
+		t.Fatalf("Revoke: %s", err)
+	}
+	if token, _ := tokens.Authenticate(secret); token == nil {
+		t.Fatal("Token was revoked by another user")
+	}
+
+	if err := tokens.Revoke("posener", list[0].ID); err != nil {
+		t.Fatalf("Revoke: %s", err)
+	}
+	if token, _ := tokens.Authenticate(secret); token != nil {
+		t.Error("Revoked token is valid")
+	}
+	if n, err := tokens.Count("other"); err != nil || n != 1 {
+		t.Errorf("Count(other) = %d, %v, want 1", n, err)
+	}
+}
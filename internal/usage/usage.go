@@ -0,0 +1,122 @@
+// Package usage tracks and reports the usage of the service by installations.
+//
+// The number of Github API calls is counted by wrapping the HTTP clients of jobs
+// with a counting transport, and stored per installation per day.
+package usage
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+// Counter is an http.RoundTripper that counts the requests that pass through it.
+type Counter struct {
+	// Transport is the underlying transport.
+	Transport http.RoundTripper
+	calls     int64
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Counter) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&c.calls, 1)
+	return c.Transport.RoundTrip(r)
+}
+
+// Calls returns the number of requests so far.
+func (c *Counter) Calls() int {
+	return int(atomic.LoadInt64(&c.calls))
+}
+
+// RecordCalls adds a number of Github API calls to the current UTC day of an installation.
+func RecordCalls(db *gorm.DB, install int64, calls int) error {
+	if calls == 0 {
+		return nil
+	}
+	err := db.Exec(`
+INSERT INTO api_calls (install, day, calls) VALUES (?, (now() AT TIME ZONE 'UTC')::date, ?)
+ON CONFLICT (install, day) DO UPDATE SET calls = api_calls.calls + EXCLUDED.calls`,
+		install, calls).Error
+	return errors.Wrap(err, "recording api calls")
+}
+
+// Day is the usage of an installation in a single day.
+type Day struct {
+	Day         time.Time
+	Jobs        int
+	Failed      int
+	AvgDuration time.Duration
+	APICalls    int
+}
+
+// ErrorRate returns the percentage of failed jobs.
+func (d Day) ErrorRate() float64 {
+	if d.Jobs == 0 {
+		return 0
+	}
+	return 100 * float64(d.Failed) / float64(d.Jobs)
+}
+
+// Report returns the daily usage of an installation in the last given number of UTC days,
+// newest first. Days without any usage are omitted.
+func Report(db *gorm.DB, install int64, days int) ([]Day, error) {
+	var jobs []struct {
+		Day         time.Time
+		Jobs        int
+		Failed      int
+		AvgDuration float64
+	}
+	err := db.Raw(`
+SELECT date(created_at AT TIME ZONE 'UTC') AS day,
+	COUNT(*) AS jobs,
+	SUM(CASE WHEN status IN (?, ?) THEN 1 ELSE 0 END) AS failed,
+	COALESCE(AVG(duration), 0) AS avg_duration
+FROM jobs
+WHERE install = ? AND created_at > ((now() AT TIME ZONE 'UTC')::date - ?::integer)::timestamp AT TIME ZONE 'UTC'
+GROUP BY day`, status.Failed, status.Aborted, install, days).Scan(&jobs).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "querying jobs usage")
+	}
+
+	var calls []struct {
+		Day   time.Time
+		Calls int
+	}
+	err = db.Raw(`
+SELECT day, calls FROM api_calls
+WHERE install = ? AND day > (now() AT TIME ZONE 'UTC')::date - ?::integer`, install, days).Scan(&calls).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "querying api calls usage")
+	}
+
+	byDay := make(map[string]*Day)
+	get := func(t time.Time) *Day {
+		key := t.Format("2006-01-02")
+		if byDay[key] == nil {
+			byDay[key] = &Day{Day: t}
+		}
+		return byDay[key]
+	}
+	for _, j := range jobs {
+		d := get(j.Day)
+		d.Jobs = j.Jobs
+		d.Failed = j.Failed
+		d.AvgDuration = time.Duration(j.AvgDuration)
+	}
+	for _, c := range calls {
+		get(c.Day).APICalls = c.Calls
+	}
+
+	report := make([]Day, 0, len(byDay))
+	for i := 0; i < days; i++ {
+		key := time.Now().UTC().AddDate(0, 0, -i).Format("2006-01-02")
+		if d := byDay[key]; d != nil {
+			report = append(report, *d)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,58 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+func TestReportUTCDays(t *testing.T) {
+	db := githubtest.DB(t)
+	defer db.Close()
+	// The session time zone applies to all the queries of a single connection.
+	db.DB().SetMaxOpenConns(1)
+	if err := db.Exec("SET TIME ZONE 'America/Los_Angeles'").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Yesterday early in UTC is still the day before yesterday in Los Angeles.
+	now := time.Now().UTC()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day(), 1, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	for i, s := range []status.Status{status.Success, status.Failed} {
+		err := db.Exec("INSERT INTO jobs (install, owner, repo, num, status, duration, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			1, "posener", "hello", i+1, s, int64(time.Second), yesterday).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordCalls(db, 1, 5); err != nil {
+		t.Fatalf("RecordCalls: %s", err)
+	}
+	if err := RecordCalls(db, 1, 2); err != nil {
+		t.Fatalf("RecordCalls: %s", err)
+	}
+
+	report, err := Report(db, 1, 7)
+	if err != nil {
+		t.Fatalf("Report: %s", err)
+	}
+	if len(report) != 2 {
+		t.Fatalf("Report() = %+v, want today and yesterday", report)
+	}
+	today := report[0]
+	if got, want := today.Day.Format("2006-01-02"), now.Format("2006-01-02"); got != want {
+		t.Errorf("First day = %s, want %s", got, want)
+	}
+	if today.APICalls != 7 || today.Jobs != 0 {
+		t.Errorf("Today = %+v, want 7 API calls and no jobs", today)
+	}
+	jobs := report[1]
+	if got, want := jobs.Day.Format("2006-01-02"), yesterday.Format("2006-01-02"); got != want {
+		t.Errorf("Jobs day = %s, want %s", got, want)
+	}
+	if jobs.Jobs != 2 || jobs.Failed != 1 || jobs.ErrorRate() != 50 || jobs.AvgDuration != time.Second {
+		t.Errorf("Jobs day = %+v, want 2 jobs with 1 failure of 1s", jobs)
+	}
+}
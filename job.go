@@ -6,13 +6,31 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/jinzhu/gorm"
+	"github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
 	"github.com/posener/goreadme"
+	"github.com/posener/goreadme-server/internal/artifacts"
+	"github.com/posener/goreadme-server/internal/breaker"
+	"github.com/posener/goreadme-server/internal/diff"
+	"github.com/posener/goreadme-server/internal/events"
+	"github.com/posener/goreadme-server/internal/flags"
+	"github.com/posener/goreadme-server/internal/linkcheck"
+	"github.com/posener/goreadme-server/internal/metrics"
+	"github.com/posener/goreadme-server/internal/notify"
+	"github.com/posener/goreadme-server/internal/quality"
+	"github.com/posener/goreadme-server/internal/regions"
+	"github.com/posener/goreadme-server/internal/report"
+	"github.com/posener/goreadme-server/internal/sections"
+	"github.com/posener/goreadme-server/internal/settings"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/posener/goreadme-server/internal/usage"
 	"github.com/sirupsen/logrus"
 	"github.com/src-d/go-git/plumbing"
 )
@@ -26,9 +44,57 @@ const (
 	goreadmeAuthor = "goreadme"
 	goreadmeEmail  = "posener@gmail.com"
 	goreadmeBranch = "goreadme"
-	goreadmeRef    = "refs/heads/" + goreadmeBranch
+	// configBranch is the branch of pull requests that add a goreadme.json file.
+	configBranch = "goreadme-config"
 )
 
+// Pull request strategies, set by the pr_strategy option.
+const (
+	// prStrategyReuse keeps a single goreadme pull request, and updates it on changes.
+	prStrategyReuse = "reuse"
+	// prStrategyNew opens a new pull request for each head commit, from a branch that is
+	// named after the commit.
+	prStrategyNew = "new"
+	// prStrategyAmend resets the goreadme branch to the head commit on every change, and
+	// comments on the pull request with the change.
+	prStrategyAmend = "amend"
+)
+
+// config is the goreadme.json configuration. It extends the goreadme library
+// configuration with options that are applied by the server.
+type config struct {
+	goreadme.Config
+	// HeaderFile is a path of a file in the repository, which its content is
+	// prepended to the generated readme.
+	HeaderFile string `json:"header_file"`
+	// FooterFile is a path of a file in the repository, which its content is
+	// appended to the generated readme.
+	FooterFile string `json:"footer_file"`
+	// NormalizeReadme renames an existing readme file with a different name,
+	// such as readme.md or README.markdown, to README.md.
+	NormalizeReadme bool `json:"normalize_readme"`
+	// MinChange is the minimal number of changed characters for updating an existing
+	// readme. Smaller changes are ignored.
+	MinChange int `json:"min_change"`
+	// IgnoreWhitespace ignores whitespace changes when comparing the generated readme
+	// to the existing readme.
+	IgnoreWhitespace bool `json:"ignore_whitespace"`
+	// Sections are names of readme sections, in the order that they should appear in the
+	// readme, for example: badges, description, install, usage, examples, subpackages.
+	Sections []string `json:"sections"`
+	// LintCheckRun adds the lint findings of the generated readme as annotations of a check
+	// run on the goreadme branch.
+	LintCheckRun bool `json:"lint_check_run"`
+	// PRStrategy is how pull requests are opened: "reuse" (default), "new" or "amend".
+	PRStrategy string `json:"pr_strategy"`
+	// PackageDir is the directory of the package that the root readme is generated from. It
+	// is detected if it is empty, see goDir.
+	PackageDir string `json:"package_dir"`
+	// ClosesIssue is the number of an issue that goreadme pull requests link to, and that is
+	// closed when they are merged.
+	ClosesIssue int `json:"closes_issue"`
+}
+
 type Project struct {
 	// Install is installation ID for authentication purposes.
 	Install       int64
@@ -38,12 +104,24 @@ type Project struct {
 	HeadSHA       string
 	PR            int
 	Message       string
-	Status        string
+	Status        status.Status
 	DefaultBranch string
 	Private       bool
 	Stars         int
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// ForkOf is the full name of the parent repository, if the repository is a fork.
+	ForkOf string
+	// Quality is the quality score of the generated readme, between 0 and 100.
+	Quality int
+	// PRCreatedAt is the creation time of the open goreadme pull request, and PRRemindedAt
+	// is the last time that a reminder about it was posted.
+	PRCreatedAt  *time.Time
+	PRRemindedAt *time.Time
+	// SourceSHA and SourceTree are the head commit and its tree of the last successful
+	// readme generation.
+	SourceSHA  string
+	SourceTree string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 type Job struct {
@@ -52,34 +130,114 @@ type Job struct {
 	Duration time.Duration
 	Debug    string
 	Trigger  string
+	// TriggerMessage and TriggerAuthor are the message and author of the commit that
+	// triggered the job.
+	TriggerMessage string
+	TriggerAuthor  string
+	// Warnings are the lint findings of the generated readme, one per line.
+	Warnings string
+	// Note is a note of an admin about the job, such as an incident that affected it, and
+	// NoteAuthor is the login of the admin.
+	Note       string
+	NoteAuthor string
+
+	// QueuePosition is the position of a queued job in the queue.
+	QueuePosition int `gorm:"-"`
+	// EstimatedStart is the estimated start time of a queued job.
+	EstimatedStart time.Time `gorm:"-"`
 
 	db       *gorm.DB
 	github   *github.Client
 	goreadme *goreadme.GoReadme
+	events   *events.Hub
+	flags    *flags.Flags
+	settings *settings.Settings
+	notify   *notify.Registry
+	report   report.Reporter
+	apiCalls *usage.Counter
 	log      logrus.FieldLogger
 	start    time.Time
+	// missingPermissions are required installation permissions that were not granted.
+	missingPermissions []string
+	// scored is true if the readme quality was computed by the job.
+	scored bool
+	// artifacts stores generated readmes.
+	artifacts *artifacts.Store
+	// orgConfigs caches the account configurations of installations.
+	orgConfigs *cache.Cache
+	// push is true if the job was triggered by a push, and can be skipped if no doc
+	// relevant files were changed.
+	push bool
+	// breaker fails Github API requests fast when Github is unavailable, and retries counts
+	// the times that the job was queued again because of it.
+	breaker *breaker.Breaker
+	retries int
+	queue   *queue
+	// links checks the links of generated readmes, and deadLinks gets the dead links of the
+	// readme of the job.
+	links     *linkcheck.Checker
+	deadLinks <-chan []linkcheck.Dead
+	// prStrategy is the pull request strategy of the job, and branch is the branch of the
+	// pull request, if it is not the goreadme branch.
+	prStrategy string
+	branch     string
+	// closesIssue is the issue that the pull request links to.
+	closesIssue int
+	// configHash is the hash of the repository configuration, and prChanges are the files
+	// that were changed in the pull request, for the pull request body.
+	configHash string
+	prChanges  []prChange
+	// paths are the paths of the head commit, once they were fetched.
+	paths map[string]bool
 }
 
-// Run runs the pull request flow
-func (j *Job) Run() (done <-chan struct{}, jobNum int) {
-	err := j.init()
-	if err != nil {
-		j.log.Errorf("Failed creating job entry in database: %s", err)
-		return nil, 0
+// Run enqueues the pull request flow.
+func (j *Job) Run(q *queue) (done <-chan struct{}, jobNum int, err error) {
+	if err := j.init(); err != nil {
+		return nil, 0, errors.Wrap(err, "failed creating job entry in database")
+	}
+	// The lease allows requeuing the job if the server is stopped before it finishes.
+	if err := j.acquireLease(); err != nil {
+		j.log.Errorf("Failed acquiring job lease: %s", err)
 	}
 
 	ch := make(chan struct{})
 	done = ch
 	jobNum = j.Num
 
-	j.log.Infof("Starting PR process")
+	j.log.Infof("Queuing PR process")
 
-	go j.runInBackground(ch)
-	return done, jobNum
+	q.push(j, ch)
+	return done, jobNum, nil
 }
 
 func (j *Job) runInBackground(done chan<- struct{}) {
 	defer close(done)
+	// A panicking job fails instead of crashing the worker, which would never release the
+	// job from the queue.
+	defer func() {
+		if r := recover(); r != nil {
+			j.log.Errorf("Panic: %v\n%s", r, debug.Stack())
+			j.done(errors.Errorf("panic: %v", r), "Internal error")
+		}
+	}()
+
+	j.log.Infof("Starting PR process")
+	j.start = time.Now()
+	if err := j.setStatus(status.Started); err != nil {
+		j.log.Errorf("Not starting: %s", err)
+		return
+	}
+	if err := j.db.Save(j).Error; err != nil {
+		j.log.Errorf("Failed saving started job: %s", err)
+	}
+	j.publish()
+
+	// Don't wait for the timeout of requests to Github API endpoints that are known to fail.
+	if open := j.breaker.Open(); len(open) > 0 {
+		j.done(&breaker.OpenError{Category: open[0]}, "Github unavailable")
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -90,30 +248,119 @@ func (j *Job) runInBackground(done chan<- struct{}) {
 		j.done(err, "Failed getting config")
 		return
 	}
+	j.configHash, err = artifacts.Hash(cfg)
+	if err != nil {
+		j.done(err, "Failed hashing config")
+		return
+	}
+
+	// Skip pushes that did not change the docs since the last generation.
+	if j.push {
+		last, err := j.lastProject()
+		if err != nil {
+			j.done(err, "Failed getting project")
+			return
+		}
+		unchanged, err := j.docsUnchanged(ctx, cfg, last)
+		switch {
+		case err != nil:
+			// Fallback to a full generation.
+			j.log.Warnf("Failed checking for doc changes: %s", err)
+		case unchanged:
+			j.SourceSHA = j.HeadSHA
+			j.PR, j.PRCreatedAt = last.PR, last.PRCreatedAt
+			j.done(nil, "No doc changes")
+			return
+		}
+	}
 
-	// Create new readme for repository.
-	newContent := bytes.NewBuffer(nil)
-	err = j.goreadme.WithConfig(cfg).Create(ctx, j.githubURL(), newContent)
+	// Readmes of several packages are defined by the files of the targets directory.
+	targets, err := j.getTargets(ctx, cfg)
 	if err != nil {
-		j.done(err, "Failed running goreadme: %s", err)
+		j.done(err, "Failed getting targets")
+		return
+	}
+	if len(targets) > 0 {
+		j.runTargets(ctx, cfg, targets)
+		return
+	}
+
+	pkg, detected := j.goDir(ctx, cfg)
+	content, err := j.generate(ctx, cfg, pkg)
+	switch {
+	case err == errNoContent:
+		j.done(err, "No content generated: add a package comment (// Package %s ...) to the main package Go doc", j.Repo)
+		return
+	case err != nil:
+		j.done(err, "Failed generating readme: %s", err)
 		return
 	}
-	newContent.WriteString(credits)
-	newSHA := computeSHA(newContent.Bytes())
 
 	// Check for changes from current readme
-	defaultBranchSHA, readmePath, err := j.remoteReadme(ctx, j.DefaultBranch)
+	readmePath, defaultBranchSHA, err := j.remoteReadme(ctx, j.DefaultBranch)
 	if err != nil {
 		j.done(err, "Failed getting github README content")
 		return
 	}
+	targetPath := readmePath
+	if cfg.NormalizeReadme {
+		targetPath = defaultReadmePath
+	}
+
+	// The current readme is only fetched if it differs from the generated one.
+	current := content
+	if defaultBranchSHA != "" && defaultBranchSHA != computeSHA([]byte(content)) {
+		current, _, err = j.getFile(ctx, readmePath)
+		if err != nil {
+			j.done(err, "Failed getting github README content")
+			return
+		}
+	}
+
+	// Merge the generated readme into the generated region of the current readme. The
+	// readme of a detected Go module in a subdirectory is only merged to a region, so the
+	// rest of the readme is kept.
+	if merged, ok := regions.Merge(current, content); ok {
+		content = merged
+	} else if detected {
+		if defaultBranchSHA != "" {
+			j.done(errors.New("readme has no generated region"),
+				"The Go module is in %s: add the %s and %s markers to %s, where its readme should be", pkg, regions.Begin, regions.End, readmePath)
+			return
+		}
+		content = regions.Wrap(content)
+	}
+
+	newContent := bytes.NewBufferString(content)
+	newSHA := computeSHA(newContent.Bytes())
+	j.Quality, _ = quality.Score(newContent.String())
+	j.scored = true
+	findings := j.lint(ctx, newContent.String())
+	j.checkLinks(newContent.String())
 
 	// Check if there are any changes from HEAD.
-	if defaultBranchSHA == newSHA {
+	if defaultBranchSHA == newSHA && targetPath == readmePath {
 		j.done(nil, "Readme in branch %s is up to date", j.DefaultBranch)
 		return
 	}
 
+	// Ignore changes that are too small.
+	if defaultBranchSHA != "" && targetPath == readmePath && (cfg.MinChange > 0 || cfg.IgnoreWhitespace) {
+		changed := diff.ChangedChars(current, newContent.String(), cfg.IgnoreWhitespace)
+		if changed == 0 || changed < cfg.MinChange {
+			j.done(nil, "Readme changes are below the threshold (%d changed characters)", changed)
+			return
+		}
+	}
+
+	if !j.checkPermissions() {
+		return
+	}
+	if err := j.setPROptions(cfg); err != nil {
+		j.done(err, "Invalid configuration")
+		return
+	}
+
 	// Reset goreadme branch - delete it if exists and then create it.
 	err = j.createBranch(ctx)
 	if err != nil {
@@ -121,7 +368,7 @@ func (j *Job) runInBackground(done chan<- struct{}) {
 		return
 	}
 
-	sha, _, err := j.remoteReadme(ctx, goreadmeBranch)
+	sha, err := j.fileSHA(ctx, j.headBranch(), targetPath)
 	if err != nil {
 		j.done(err, "Failed get remote readme SHA")
 		return
@@ -129,16 +376,138 @@ func (j *Job) runInBackground(done chan<- struct{}) {
 
 	// Check if the goreadme readme file is the same as the new one.
 	if sha == newSHA {
-		j.log.Infof("Readme in branch %s is up to date, making sure PR is open", goreadmeBranch)
+		j.log.Infof("Readme in branch %s is up to date, making sure PR is open", j.headBranch())
 	}
 
 	// Commit changes to readme file.
-	err = j.commit(ctx, readmePath, newContent.Bytes(), sha)
+	commitSHA, err := j.commit(ctx, targetPath, newContent.Bytes(), sha, "Update readme according to go doc")
 	if err != nil {
 		j.done(err, "Failed pushing readme content")
 		return
 	}
+	j.addPRChange(targetPath, current, newContent.String())
+	if cfg.LintCheckRun {
+		if err := j.annotate(ctx, commitSHA, targetPath, findings); err != nil {
+			j.log.Warnf("Failed annotating lint findings: %s", err)
+		}
+	}
+
+	// Remove the old readme file if it was renamed.
+	if targetPath != readmePath {
+		err = j.removeFile(ctx, readmePath)
+		if err != nil {
+			j.done(err, "Failed removing old readme file %s", readmePath)
+			return
+		}
+	}
+
+	j.openPR(ctx)
+}
+
+// generate returns the readme of a package of the repository, or of the root package if
+// pkg is empty. The readme that was generated for the same head commit and configuration
+// is reused.
+func (j *Job) generate(ctx context.Context, cfg config, pkg string) (string, error) {
+	header, err := j.snippet(ctx, cfg.HeaderFile)
+	if err != nil {
+		return "", errors.Wrap(err, "failed getting header file")
+	}
+	footer, err := j.snippet(ctx, cfg.FooterFile)
+	if err != nil {
+		return "", errors.Wrap(err, "failed getting footer file")
+	}
+
+	var key interface{} = cfg.Config
+	name := j.githubURL()
+	if pkg != "" {
+		// Readmes of different packages are cached separately.
+		key = []interface{}{cfg.Config, pkg}
+		name += "/" + pkg
+	}
+	configHash, err := artifacts.Hash(key)
+	if err != nil {
+		return "", errors.Wrap(err, "failed hashing config")
+	}
+	generated := bytes.NewBuffer(nil)
+	cached, found, err := j.artifacts.Get(j.Owner, j.Repo, j.HeadSHA, configHash)
+	if err != nil {
+		j.log.Warnf("Failed getting cached readme: %s", err)
+	}
+	if found {
+		j.log.Infof("Using cached readme of %s", name)
+		generated.WriteString(cached)
+	} else {
+		start := time.Now()
+		err = j.goreadme.WithConfig(cfg.Config).Create(ctx, name, generated)
+		metrics.Generations.Since(start)
+		if err != nil {
+			return "", errors.Wrap(err, "failed running goreadme")
+		}
+	}
+
+	// Don't replace an existing readme with an empty one.
+	if isEmptyReadme(generated.String()) {
+		return "", errNoContent
+	}
+
+	if !found {
+		err = j.artifacts.Put(j.Owner, j.Repo, j.HeadSHA, configHash, generated.String())
+		if err != nil {
+			j.log.Warnf("Failed caching readme: %s", err)
+		}
+	}
+
+	content := bytes.NewBuffer(nil)
+	if header != "" {
+		content.WriteString(header + "\n\n")
+	}
+	content.WriteString(sections.Order(generated.String(), cfg.Sections))
+	if footer != "" {
+		content.WriteString("\n" + footer + "\n")
+	}
+	content.WriteString(credits)
+	return content.String(), nil
+}
+
+// checkPermissions returns whether the installation was granted the permissions that are
+// required for opening a PR. If not, the job is finished.
+func (j *Job) checkPermissions() bool {
+	if len(j.missingPermissions) == 0 {
+		return true
+	}
+	j.Duration = time.Now().Sub(j.start)
+	j.Message = fmt.Sprintf("Readme is outdated, but a PR can't be opened without write permission for: %s",
+		strings.Join(j.missingPermissions, ", "))
+	if err := j.setStatus(status.NoPermissions); err != nil {
+		j.log.Errorf("Not finishing: %s", err)
+		return false
+	}
+	j.log.Warn(j.Message)
+	j.finish()
+	return false
+}
+
+// setPROptions sets the pull request strategy and linked issue of the job from the
+// configuration.
+func (j *Job) setPROptions(cfg config) error {
+	j.prStrategy = cfg.PRStrategy
+	switch j.prStrategy {
+	case "", prStrategyReuse, prStrategyAmend:
+	case prStrategyNew:
+		j.branch = goreadmeBranch + "-" + shortSHA(j.HeadSHA)
+	default:
+		return errors.Errorf("unknown pr_strategy %q", j.prStrategy)
+	}
+	if cfg.ClosesIssue < 0 {
+		return errors.Errorf("invalid closes_issue %d", cfg.ClosesIssue)
+	}
+	j.closesIssue = cfg.ClosesIssue
+	return nil
+}
 
+// openPR opens a pull request from the committed branch, or updates the existing one, and
+// finishes the job.
+func (j *Job) openPR(ctx context.Context) {
 	prNum, createdNewPR, err := j.pullRequest(ctx)
 	if err != nil {
 		j.done(err, "Failed creating PR")
@@ -148,25 +517,175 @@ func (j *Job) runInBackground(done chan<- struct{}) {
 	message := "PR updated"
 	if createdNewPR {
 		message = "Created PR"
+	} else {
+		if err := j.updatePRBody(ctx, prNum); err != nil {
+			j.log.Warnf("Failed updating PR #%d body: %s", prNum, err)
+		}
+		if j.prStrategy == prStrategyAmend {
+			if err := j.changelogComment(ctx, prNum); err != nil {
+				j.log.Warnf("Failed commenting on PR #%d: %s", prNum, err)
+			}
+		}
 	}
 	j.done(nil, message)
+}
 
+// headBranch returns the branch of the pull request.
+func (j *Job) headBranch() string {
+	if j.branch == "" {
+		return goreadmeBranch
+	}
+	return j.branch
+}
+
+// changelogComment comments on an amended pull request with the commit that it was
+// amended for.
+func (j *Job) changelogComment(ctx context.Context, prNum int) error {
+	body := fmt.Sprintf("Amended according to %s", j.HeadSHA)
+	if msg := strings.SplitN(j.TriggerMessage, "\n", 2)[0]; msg != "" {
+		body += fmt.Sprintf(": %s", msg)
+	}
+	_, _, err := j.github.Issues.CreateComment(ctx, j.Owner, j.Repo, prNum, &github.IssueComment{
+		Body: github.String(body),
+	})
+	return errors.Wrap(err, "failed commenting")
 }
 
 // done saves the job and project state once it is done.
 func (j *Job) done(err error, format string, args ...interface{}) {
+	if err != nil && breaker.IsOpen(err) && j.retries < maxRetries {
+		j.retry(err)
+		return
+	}
+	j.addDeadLinks()
+	s := status.Success
+	if err != nil {
+		s = status.Failed
+	}
+	if serr := j.setStatus(s); serr != nil {
+		j.log.Errorf("Not finishing: %s", serr)
+		return
+	}
 	j.Message = fmt.Sprintf(format, args...)
-	j.Status = "Success"
 	j.Duration = time.Now().Sub(j.start)
-	if err != nil {
-		j.Status = "Failed"
+	if err == nil {
+		if j.scored {
+			j.SourceSHA = j.HeadSHA
+		}
+	} else {
 		j.Debug = err.Error()
 		j.log.WithError(err).Error(j.Message)
+		if j.report != nil {
+			j.report.Report(err, map[string]string{
+				"install": strconv.FormatInt(j.Install, 10),
+				"repo":    j.Owner + "/" + j.Repo,
+				"job":     strconv.Itoa(j.Num),
+			})
+		}
 	}
-	if err := j.db.Save(j).Error; err != nil {
-		j.log.Errorf("Failed saving %s job: %s", strings.ToLower(j.Status), err)
+	j.finish()
+}
+
+// skip records a job that was not run, with a given status.
+func (j *Job) skip(s status.Status, format string, args ...interface{}) error {
+	if err := j.init(); err != nil {
+		return errors.Wrap(err, "failed creating job entry in database")
+	}
+	if err := j.setStatus(s); err != nil {
+		return err
 	}
+	j.Message = fmt.Sprintf(format, args...)
+	j.log.Infof("Skipping: %s", j.Message)
+	j.finish()
+	return nil
+}
+
+// setStatus moves the job to a new state. It returns an error and keeps the current state if
+// the job can't move to the new state.
+func (j *Job) setStatus(s status.Status) error {
+	if err := status.Check(j.Status, s); err != nil {
+		return errors.Wrapf(err, "job state")
+	}
+	j.Status = s
+	return nil
+}
+
+// finish saves the final state of the job and notifies about it.
+func (j *Job) finish() {
+	if !j.save() {
+		j.releaseLease()
+		return
+	}
+	metrics.Jobs.Observe(j.Duration.Seconds(), string(j.Status))
 	j.saveProject()
+	j.releaseLease()
+	j.publish()
+	j.sendNotifications()
+	if j.apiCalls == nil {
+		return
+	}
+	if err := usage.RecordCalls(j.db, j.Install, j.apiCalls.Calls()); err != nil {
+		j.log.Errorf("Failed recording API calls: %s", err)
+	}
+}
+
+// save saves the job, and returns false if the job was already finished in the database,
+// for example when it was aborted by the reaper while it was running, in which case the
+// finished job is kept.
+func (j *Job) save() bool {
+	tx := j.db.Begin()
+	var current Job
+	err := tx.Set("gorm:query_option", "FOR UPDATE").Select("status").
+		Where("owner = ? AND repo = ? AND num = ?", j.Owner, j.Repo, j.Num).First(&current).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		j.log.Errorf("Failed getting %s job: %s", strings.ToLower(string(j.Status)), err)
+		tx.Rollback()
+		return true
+	}
+	if current.Status.Final() && current.Status != j.Status {
+		j.log.Warnf("Job was already %s, dropping its %s result", strings.ToLower(string(current.Status)), strings.ToLower(string(j.Status)))
+		tx.Rollback()
+		return false
+	}
+	if err := tx.Save(j).Error; err != nil {
+		j.log.Errorf("Failed saving %s job: %s", strings.ToLower(string(j.Status)), err)
+		tx.Rollback()
+		return true
+	}
+	tx.Commit()
+	return true
+}
+
+// sendNotifications notifies the sinks that are configured in the project settings.
+func (j *Job) sendNotifications() {
+	prefs, err := j.settings.Project(j.Owner, j.Repo)
+	if err != nil {
+		j.log.Errorf("Failed getting notification settings: %s", err)
+		return
+	}
+	j.notify.Send(context.Background(), prefs, notify.Notification{
+		Owner:   j.Owner,
+		Repo:    j.Repo,
+		Num:     j.Num,
+		Status:  j.Status,
+		Message: j.Message,
+		PR:      j.PR,
+		URL:     fmt.Sprintf("%s/jobs?owner=%s&repo=%s", cfg.Domain, j.Owner, j.Repo),
+		Github:  j.github,
+	})
+}
+
+// publish notifies listening dashboards about the job state.
+func (j *Job) publish() {
+	j.events.Publish(events.Event{
+		Install: j.Install,
+		Owner:   j.Owner,
+		Repo:    j.Repo,
+		Num:     j.Num,
+		Status:  j.Status,
+		Message: j.Message,
+		PR:      j.PR,
+	})
 }
 
 // updateProject saves the project data if it is the latest.
@@ -184,6 +703,18 @@ func (j *Job) saveProject() {
 		tx.Rollback()
 		return
 	}
+	if !j.scored {
+		// Keep the quality score of the last generated readme.
+		j.Quality = currentProject.Quality
+	}
+	if j.SourceSHA == "" {
+		// Keep the source of the last successful generation.
+		j.SourceSHA, j.SourceTree = currentProject.SourceSHA, currentProject.SourceTree
+	}
+	if j.PR != 0 && j.PR == currentProject.PR {
+		// Keep the reminders state of the same pull request.
+		j.PRRemindedAt = currentProject.PRRemindedAt
+	}
 	err := tx.Save(&j.Project).Error
 	if err != nil {
 		j.log.Errorf("Failed saving new project: %s", err)
@@ -193,117 +724,392 @@ func (j *Job) saveProject() {
 	tx.Commit()
 }
 
-// remoteReadme returns the SHA of the remote README file and its path.
-func (j *Job) remoteReadme(ctx context.Context, branch string) (remoteSHA, readmePath string, err error) {
-	readme, resp, err := j.github.Repositories.GetReadme(ctx, j.Owner, j.Repo, &github.RepositoryContentGetOptions{Ref: branch})
-	var upstreamContent string
+// remoteReadme returns the path of the markdown readme file in a branch and its SHA.
+// The readme file name is detected case insensitively, and both .md and .markdown
+// extensions are accepted. If no such file exists, the default readme path and an
+// empty SHA are returned.
+func (j *Job) remoteReadme(ctx context.Context, branch string) (readmePath, sha string, err error) {
+	_, files, resp, err := j.github.Repositories.GetContents(ctx, j.Owner, j.Repo, "", &github.RepositoryContentGetOptions{Ref: branch})
 	switch {
-	case resp.StatusCode == http.StatusNotFound:
-		j.log.Infof("No current readme, creating a new readme!")
-		return "", defaultReadmePath, nil
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		j.log.Infof("Empty repository, creating a new readme!")
+		return defaultReadmePath, "", nil
 	case err != nil:
-		return "", "", errors.Wrap(err, "failed reading current readme")
-	default:
-		upstreamContent, err = readme.GetContent()
-		if err != nil {
-			return "", "", errors.Wrap(err, "failed get readme content")
+		return "", "", errors.Wrap(err, "failed listing repository files")
+	}
+
+	for _, f := range files {
+		if f.GetType() != "file" {
+			continue
+		}
+		switch strings.ToLower(f.GetName()) {
+		case "readme.md", "readme.markdown":
+			// Prefer the default readme path if more than one variant exists.
+			if readmePath == "" || f.GetPath() == defaultReadmePath {
+				readmePath, sha = f.GetPath(), f.GetSHA()
+			}
 		}
-		return computeSHA([]byte(upstreamContent)), readme.GetPath(), nil
 	}
+	if readmePath == "" {
+		j.log.Infof("No current readme, creating a new readme!")
+		return defaultReadmePath, "", nil
+	}
+	return readmePath, sha, nil
 }
 
-// createBranch gets existing goreadme branch or creates a new goreadme branch.
+// fileSHA returns the SHA of a file in a branch, or an empty string if it does not exist.
+func (j *Job) fileSHA(ctx context.Context, branch, path string) (string, error) {
+	f, _, resp, err := j.github.Repositories.GetContents(ctx, j.Owner, j.Repo, path, &github.RepositoryContentGetOptions{Ref: branch})
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		return "", nil
+	case err != nil:
+		return "", errors.Wrapf(err, "failed getting %s", path)
+	case f == nil:
+		return "", errors.Errorf("path %s is not a file", path)
+	}
+	return f.GetSHA(), nil
+}
+
+// createBranch gets existing goreadme branch or creates a new goreadme branch. An existing
+// branch that conflicts with the default branch is reset to its head.
 func (j *Job) createBranch(ctx context.Context) error {
-	_, resp, err := j.github.Repositories.GetBranch(ctx, j.Owner, j.Repo, goreadmeBranch)
+	ref := "refs/heads/" + j.headBranch()
+	_, resp, err := j.github.Repositories.GetBranch(ctx, j.Owner, j.Repo, j.headBranch())
 	switch {
-	case resp.StatusCode == http.StatusNotFound:
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
 		// Branch does not exist, create it
 		j.log.Infof("Creating new branch")
 		_, _, err = j.github.Git.CreateRef(ctx, j.Owner, j.Repo, &github.Reference{
-			Ref:    github.String(goreadmeRef),
+			Ref:    github.String(ref),
 			Object: &github.GitObject{SHA: github.String(j.HeadSHA)},
 		})
 		if err != nil {
-			return errors.Wrapf(err, "failed creating %q ref", goreadmeRef)
+			return errors.Wrapf(err, "failed creating %q ref", ref)
 		}
 		return nil
 	case err != nil:
-		return errors.Wrapf(err, "Failed getting %q branch", goreadmeBranch)
+		return errors.Wrapf(err, "Failed getting %q branch", j.headBranch())
 	default:
-		// Branch exist, delete it
 		j.log.Infof("Found existing branch")
+		if j.prStrategy == prStrategyAmend {
+			j.log.Infof("Resetting branch %s to %s", j.headBranch(), shortSHA(j.HeadSHA))
+		} else {
+			conflicts, err := j.branchConflicts(ctx)
+			if err != nil {
+				return err
+			}
+			if !conflicts {
+				return nil
+			}
+			// Branch conflicts with the default branch, reset it to the head of the default
+			// branch so the pull request will be mergeable.
+			j.log.Infof("Branch %s conflicts with %s, resetting it", j.headBranch(), j.DefaultBranch)
+		}
+		_, _, err = j.github.Git.UpdateRef(ctx, j.Owner, j.Repo, &github.Reference{
+			Ref:    github.String(ref),
+			Object: &github.GitObject{SHA: github.String(j.HeadSHA)},
+		}, true)
+		if err != nil {
+			return errors.Wrapf(err, "failed resetting %q ref", ref)
+		}
 		return nil
 	}
 }
 
-// commit upload the file content to the goreadme branch.
-func (j *Job) commit(ctx context.Context, readmePath string, content []byte, sha string) error {
-	date := time.Now()
-	author := &github.CommitAuthor{
-		Name:  github.String(goreadmeAuthor),
-		Email: github.String(goreadmeEmail),
-		Date:  &date,
+// branchConflicts checks if the goreadme branch conflicts with the head of the default
+// branch: both branches changed the same file since they diverged.
+func (j *Job) branchConflicts(ctx context.Context) (bool, error) {
+	cmp, _, err := j.github.Repositories.CompareCommits(ctx, j.Owner, j.Repo, j.HeadSHA, j.headBranch())
+	if err != nil {
+		return false, errors.Wrapf(err, "failed comparing %q branch", j.headBranch())
+	}
+	if cmp.GetStatus() != "diverged" {
+		return false, nil
 	}
-	_, _, err := j.github.Repositories.UpdateFile(ctx, j.Owner, j.Repo, readmePath, &github.RepositoryContentFileOptions{
+	changed := make(map[string]bool, len(cmp.Files))
+	for _, f := range cmp.Files {
+		changed[f.GetFilename()] = true
+	}
+	// Get the files that were changed in the default branch since the goreadme branch
+	// was diverged from it.
+	base, _, err := j.github.Repositories.CompareCommits(ctx, j.Owner, j.Repo, cmp.MergeBaseCommit.GetSHA(), j.HeadSHA)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed comparing %q branch", j.DefaultBranch)
+	}
+	for _, f := range base.Files {
+		if changed[f.GetFilename()] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// commit upload the file content to the goreadme branch.
+func (j *Job) commit(ctx context.Context, path string, content []byte, sha string, message string) (commitSHA string, err error) {
+	author := commitAuthor()
+	resp, _, err := j.github.Repositories.UpdateFile(ctx, j.Owner, j.Repo, path, &github.RepositoryContentFileOptions{
 		Author:    author,
 		Committer: author,
-		Branch:    github.String(goreadmeBranch),
+		Branch:    github.String(j.headBranch()),
 		Content:   content,
-		Message:   github.String("Update readme according to go doc"),
+		Message:   github.String(message),
 		SHA:       github.String(sha),
 	})
-	return err
+	if err != nil {
+		return "", err
+	}
+	return resp.Commit.GetSHA(), nil
 }
 
-// pullRequest return a current open pull request or create a new pull request and returns it.
-func (j *Job) pullRequest(ctx context.Context) (prNum int, created bool, err error) {
+// commitConfig commits a goreadme.json content to the config branch, and returns the
+// number of the pull request that contains it. The config branch is separate from the
+// goreadme branch, so the readme pull request is not changed.
+func (j *Job) commitConfig(ctx context.Context, content []byte) (prNum int, err error) {
+	j.branch = configBranch
+	err = j.createBranch(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed creating branch")
+	}
+	sha, err := j.fileSHA(ctx, j.headBranch(), configPath)
+	if err != nil {
+		return 0, err
+	}
+	_, err = j.commit(ctx, configPath, content, sha, "Add goreadme configuration")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed pushing config content")
+	}
+	return j.configPullRequest(ctx)
+}
+
+// configPullRequest returns the open pull request of the config branch, or creates it.
+func (j *Job) configPullRequest(ctx context.Context) (prNum int, err error) {
 	prs, _, err := j.github.PullRequests.List(ctx, j.Owner, j.Repo, &github.PullRequestListOptions{
-		Base: j.DefaultBranch,
+		State: "open",
+		Head:  j.Owner + ":" + configBranch,
 	})
 	if err != nil {
-		return 0, false, errors.Wrap(err, "Failed listing PRs")
+		return 0, errors.Wrap(err, "Failed listing PRs")
 	}
 	for _, pr := range prs {
-		if pr.Head.GetRef() == goreadmeBranch {
-			return pr.GetNumber(), false, nil
+		if pr.Head.GetRef() == configBranch && pr.Base.GetRef() == j.DefaultBranch {
+			return pr.GetNumber(), nil
 		}
 	}
-
-	// No pr exists, create a new one.
-	j.log.Infof("Creating a new PR")
+	j.log.Infof("Creating a new config PR")
 	pr, _, err := j.github.PullRequests.Create(ctx, j.Owner, j.Repo, &github.NewPullRequest{
-		Title: github.String("readme: Update according to go doc"),
-		Base:  github.String(j.DefaultBranch),
-		Head:  github.String(goreadmeBranch),
+		Title: github.String("goreadme: Add configuration"),
+		Body: github.String(fmt.Sprintf("Adds a `%s` file that was created with the [configuration generator](%s/config). "+
+			"Once it is merged, goreadme generates the readme according to it.", configPath, cfg.Domain)),
+		Base: github.String(j.DefaultBranch),
+		Head: github.String(configBranch),
 	})
 	if err != nil {
-		return 0, false, errors.Wrap(err, "Failed creatring PR")
+		return 0, errors.Wrap(err, "Failed creating config PR")
 	}
-	return pr.GetNumber(), true, nil
+	if err := j.labelPR(ctx, pr.GetNumber()); err != nil {
+		j.log.Warnf("Failed labeling PR #%d: %s", pr.GetNumber(), err)
+	}
+	return pr.GetNumber(), nil
 }
 
-func (j *Job) getConfig(ctx context.Context) (goreadme.Config, error) {
-	var cfg goreadme.Config
-	cfgContent, _, resp, err := j.github.Repositories.GetContents(ctx, j.Owner, j.Repo, configPath, nil)
-	switch {
-	case resp.StatusCode == http.StatusNotFound:
-		return cfg, nil
-	case err != nil:
-		return cfg, errors.Wrap(err, "failed get config file")
+// removeFile removes a file from the goreadme branch, if it exists.
+func (j *Job) removeFile(ctx context.Context, path string) error {
+	sha, err := j.fileSHA(ctx, j.headBranch(), path)
+	if err != nil || sha == "" {
+		return err
+	}
+	author := commitAuthor()
+	_, _, err = j.github.Repositories.DeleteFile(ctx, j.Owner, j.Repo, path, &github.RepositoryContentFileOptions{
+		Author:    author,
+		Committer: author,
+		Branch:    github.String(j.headBranch()),
+		Message:   github.String("Rename " + path + " to " + defaultReadmePath),
+		SHA:       github.String(sha),
+	})
+	return err
+}
+
+func commitAuthor() *github.CommitAuthor {
+	date := time.Now()
+	return &github.CommitAuthor{
+		Name:  github.String(goreadmeAuthor),
+		Email: github.String(goreadmeEmail),
+		Date:  &date,
+	}
+}
+
+// pullRequest return a current open pull request or create a new pull request and returns it.
+// Goreadme pull requests that are open against another base branch, for example when the
+// default branch was renamed, and with the new strategy, the pull requests of previous
+// changes, are closed in favor of the returned pull request.
+func (j *Job) pullRequest(ctx context.Context) (prNum int, created bool, err error) {
+	opt := &github.PullRequestListOptions{
+		State:       "open",
+		Head:        j.Owner + ":" + j.headBranch(),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	if j.prStrategy == prStrategyNew {
+		// Every change has its own branch, so all the open pull requests are listed to find
+		// the pull requests of previous changes.
+		opt.Head = ""
+	}
+	var stale []*github.PullRequest
+	for {
+		prs, resp, err := j.github.PullRequests.List(ctx, j.Owner, j.Repo, opt)
+		if err != nil {
+			return 0, false, errors.Wrap(err, "Failed listing PRs")
+		}
+		for _, pr := range prs {
+			switch {
+			case pr.Head.GetRef() == j.headBranch() && pr.Base.GetRef() == j.DefaultBranch && prNum == 0:
+				prNum = pr.GetNumber()
+				j.PRCreatedAt = pr.CreatedAt
+			case pr.Head.GetRef() == j.headBranch() || j.supersedes(pr):
+				stale = append(stale, pr)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	if prNum == 0 {
+		// No pr exists, create a new one.
+		j.log.Infof("Creating a new PR")
+		body, err := j.prBody()
+		if err != nil {
+			return 0, false, err
+		}
+		pr, _, err := j.github.PullRequests.Create(ctx, j.Owner, j.Repo, &github.NewPullRequest{
+			Title: github.String("readme: Update according to go doc"),
+			Body:  github.String(body),
+			Base:  github.String(j.DefaultBranch),
+			Head:  github.String(j.headBranch()),
+		})
+		if err != nil {
+			return 0, false, errors.Wrap(err, "Failed creatring PR")
+		}
+		prNum, created = pr.GetNumber(), true
+		j.PRCreatedAt = pr.CreatedAt
+		if err := j.labelPR(ctx, prNum); err != nil {
+			// Labels only help to filter the pull requests, the pull request is still valid.
+			j.log.Warnf("Failed labeling PR #%d: %s", prNum, err)
+		}
+	}
+
+	for _, pr := range stale {
+		err := j.closeSuperseded(ctx, pr.GetNumber(), prNum)
+		if err != nil {
+			// The new PR is already open, closing old PRs is best effort.
+			j.log.Errorf("Failed closing superseded PR #%d: %s", pr.GetNumber(), err)
+		}
 	}
-	content, err := cfgContent.GetContent()
+	return prNum, created, nil
+}
+
+// supersedes returns whether the job pull request supersedes a goreadme pull request of a
+// previous change with the new strategy, which is identified by its branch prefix.
+func (j *Job) supersedes(pr *github.PullRequest) bool {
+	return j.prStrategy == prStrategyNew &&
+		pr.Head.GetRepo().GetFullName() == j.Owner+"/"+j.Repo &&
+		pr.Head.GetRef() != j.headBranch() &&
+		pr.Head.GetRef() != configBranch &&
+		strings.HasPrefix(pr.Head.GetRef(), goreadmeBranch+"-")
+}
+
+// closeSuperseded closes an old goreadme pull request with a comment that links to the
+// pull request that replaces it.
+func (j *Job) closeSuperseded(ctx context.Context, oldNum, newNum int) error {
+	j.log.Infof("Closing PR #%d, superseded by #%d", oldNum, newNum)
+	_, _, err := j.github.Issues.CreateComment(ctx, j.Owner, j.Repo, oldNum, &github.IssueComment{
+		Body: github.String(fmt.Sprintf("Superseded by #%d, which is up to date with the %s branch.", newNum, j.DefaultBranch)),
+	})
 	if err != nil {
-		return cfg, errors.Wrap(err, "failed get config content")
+		return errors.Wrap(err, "failed commenting")
 	}
-	err = json.Unmarshal([]byte(content), &cfg)
+	_, _, err = j.github.PullRequests.Edit(ctx, j.Owner, j.Repo, oldNum, &github.PullRequest{
+		State: github.String("closed"),
+	})
+	return errors.Wrap(err, "failed closing")
+}
+
+// getConfig returns the goreadme.json configuration of the repository, merged with the
+// goreadme.json of the account configuration repository, and with the goreadme options of
+// the project settings. Fields of the repository goreadme.json override the account fields.
+func (j *Job) getConfig(ctx context.Context) (config, error) {
+	var cfg config
+	orgContent, found, err := j.getOrgConfig(ctx)
 	if err != nil {
-		return cfg, errors.Wrapf(err, "unmarshaling config content %s", content)
+		return cfg, errors.Wrap(err, "failed get account config file")
 	}
+	if found && j.Repo != orgConfigRepo {
+		err = json.Unmarshal([]byte(orgContent), &cfg)
+		if err != nil {
+			return cfg, errors.Wrapf(err, "unmarshaling account config content %s", orgContent)
+		}
+	}
+	content, found, err := j.getFile(ctx, configPath)
+	if err != nil {
+		return cfg, errors.Wrap(err, "failed get config file")
+	}
+	if found {
+		err = json.Unmarshal([]byte(content), &cfg)
+		if err != nil {
+			return cfg, errors.Wrapf(err, "unmarshaling config content %s", content)
+		}
+	}
+	prefs, err := j.settings.Project(j.Owner, j.Repo)
+	if err != nil {
+		return cfg, err
+	}
+	applyConfigOptions(&cfg.Config, prefs)
 	return cfg, nil
 }
 
+// snippet returns the trimmed content of a header or footer file. It returns an
+// empty string if path is empty.
+func (j *Job) snippet(ctx context.Context, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	content, found, err := j.getFile(ctx, path)
+	switch {
+	case err != nil:
+		return "", err
+	case !found:
+		return "", errors.Errorf("file %s does not exist", path)
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// getFile returns the content of a file in the default branch of the repository,
+// and whether it exists.
+func (j *Job) getFile(ctx context.Context, path string) (content string, found bool, err error) {
+	return j.getRepoFile(ctx, j.Repo, path)
+}
+
+// getRepoFile returns the content of a file in the default branch of a repository of
+// the project owner, and whether it exists.
+func (j *Job) getRepoFile(ctx context.Context, repo, path string) (content string, found bool, err error) {
+	fileContent, _, resp, err := j.github.Repositories.GetContents(ctx, j.Owner, repo, path, nil)
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		return "", false, nil
+	case err != nil:
+		return "", false, errors.Wrapf(err, "failed get file %s", path)
+	case fileContent == nil:
+		return "", false, errors.Errorf("path %s is not a file", path)
+	}
+	content, err = fileContent.GetContent()
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed get content of %s", path)
+	}
+	return content, true, nil
+}
+
 func (j *Job) init() error {
-	j.start = time.Now()
 	tx := j.db.Begin()
 
 	var maxNum struct{ Num int }
@@ -314,7 +1120,8 @@ func (j *Job) init() error {
 	}
 	j.Num = maxNum.Num + 1
 	j.LastJob = j.Num
-	j.Status = "Started"
+	// A new job always starts queued, regardless of the status of the project.
+	j.Status = status.Queued
 	j.log = logrus.WithFields(logrus.Fields{
 		"sha": shortSHA(j.HeadSHA),
 		"job": fmt.Sprintf("%s/%s#%d", j.Owner, j.Repo, j.Num),
@@ -329,7 +1136,12 @@ func (j *Job) init() error {
 		tx.Rollback()
 		return errors.Wrap(err, "saving project")
 	}
-	return tx.Commit().Error
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+	j.publish()
+	return nil
 }
 
 func (j *Job) setNextNum() error {
@@ -347,8 +1159,29 @@ func shortSHA(sha string) string {
 	return sha[:8]
 }
 
+// minContentLength is the minimal length of generated readme content, excluding the
+// title and the badges, for it to be considered non-empty.
+const minContentLength = 20
+
+// isEmptyReadme returns true if a generated readme has no meaningful content. This
+// happens when the package has no doc.
+func isEmptyReadme(content string) bool {
+	length := 0
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "# ") || strings.HasPrefix(line, "[![") {
+			continue
+		}
+		length += len(line)
+	}
+	return length < minContentLength
+}
+
 func computeSHA(b []byte) string {
 	return plumbing.ComputeHash(plumbing.BlobObject, b).String()
 }
 
+// errNoContent is returned when a generated readme has no meaningful content.
+var errNoContent = errors.New("no content generated")
+
 const credits = "\n\n---\n\nCreated by [goreadme](" + githubAppURL + ")\n"
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJobSetStatus(t *testing.T) {
+	j := &Job{}
+	j.Status = status.Queued
+	if err := j.setStatus(status.Started); err != nil {
+		t.Fatalf("Queued -> Started: %s", err)
+	}
+	if err := j.setStatus(status.Success); err != nil {
+		t.Fatalf("Started -> Success: %s", err)
+	}
+	if err := j.setStatus(status.Started); err == nil {
+		t.Fatal("Success -> Started succeeded")
+	}
+	if j.Status != status.Success {
+		t.Errorf("Status after invalid transition = %q, want %q", j.Status, status.Success)
+	}
+}
+
+func TestCreateBranchTransportError(t *testing.T) {
+	gh := githubtest.New()
+	client := gh.Client()
+	// Closing the server makes every request fail without a response.
+	gh.Close()
+
+	j := &Job{github: client, log: logrus.New()}
+	j.Owner, j.Repo = "posener", "hello"
+	if err := j.createBranch(context.Background()); err == nil {
+		t.Fatal("createBranch succeeded without a server")
+	}
+}
+
+// reports records the reported errors.
+type reports struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (r *reports) Report(err error, tags map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+func TestRunInBackgroundPanic(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+
+	ctx := context.Background()
+	j, err := h.newJob(ctx, &Project{Owner: "posener", Repo: "hello", Install: testInstall}, trigger{Name: "test"})
+	if err != nil {
+		t.Fatalf("newJob: %s", err)
+	}
+	var r reports
+	j.report = &r
+	// The job panics when generating the readme.
+	j.goreadme = nil
+	done, _, err := j.Run(h.queue)
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	<-done
+	h.queue.wait()
+
+	if js := jobs(t, h); len(js) != 1 || js[0].Status != status.Failed {
+		t.Fatalf("Got jobs %+v, want a single failed job", js)
+	}
+	if len(r.errs) != 1 {
+		t.Errorf("Reported %v, want the panic", r.errs)
+	}
+}
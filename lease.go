@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// leaseHeartbeat is the interval in which a server instance renews the leases of its
+	// jobs.
+	leaseHeartbeat = 30 * time.Second
+	// leaseTimeout is the duration after the last heartbeat of a lease, in which the job is
+	// considered interrupted, and is requeued.
+	leaseTimeout = 3 * leaseHeartbeat
+)
+
+// instanceID identifies the server instance in job leases.
+var instanceID = newInstanceID()
+
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+// Lease marks a queued or running job as held by a server instance. Leases are removed
+// when jobs finish. A lease that was not renewed means that the server instance that
+// held the job was stopped. Jobs that were queued by a web process have a lease without an
+// instance until a worker claims them.
+type Lease struct {
+	Owner       string `gorm:"primary_key"`
+	Repo        string `gorm:"primary_key"`
+	Num         int    `gorm:"primary_key;auto_increment:false"`
+	Instance    string
+	HeartbeatAt time.Time
+}
+
+// acquireLease stores a lease of the job for the current server instance.
+func (j *Job) acquireLease() error {
+	err := j.db.Save(&Lease{Owner: j.Owner, Repo: j.Repo, Num: j.Num, Instance: instanceID, HeartbeatAt: time.Now()}).Error
+	return errors.Wrap(err, "failed saving lease")
+}
+
+// releaseLease removes the lease of the job.
+func (j *Job) releaseLease() {
+	err := j.db.Where("owner = ? AND repo = ? AND num = ?", j.Owner, j.Repo, j.Num).Delete(&Lease{}).Error
+	if err != nil {
+		j.log.Errorf("Failed releasing lease: %s", err)
+	}
+}
+
+// leaseLoop renews the leases of the jobs of the current server instance, and requeues jobs
+// with expired leases, until the context is done.
+func (h *handler) leaseLoop(ctx context.Context) {
+	t := time.NewTicker(leaseHeartbeat)
+	defer t.Stop()
+	for {
+		err := h.db.Model(&Lease{}).Where("instance = ?", instanceID).UpdateColumn("heartbeat_at", time.Now()).Error
+		if err != nil {
+			logrus.Errorf("Failed renewing leases: %s", err)
+		}
+		h.requeueExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// requeueExpired requeues jobs that their lease has expired.
+func (h *handler) requeueExpired(ctx context.Context) {
+	var leases []Lease
+	err := h.db.Where("instance != '' AND heartbeat_at < ?", time.Now().Add(-leaseTimeout)).Find(&leases).Error
+	if err != nil {
+		logrus.Errorf("Failed getting expired leases: %s", err)
+		return
+	}
+	for _, l := range leases {
+		// Take over the lease, unless another instance already did.
+		query := h.db.Model(&Lease{}).
+			Where("owner = ? AND repo = ? AND num = ? AND instance = ?", l.Owner, l.Repo, l.Num, l.Instance).
+			UpdateColumns(map[string]interface{}{"instance": instanceID, "heartbeat_at": time.Now()})
+		if query.Error != nil {
+			logrus.Errorf("Failed taking over lease of %s/%s#%d: %s", l.Owner, l.Repo, l.Num, query.Error)
+			continue
+		}
+		if query.RowsAffected == 0 {
+			continue
+		}
+		err := h.requeue(ctx, l)
+		if err != nil {
+			logrus.Errorf("Failed requeuing %s/%s#%d: %s", l.Owner, l.Repo, l.Num, err)
+		}
+	}
+}
+
+// requeue runs again a job that was interrupted, with the same job number.
+func (h *handler) requeue(ctx context.Context, l Lease) error {
+	logrus.Infof("Requeuing interrupted job %s/%s#%d held by %s", l.Owner, l.Repo, l.Num, l.Instance)
+	return h.resume(ctx, l, "Interrupted by a server restart, and could not be requeued")
+}
+
+// resume pushes a job that its lease was taken by the current server instance to the queue,
+// with the same job number. If the job can't run, it fails with the given message.
+func (h *handler) resume(ctx context.Context, l Lease, failMessage string) error {
+	var old Job
+	query := h.db.Where("owner = ? AND repo = ? AND num = ?", l.Owner, l.Repo, l.Num).First(&old)
+	if err := query.Error; err != nil && !query.RecordNotFound() {
+		return errors.Wrap(err, "failed getting job")
+	}
+	if query.RecordNotFound() || old.Status.Final() {
+		// The job was finished, only the lease was left.
+		h.db.Delete(&l)
+		return nil
+	}
+
+	j, err := h.newJob(ctx, &old.Project, trigger{Name: old.Trigger, Message: old.TriggerMessage, Author: old.TriggerAuthor})
+	if err != nil {
+		// Don't keep renewing the lease of a job that can't run.
+		old.Status = status.Failed
+		old.Message = failMessage
+		old.Debug = err.Error()
+		h.db.Save(&old)
+		h.db.Delete(&l)
+		return err
+	}
+	j.Num = old.Num
+	j.CreatedAt = old.CreatedAt
+	j.Status = old.Status
+	if err := j.setStatus(status.Queued); err != nil {
+		h.db.Delete(&l)
+		return err
+	}
+	j.log = j.log.WithField("job", fmt.Sprintf("%s/%s#%d", j.Owner, j.Repo, j.Num))
+	if err := j.db.Save(j).Error; err != nil {
+		return errors.Wrap(err, "failed saving job")
+	}
+	j.publish()
+	h.queue.push(j, make(chan struct{}))
+	return nil
+}
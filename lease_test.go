@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+// leases returns the leases of the test repository.
+func leases(t *testing.T, h *handler) []Lease {
+	t.Helper()
+	var ls []Lease
+	if err := h.db.Where("owner = ? AND repo = ?", "posener", "hello").Order("num").Find(&ls).Error; err != nil {
+		t.Fatalf("Get leases: %s", err)
+	}
+	return ls
+}
+
+func TestRequeueExpired(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+
+	expired := time.Now().Add(-2 * leaseTimeout)
+	interrupted := &Job{Num: 1, Trigger: "Push"}
+	interrupted.Owner, interrupted.Repo, interrupted.Install = "posener", "hello", testInstall
+	interrupted.Status = status.Started
+	// Held by a live instance.
+	live := &Job{Num: 2, Trigger: "Push"}
+	live.Owner, live.Repo, live.Install = "posener", "hello", testInstall
+	live.Status = status.Queued
+	// Finished, but its lease was not removed.
+	finished := &Job{Num: 3, Trigger: "Push"}
+	finished.Owner, finished.Repo, finished.Install = "posener", "hello", testInstall
+	finished.Status = status.Success
+	githubtest.Fixtures(t, h.db, interrupted, live, finished,
+		&Lease{Owner: "posener", Repo: "hello", Num: 1, Instance: "stopped", HeartbeatAt: expired},
+		&Lease{Owner: "posener", Repo: "hello", Num: 2, Instance: "live", HeartbeatAt: time.Now()},
+		&Lease{Owner: "posener", Repo: "hello", Num: 3, Instance: "stopped", HeartbeatAt: expired},
+	)
+
+	h.requeueExpired(context.Background())
+	h.queue.wait()
+
+	js := jobs(t, h)
+	want := []status.Status{status.Success, status.Queued, status.Success}
+	if len(js) != len(want) {
+		t.Fatalf("Got %d jobs, want %d", len(js), len(want))
+	}
+	for i, j := range js {
+		if j.Status != want[i] {
+			t.Errorf("Job #%d status = %s, want %s", j.Num, j.Status, want[i])
+		}
+	}
+	// The interrupted job ran again with the same number.
+	if js[0].Trigger != "Push" || js[0].HeadSHA != gh.HeadSHA("posener", "hello", "master") {
+		t.Errorf("Requeued job = %+v, want the push job on the head", js[0])
+	}
+	if ls := leases(t, h); len(ls) != 1 || ls[0].Num != 2 || ls[0].Instance != "live" {
+		t.Errorf("Got leases %+v, want only the live lease", ls)
+	}
+}
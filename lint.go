@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/linkcheck"
+	"github.com/posener/goreadme-server/internal/lint"
+)
+
+const (
+	// lintCheckRunName is the name of the check run of the readme lint findings.
+	lintCheckRunName = "goreadme lint"
+	// linkCheckTimeout is the maximal duration of checking the links of a readme.
+	linkCheckTimeout = 30 * time.Second
+)
+
+// lint checks the generated readme, and records the findings as the job warnings.
+func (j *Job) lint(ctx context.Context, readme string) []lint.Finding {
+	opts := lint.Options{MaxLineLength: lint.MaxLineLength}
+	paths, err := j.repoPaths(ctx)
+	if err != nil {
+		j.log.Warnf("Failed getting repository paths, skipping links check: %s", err)
+	} else {
+		opts.Exists = func(path string) bool { return paths[path] }
+	}
+	findings := lint.Lint(readme, opts)
+	warnings := make([]string, len(findings))
+	for i, f := range findings {
+		warnings[i] = f.String()
+	}
+	j.Warnings = strings.Join(warnings, "\n")
+	return findings
+}
+
+// checkLinks checks the links of the generated readme in the background, while the job
+// continues. The dead links are added to the job warnings when the job is done.
+func (j *Job) checkLinks(readme string) {
+	ch := make(chan []linkcheck.Dead, 1)
+	j.deadLinks = ch
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), linkCheckTimeout)
+		defer cancel()
+		ch <- j.links.Check(ctx, readme)
+	}()
+}
+
+// addDeadLinks waits for the links check, and adds the dead links to the job warnings.
+func (j *Job) addDeadLinks() {
+	if j.deadLinks == nil {
+		return
+	}
+	dead := <-j.deadLinks
+	j.deadLinks = nil
+	var warnings []string
+	if j.Warnings != "" {
+		warnings = append(warnings, j.Warnings)
+	}
+	for _, d := range dead {
+		warnings = append(warnings, d.String())
+	}
+	j.Warnings = strings.Join(warnings, "\n")
+}
+
+// repoPaths returns the file and directory paths of the head commit.
+func (j *Job) repoPaths(ctx context.Context) (map[string]bool, error) {
+	if j.paths != nil {
+		return j.paths, nil
+	}
+	tree, _, err := j.github.Git.GetTree(ctx, j.Owner, j.Repo, j.HeadSHA, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting tree")
+	}
+	if tree.GetTruncated() {
+		return nil, errors.New("tree is too large")
+	}
+	paths := make(map[string]bool, len(tree.Entries))
+	for _, e := range tree.Entries {
+		paths[e.GetPath()] = true
+	}
+	j.paths = paths
+	return paths, nil
+}
+
+// annotate creates a check run on a commit, with the lint findings as annotations of the
+// readme file. The check run is created directly since the github library uses an old
+// format of annotations.
+func (j *Job) annotate(ctx context.Context, sha, path string, findings []lint.Finding) error {
+	type annotation struct {
+		Path            string `json:"path"`
+		StartLine       int    `json:"start_line"`
+		EndLine         int    `json:"end_line"`
+		AnnotationLevel string `json:"annotation_level"`
+		Title           string `json:"title"`
+		Message         string `json:"message"`
+	}
+	var annotations []annotation
+	for _, f := range findings {
+		annotations = append(annotations, annotation{
+			Path:            path,
+			StartLine:       f.Line,
+			EndLine:         f.Line,
+			AnnotationLevel: "warning",
+			Title:           f.Rule,
+			Message:         f.Message,
+		})
+	}
+	conclusion := "success"
+	summary := "No issues found in the generated readme."
+	if len(findings) > 0 {
+		conclusion = "neutral"
+		summary = fmt.Sprintf("Found %d issues in the generated readme.", len(findings))
+	}
+	body := map[string]interface{}{
+		"name":       lintCheckRunName,
+		"head_sha":   sha,
+		"status":     "completed",
+		"conclusion": conclusion,
+		"output": map[string]interface{}{
+			"title":       lintCheckRunName,
+			"summary":     summary,
+			"annotations": annotations,
+		},
+	}
+	req, err := j.github.NewRequest("POST", fmt.Sprintf("repos/%s/%s/check-runs", j.Owner, j.Repo), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.antiope-preview+json")
+	_, err = j.github.Do(ctx, req, nil)
+	return errors.Wrap(err, "failed creating check run")
+}
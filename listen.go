@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// unixPrefix is the prefix of bind addresses of unix sockets.
+const unixPrefix = "unix:"
+
+// listen listens on the bind address and port. The bind address can be an IPv4 or IPv6
+// host address, or empty to listen on all interfaces. A bind address with the "unix:"
+// prefix is a path of a unix socket, and the port is ignored.
+func listen(bind string, port int) (net.Listener, error) {
+	if strings.HasPrefix(bind, unixPrefix) {
+		path := strings.TrimPrefix(bind, unixPrefix)
+		// Remove a socket that was left by a previous run.
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "removing socket %s", path)
+		}
+		l, err := net.Listen("unix", path)
+		return l, errors.Wrapf(err, "listening on %s", path)
+	}
+	if port == 0 {
+		return nil, errors.New("port is required")
+	}
+	addr := net.JoinHostPort(strings.Trim(bind, "[]"), strconv.Itoa(port))
+	l, err := net.Listen("tcp", addr)
+	return l, errors.Wrapf(err, "listening on %s", addr)
+}
@@ -27,32 +27,149 @@
 // that calls goreadme server whenever the repository default branch is
 // modified. Goreadme then computes the new README.md file and compairs it
 // to the exiting one. If a change is needed, Goreadme will create a PR with
-// the new content of the README.md file.
+// the new content of the README.md file. The PR description lists the changed files and
+// the details of the generation, and it is updated whenever the PR is updated.
 //
 // Customization
 //
 // Adding a `goreadme.json` file to your repository main directory can enable some
 // customization to the generated readme file. The configuration is available
 // according to (goreadme.Config struct) https://godoc.org/github.com/posener/goreadme#Config.
+// Additionally, the `header_file` and `footer_file` options can point to files in the
+// repository, which their content is added to the top or to the bottom of the generated
+// readme file. An existing readme file named differently, such as `readme.md` or
+// `README.markdown`, is updated in place, unless the `normalize_readme` option is set, in
+// which case it is renamed to `README.md`. Small changes to an existing readme can be
+// ignored with the `min_change` option, which sets the minimal number of changed characters,
+// and the `ignore_whitespace` option. The `sections` option lists readme sections, such as
+// `["badges", "description", "install", "usage", "examples", "subpackages"]`, in the
+// order that they should appear in the readme. The generated readme is checked for long
+// lines, broken relative links and duplicate headings, and the `lint_check_run` option adds
+// the findings as annotations of a check run on the goreadme pull request. Dead links in
+// the generated readme are also reported in the job warnings. The `pr_strategy` option sets
+// how pull requests are opened: "reuse" updates a single goreadme pull request, "new" opens
+// a pull request for every change, and "amend" resets the goreadme branch on every change
+// and comments on the pull request. Goreadme pull requests have a `goreadme` label, which is
+// created in repositories that don't have it, and the `closes_issue` option links them to
+// an issue, such as a tracking issue of the readme, that is closed when they are merged.
+//
+// If a readme has a region between the `<!-- goreadme:begin -->` and `<!-- goreadme:end -->`
+// markers, only the region is replaced with the generated readme, and the rest of the readme
+// is kept. In repositories that have no Go code in the root directory, the readme is
+// generated from the shallowest Go module, and it is merged into the region of the existing
+// readme. Module detection is rolled out with the `module_detection` feature flag, which is
+// set for installations in the admin page. The `package_dir` option sets the directory of
+// the package explicitly.
+//
+// A `goreadme.json` file in the `.github` repository of an account applies to all the
+// repositories of the account. Options that are set in a repository `goreadme.json` file
+// override it.
+//
+// Readmes of several packages can be generated by adding a `.goreadme` directory with a JSON
+// file for every readme. The `source` option of a file is the package path in the repository,
+// the `output` option is the path of the generated readme, which defaults to a `README.md` in
+// the package directory, and the other options override the repository configuration. All
+// the changed readmes are committed to one pull request. The result of every readme is
+// reported as a commit status, named `goreadme/` and the file name without the extension,
+// which requires the statuses permission.
+//
+// Organization members can use the dashboard of an organization installation according to
+// the roles of their teams, which are set in the organization settings page: viewers can
+// see the jobs, operators can also run jobs and change project settings, and admins can
+// also change the organization settings. The app needs read access to the organization
+// members to check the team membership.
+//
+// The server exports Prometheus histograms on `/metrics`: the latency of Github API
+// requests by endpoint family and status, the duration of readme generations, and the
+// duration of jobs by status. Set `METRICS_TOKEN` to require it as a bearer token.
+//
+// Github API requests identify the server with a User-Agent of the server name, version
+// and contact URLs. Self hosted servers can set their own with `USER_AGENT`. The server
+// version is set at build time with `-ldflags "-X main.version=<version>"`, and it is shown
+// in the page footers and in the `X-Goreadme-Version` header of the API responses. The
+// `/version` endpoint returns the version, the commit, which is set with
+// `-X main.commit=<sha>`, and the Go and goreadme versions that the server was built with.
+//
+// Admins can change the log level, the number of workers and the API rate limit in the
+// runtime settings page without a restart. The settings are stored in the database, and the
+// server reloads them every minute and on a SIGHUP signal.
+//
+// Admins can attach notes to jobs, such as a Github outage that affected them, and resolve
+// stuck or failed jobs in the jobs page. Notes are shown to all the users of the job.
+//
+// Repositories can also use goreadme without installing the Github app, with a classic
+// repository webhook: in the webhooks page, the user provides a Github token with push access
+// to the repository, and adds a webhook with the shown payload URL and secret to the
+// repository. The token is stored encrypted with the session secret, and the webhook secret
+// of every repository is derived from `GITHUB_HOOK_SECRET`.
+//
+// Projects can be imported by their godoc.org or pkg.go.dev URL, or by their import path, in
+// the import page. The Github repository is resolved from the import path, or from its
+// `go-import` meta tag for vanity import paths. The first job runs if the repository is
+// installed, otherwise the page shows how to install it.
+//
+// Forks usually keep the readme of their parent repository, so goreadme runs on them only
+// when a user runs it manually. Automatic runs can be turned on in the project settings of
+// a fork.
+//
+// The server can run as separate web and worker processes, such that job processing scales
+// independently from serving HTTP. With `MODE=web`, or the `-mode web` flag, the process
+// serves HTTP and leaves the jobs in the database. With `-mode worker`, the process doesn't
+// serve HTTP, and runs the jobs that web processes left, as long as it has idle workers. The
+// default mode, `all`, serves HTTP and runs the jobs, and also runs jobs that web processes
+// left. The Procfile has a worker process for Heroku deployments.
+//
+// The server degrades gracefully while the database is briefly unavailable: badges are served
+// from the last known projects, or as unknown, and are not cached, and validated webhook
+// payloads of the Github app are stored in `HOOK_BUFFER_DIR` and replayed once the database
+// is available again, instead of being lost.
 package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
+	"net/smtp"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/posener/goreadme-server/internal/googleanalytics"
+	"github.com/posener/goreadme-server/internal/linkcheck"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
 	"github.com/kelseyhightower/envconfig"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/artifacts"
 	"github.com/posener/goreadme-server/internal/auth"
-	"github.com/posener/githubapp"
-	"github.com/posener/githubapp/cache"
+	"github.com/posener/goreadme-server/internal/breaker"
+	"github.com/posener/goreadme-server/internal/events"
+	"github.com/posener/goreadme-server/internal/flags"
+	"github.com/posener/goreadme-server/internal/githubapp"
+	"github.com/posener/goreadme-server/internal/githubapp/cache"
+	"github.com/posener/goreadme-server/internal/hookbuffer"
+	"github.com/posener/goreadme-server/internal/logging"
+	"github.com/posener/goreadme-server/internal/metrics"
+	"github.com/posener/goreadme-server/internal/migrations"
+	"github.com/posener/goreadme-server/internal/notify"
+	"github.com/posener/goreadme-server/internal/plans"
+	"github.com/posener/goreadme-server/internal/repohooks"
+	"github.com/posener/goreadme-server/internal/report"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/secrets"
+	"github.com/posener/goreadme-server/internal/settings"
+	"github.com/posener/goreadme-server/internal/static"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/posener/goreadme-server/internal/tokens"
 	"github.com/sirupsen/logrus"
 
 	_ "github.com/jinzhu/gorm/dialects/postgres"
@@ -60,7 +177,7 @@ import (
 
 var cfg struct {
 	Domain           string `required:"true" split_words:"true"`
-	Port             int    `required:"true" split_words:"true"`
+	Port             int    `split_words:"true"`
 	DatabaseURL      string `required:"true" split_words:"true"`
 	SessionSecret    string `required:"true" split_words:"true"`
 	GithubAppID      int    `required:"true" split_words:"true"`
@@ -68,43 +185,191 @@ var cfg struct {
 	GithubID         string `required:"true" split_words:"true"`
 	GithubSecret     string `required:"true" split_words:"true"`
 	GithubHookSecret string `required:"true" split_words:"true"`
-	Debug            bool   `default:"false" envconfig:"debug_server"`
+	// DatabaseReplicaURL is an optional read replica of the database, for read-only
+	// queries of the dashboard.
+	DatabaseReplicaURL string `split_words:"true"`
+	// LogFormat is the log output format: text or json.
+	LogFormat string `default:"text" split_words:"true"`
+	// LogLevel is the minimal level of logged entries, for example: debug, info or warning.
+	LogLevel string `default:"info" split_words:"true"`
+	// DebugServer sets the log level to debug. It is kept for existing deployments, new
+	// deployments should set the log level instead.
+	DebugServer bool `split_words:"true"`
+	// Workers is the number of jobs that can run concurrently.
+	Workers int `default:"4"`
+	// SMTP server configuration for email notifications. Email notifications are
+	// enabled only if the address is set.
+	SMTPAddr     string `envconfig:"smtp_addr"`
+	SMTPFrom     string `envconfig:"smtp_from" default:"goreadme@goreadme.herokuapp.com"`
+	SMTPUser     string `envconfig:"smtp_user"`
+	SMTPPassword string `envconfig:"smtp_password"`
+	// SentryDSN enables reporting of errors to Sentry.
+	SentryDSN string `envconfig:"sentry_dsn"`
+	// StalePRDays is the number of days after which a reminder is posted on an open
+	// goreadme pull request. Zero disables the reminders.
+	StalePRDays int `default:"14" split_words:"true"`
+	// RequeueAborted runs a new job for projects of jobs that were aborted since they did
+	// not finish in time.
+	RequeueAborted bool `split_words:"true"`
+	// SessionStore is where user sessions are stored: "cookie" stores the sessions in
+	// signed cookies, and "database" stores them in the database. Sessions of both stores
+	// can be revoked.
+	SessionStore string `default:"cookie" split_words:"true"`
+	// BindAddr is the address that the server listens on, together with the port: an IPv4
+	// or IPv6 host address, or empty for all interfaces. A "unix:" prefixed path listens on
+	// a unix socket instead, for example for a local reverse proxy.
+	BindAddr string `split_words:"true"`
+	// Admins are Github logins of users that can access the admin pages.
+	Admins []string `split_words:"true"`
+	// MetricsToken protects the /metrics endpoint with a bearer token. The endpoint is
+	// public if it is empty.
+	MetricsToken string `split_words:"true"`
+	// Mode selects the work of the process: "all" serves HTTP and runs jobs, "web" serves
+	// HTTP and leaves the jobs in the database, and "worker" runs the jobs that web
+	// processes left. The -mode flag overrides it.
+	Mode string `default:"all"`
+	// HookBufferDir is the directory that webhook payloads are stored in while the database
+	// is unavailable, until they are replayed. It defaults to a directory in the temporary
+	// directory.
+	HookBufferDir string `split_words:"true"`
+	// UserAgent is the User-Agent of Github API requests. It defaults to the server name,
+	// version and contact URLs. Self hosted servers can set their own contact in it.
+	UserAgent string `split_words:"true"`
+}
+
+// secretNames are the environment variables that can be loaded from files, with the _FILE
+// suffix, or from the secrets provider that is set by SECRETS_PROVIDER.
+var secretNames = []string{
+	"DATABASE_URL", "DATABASE_REPLICA_URL", "SESSION_SECRET", "GITHUB_KEY", "GITHUB_SECRET", "GITHUB_HOOK_SECRET", "SMTP_PASSWORD", "SENTRY_DSN", "METRICS_TOKEN",
 }
 
+var secretsProvider secrets.Provider
+
+var (
+	migrateOnly = flag.Bool("migrate", false, "Migrate the database and exit. Should run in the release phase.")
+	rollback    = flag.Int("rollback", -1, "Revert the database schema to the given version and exit.")
+	mode        = flag.String("mode", "", "Process mode: all, web or worker. Overrides MODE.")
+)
+
+const (
+	// homeCacheControl allows browsers to cache the home page, which may contain user data.
+	homeCacheControl = "private, max-age=60"
+	// badgeCacheControl allows proxies, such as the Github image proxy, to cache badges.
+	badgeCacheControl = "public, max-age=300"
+)
+
 func init() {
 	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [check | debug [debug flags] [payload.json] | backfill [backfill flags]]\n\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintln(flag.CommandLine.Output())
 		envconfig.Usage("", &cfg)
 	}
-	flag.Parse()
-	err := envconfig.Process("", &cfg)
+}
+
+// loadConfig loads the server configuration from the environment and the secrets provider.
+func loadConfig() error {
+	var err error
+	secretsProvider, err = secrets.New(os.Getenv("SECRETS_PROVIDER"))
 	if err != nil {
-		logrus.Fatal(err)
+		return err
+	}
+	err = secrets.Load(context.Background(), secretsProvider, secretNames...)
+	if err != nil {
+		return err
+	}
+	err = envconfig.Process("", &cfg)
+	if err != nil {
+		return err
+	}
+	if *mode != "" {
+		cfg.Mode = *mode
+	}
+	if flag.Arg(0) != "" {
+		// Commands run their jobs in the process.
+		cfg.Mode = modeAll
 	}
+	if err := checkMode(cfg.Mode); err != nil {
+		return err
+	}
+	if cfg.DebugServer {
+		cfg.LogLevel = "debug"
+	}
+	return logging.Setup(cfg.LogFormat, cfg.LogLevel,
+		cfg.SessionSecret, cfg.GithubKey, cfg.GithubSecret, cfg.GithubHookSecret, cfg.SMTPPassword)
 }
 
 func main() {
+	flag.Parse()
 	ctx := context.Background()
-	if cfg.Debug {
-		logrus.SetLevel(logrus.DebugLevel)
+
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+	case "check":
+		os.Exit(check(ctx))
+	case "debug", "backfill":
+		// Runs after the server is set up.
+	default:
+		logrus.Fatalf("Unknown command %q", cmd)
+	}
+
+	if err := loadConfig(); err != nil {
+		logrus.Fatal(err)
+	}
+
+	if err := checkGithubConfig(); err != nil {
+		logrus.Fatal(err)
 	}
 
 	ghCfg := githubapp.Config{
-		AppID:      strconv.Itoa(cfg.GithubAppID),
-		PrivateKey: []byte(cfg.GithubKey),
+		AppID:     strconv.Itoa(cfg.GithubAppID),
+		LoadKey:   githubKey,
+		UserAgent: userAgent(),
+	}
+	templates.Version = version
+
+	client, err := ghCfg.NewApp(ctx, githubapp.OptWithCache(cache.New(time.Minute*5, time.Minute*10)))
+	if err != nil {
+		logrus.Fatalf("Invalid Github app key: set GITHUB_KEY or GITHUB_KEY_FILE to the private key (.pem) from the Github app settings: %s", err)
 	}
 
-	client := ghCfg.NewApp(ctx, githubapp.OptWithCache(cache.New(time.Minute*5, time.Minute*10)))
 	db, err := gorm.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
 		logrus.Fatalf("Connect to DB on %s: %v", cfg.DatabaseURL, err)
 	}
 	defer db.Close()
-	if cfg.Debug {
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
 		db.LogMode(true)
 	}
+	replica := db
+	if cfg.DatabaseReplicaURL != "" {
+		replica, err = gorm.Open("postgres", cfg.DatabaseReplicaURL)
+		if err != nil {
+			logrus.Fatalf("Connect to DB replica on %s: %v", cfg.DatabaseReplicaURL, err)
+		}
+		defer replica.Close()
+		replica.LogMode(logrus.IsLevelEnabled(logrus.DebugLevel))
+	}
 
-	if err := db.AutoMigrate(&Job{}, &Project{}).Error; err != nil {
-		logrus.Fatalf("Migrate database: %s", err)
+	if *migrateOnly {
+		if err := migrations.Up(db); err != nil {
+			logrus.Fatalf("Migrate database: %s", err)
+		}
+		logrus.Infof("Migrated database to schema version %d", migrations.Latest())
+		return
+	}
+	if *rollback >= 0 {
+		if err := migrations.Down(db, *rollback); err != nil {
+			logrus.Fatalf("Rollback database: %s", err)
+		}
+		logrus.Infof("Reverted database to schema version %d", *rollback)
+		return
+	}
+	if err := checkSchema(db); err != nil {
+		logrus.Fatalf("Refusing to serve: %s", err)
+	}
+	if err := static.Check(); err != nil {
+		logrus.Warnf("Serving assets from the CDNs: %s", err)
 	}
 
 	a := &auth.Auth{
@@ -116,35 +381,208 @@ func main() {
 		LoginPath:     "/",
 		HomePath:      "/",
 	}
+	switch cfg.SessionStore {
+	case "cookie":
+		a.Store = auth.NewCookieStore(db, cfg.SessionSecret)
+	case "database":
+		a.Store = auth.NewDBStore(db, cfg.SessionSecret)
+	default:
+		logrus.Fatalf("Unknown session store %q", cfg.SessionStore)
+	}
 
 	a.Init()
 
+	if err := checkGithubApp(ctx, client.Client); err != nil {
+		logrus.Fatal(err)
+	}
+
+	reporter, err := report.New(cfg.SentryDSN)
+	if err != nil {
+		logrus.Fatalf("Create error reporter: %s", err)
+	}
+
 	h := &handler{
-		auth:   a,
-		db:     db,
-		github: client,
+		auth:       a,
+		db:         db,
+		replica:    replica,
+		github:     client,
+		events:     events.New(),
+		flags:      flags.New(db),
+		cooldowns:  gocache.New(manualRunCooldown, 10*time.Minute),
+		apiLimits:  gocache.New(apiRateWindow, 10*time.Minute),
+		orgConfigs: gocache.New(orgConfigExpiry, 10*time.Minute),
+		projects:   gocache.New(projectCacheExpiry, 10*time.Minute),
+		lastKnown:  gocache.New(lastKnownExpiry, time.Hour),
+		queue:      newQueue(cfg.Workers),
+		settings:   settings.New(db),
+		notify:     notifiers(),
+		report:     reporter,
+		plans:      plans.New(db),
+		artifacts:  artifacts.New(db),
+		tokens:     tokens.New(db),
+		breaker:    breaker.New(breakerThreshold, breakerCooldown),
+		links:      linkcheck.New(),
+		roles:      roles.NewResolver(),
+		repoHooks:  repohooks.New(db, cfg.SessionSecret, cfg.GithubHookSecret),
+		mode:       cfg.Mode,
+	}
+	h.apiRateLimit = defaultAPIRateLimit
+	h.events.Listen(h.invalidateProject)
+	if _, err := h.reloadRuntime(); err != nil {
+		logrus.Errorf("Failed loading runtime settings: %s", err)
+	}
+	go h.reloadRuntimePeriodically()
+	go h.reloadOnSignal()
+	switch flag.Arg(0) {
+	case "debug":
+		os.Exit(h.debug(ctx, flag.Args()[1:]))
+	case "backfill":
+		os.Exit(h.backfillCommand(ctx, flag.Args()[1:]))
+	}
+	if cfg.Mode != modeWeb {
+		go h.remindLoop(ctx)
+		go h.leaseLoop(ctx)
+		go h.reapLoop(ctx)
+		go h.claimLoop(ctx)
+	}
+	if cfg.Mode == modeWorker {
+		logrus.Infof("Starting worker %s...", instanceID)
+		<-ctx.Done()
+		return
 	}
-	h.debugPR()
+	if cfg.Mode == modeWeb {
+		go h.relayEvents(ctx)
+	}
+	h.hookBuffer, err = hookbuffer.New(hookBufferDir())
+	if err != nil {
+		logrus.Errorf("Hooks will not be buffered while the database is unavailable: %s", err)
+	}
+	go h.replayHooksLoop(ctx)
+	go refreshStatsLoop(ctx, db)
 
 	m := mux.NewRouter()
-	m.Methods("GET").Path("/").Handler(a.MayLogin(http.HandlerFunc(h.home)))
+	m.Methods("GET").Path("/").Handler(cacheControl(a.MayLogin(http.HandlerFunc(h.home)), homeCacheControl))
 	m.Methods("GET").Path("/projects").Handler(a.RequireLogin(http.HandlerFunc(h.projectsList)))
+	m.Methods("GET").Path("/projects/{owner}/{repo}/settings").Handler(a.RequireLogin(http.HandlerFunc(h.projectSettings)))
+	m.Methods("POST").Path("/projects/{owner}/{repo}/settings").Handler(a.RequireLogin(http.HandlerFunc(h.projectSettingsAction)))
 	m.Methods("GET").Path("/jobs").Handler(a.RequireLogin(http.HandlerFunc(h.jobsList)))
+	m.Methods("GET").Path("/settings").Handler(a.RequireLogin(http.HandlerFunc(h.installSettings)))
+	m.Methods("POST").Path("/settings").Handler(a.RequireLogin(http.HandlerFunc(h.installSettingsAction)))
+	m.Methods("GET").Path("/account/delete").Handler(a.RequireLogin(http.HandlerFunc(h.deleteAccount)))
+	m.Methods("POST").Path("/account/switch").Handler(a.RequireScopes(a.RequireLogin(http.HandlerFunc(h.switchAccount)), auth.ScopeReadOrg))
+	m.Methods("POST").Path("/account/delete").Handler(a.RequireLogin(http.HandlerFunc(h.deleteAccountAction)))
+	m.Methods("GET").Path("/usage").Handler(a.RequireLogin(http.HandlerFunc(h.usageReport)))
 	m.Methods("POST").Path("/add").Handler(a.RequireLogin(http.HandlerFunc(h.addRepoAction)))
 	m.Methods("GET").Path("/add").Handler(a.RequireLogin(http.HandlerFunc(h.addRepo)))
-	m.Methods("GET").Path("/badge/{owner}/{repo}.svg").HandlerFunc(http.HandlerFunc(h.badge))
+	m.Methods("GET").Path("/events").Handler(a.RequireLogin(http.HandlerFunc(h.liveEvents)))
+	m.Methods("GET").Path("/admin/flags").Handler(a.RequireLogin(http.HandlerFunc(h.adminFlags)))
+	m.Methods("POST").Path("/admin/flags").Handler(a.RequireLogin(http.HandlerFunc(h.adminFlagsAction)))
+	m.Methods("GET").Path("/admin/plans").Handler(a.RequireLogin(http.HandlerFunc(h.adminPlans)))
+	m.Methods("POST").Path("/admin/plans").Handler(a.RequireLogin(http.HandlerFunc(h.adminPlansAction)))
+	m.Methods("POST").Path("/admin/backfill").Handler(a.RequireLogin(http.HandlerFunc(h.adminBackfill)))
+	m.Methods("POST").Path("/admin/jobs/{owner}/{repo}/{num:[0-9]+}").Handler(a.RequireLogin(http.HandlerFunc(h.adminJobAction)))
+	m.Methods("GET").Path("/admin/runtime").Handler(a.RequireLogin(http.HandlerFunc(h.adminRuntime)))
+	m.Methods("POST").Path("/admin/runtime").Handler(a.RequireLogin(http.HandlerFunc(h.adminRuntimeAction)))
+	m.Methods("GET").Path("/import").Handler(a.RequireLogin(http.HandlerFunc(h.importProject)))
+	m.Methods("POST").Path("/import").Handler(a.RequireLogin(http.HandlerFunc(h.importProjectAction)))
+	m.Methods("GET").Path("/hooks").Handler(a.RequireLogin(http.HandlerFunc(h.repoHooksPage)))
+	m.Methods("POST").Path("/hooks").Handler(a.RequireLogin(http.HandlerFunc(h.repoHooksAction)))
+	m.Methods("GET").Path("/config").Handler(a.MayLogin(http.HandlerFunc(h.configGenerator)))
+	m.Methods("POST").Path("/config").Handler(a.MayLogin(http.HandlerFunc(h.configGeneratorAction)))
+	m.Methods("GET").Path("/badge/{owner}/{repo}.svg").Handler(cacheControl(http.HandlerFunc(h.badge), badgeCacheControl))
+	m.Methods("GET").Path("/badge/{owner}/{repo}/quality.svg").Handler(cacheControl(http.HandlerFunc(h.qualityBadge), badgeCacheControl))
+	h.addAPIRoutes(m)
+	m.Methods("GET").Path("/metrics").Handler(metricsAuth(metrics.Handler()))
+	m.Methods("GET").Path("/version").HandlerFunc(versionInfo)
 	m.Methods("POST").Path("/github/hook").HandlerFunc(h.hook)
+	m.Methods("POST").Path(repoHookPath).HandlerFunc(h.repoHook)
+	m.Methods("GET").Path("/github/hook/test").Handler(a.RequireLogin(http.HandlerFunc(h.hookTest)))
+	m.Methods("GET").PathPrefix(static.Prefix).Handler(static.Handler())
 	m.Path("/auth/login").Handler(a.LoginHandler())
 	m.Path("/auth/logout").Handler(a.LogoutHandler())
 	m.Path("/auth/callback").Handler(a.CallbackHandler())
 
 	googleanalytics.AddToRouter(m, "/analytics")
 
-	mh := handlers.RecoveryHandler(handlers.PrintRecoveryStack(true), handlers.RecoveryLogger(logrus.StandardLogger()))(m)
-	if cfg.Debug {
+	mh := handlers.RecoveryHandler(handlers.PrintRecoveryStack(true), handlers.RecoveryLogger(logrus.StandardLogger()))(report.Middleware(reporter, m))
+	mh = handlers.CompressHandler(mh)
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
 		mh = handlers.LoggingHandler(logrus.StandardLogger().Writer(), mh)
 	}
 
-	logrus.Infof("Starting server...")
-	http.ListenAndServe(fmt.Sprintf(":%d", cfg.Port), mh)
+	l, err := listen(cfg.BindAddr, cfg.Port)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	logrus.Infof("Starting server on %s...", l.Addr())
+	http.Serve(l, mh)
+}
+
+// githubKey loads the Github app private key from the secrets provider.
+func githubKey() ([]byte, error) {
+	key, found, err := secretsProvider.Secret(context.Background(), "GITHUB_KEY")
+	switch {
+	case err != nil:
+		return nil, err
+	case !found:
+		return nil, errors.New("private key is not configured")
+	}
+	return []byte(key), nil
+}
+
+// reloadOnSignal reloads the Github app private key and the runtime settings whenever the
+// process gets a SIGHUP signal, which enables key rotation without a restart when the key
+// is loaded from a file or from a secret manager.
+func (h *handler) reloadOnSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if _, err := h.reloadRuntime(); err != nil {
+			logrus.Errorf("Failed reloading runtime settings: %s", err)
+		} else {
+			logrus.Infof("Reloaded runtime settings")
+		}
+		if err := h.github.Reload(); err != nil {
+			logrus.Errorf("Failed reloading Github app key, keeping the current key: %s", err)
+			continue
+		}
+		logrus.Infof("Reloaded Github app key")
+	}
+}
+
+// cacheControl sets the Cache-Control header of the responses of a handler.
+func cacheControl(h http.Handler, value string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", value)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// metricsAuth requires the metrics token in the Authorization header, if it is configured.
+func metricsAuth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if cfg.MetricsToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.MetricsToken)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// notifiers returns the registry of the available notification sinks.
+func notifiers() *notify.Registry {
+	var r notify.Registry
+	r.Register("slack", notify.Slack{})
+	r.Register("webhook", notify.Webhook{})
+	r.Register("github", notify.GithubComment{})
+	if cfg.SMTPAddr != "" {
+		var auth smtp.Auth
+		if cfg.SMTPUser != "" {
+			host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+			auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, host)
+		}
+		r.Register("email", notify.Email{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, Auth: auth})
+	}
+	return &r
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/events"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+// adminJobAction sets the note of a job, and resolves the job if the resolve form value is
+// set. A resolved job that is the last job of its project also resolves the project, and
+// the lease of the job is released, so a stuck job is not requeued.
+func (h *handler) adminJobAction(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+	vars := mux.Vars(r)
+	owner, repo := vars["owner"], vars["repo"]
+	num, err := strconv.Atoi(vars["num"])
+	if err != nil {
+		http.Error(w, "Invalid job number", http.StatusBadRequest)
+		return
+	}
+	jobsURL := "/jobs?" + url.Values{"owner": {owner}, "repo": {repo}}.Encode()
+
+	var j Job
+	query := h.db.Where("owner = ? AND repo = ? AND num = ?", owner, repo, num).First(&j)
+	switch {
+	case query.RecordNotFound():
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	case query.Error != nil:
+		h.doError(w, r, errors.Wrap(query.Error, "failed getting job"))
+		return
+	}
+
+	login := data.User.GetLogin()
+	note := strings.TrimSpace(r.FormValue("note"))
+	values := map[string]interface{}{"note": note, "note_author": ""}
+	if note != "" {
+		values["note_author"] = login
+	}
+	resolve := r.FormValue("resolve") == "true"
+	if resolve {
+		if !j.Status.Resolvable() {
+			redirectError(w, r, jobsURL, "A job in status "+string(j.Status)+" can't be resolved")
+			return
+		}
+		values["status"] = status.Resolved
+	}
+
+	tx := h.db.Begin()
+	// The status condition prevents resolving a job that changed since it was read.
+	query = tx.Model(&Job{}).Where("owner = ? AND repo = ? AND num = ? AND status = ?", owner, repo, num, j.Status).UpdateColumns(values)
+	err = query.Error
+	if err == nil && query.RowsAffected == 0 {
+		tx.Rollback()
+		redirectError(w, r, jobsURL, "The job was changed, try again")
+		return
+	}
+	if err == nil && resolve {
+		err = tx.Model(&Project{}).Where("owner = ? AND repo = ? AND last_job = ?", owner, repo, num).
+			UpdateColumn("status", status.Resolved).Error
+	}
+	if err == nil && resolve {
+		err = tx.Where("owner = ? AND repo = ? AND num = ?", owner, repo, num).Delete(&Lease{}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		h.doError(w, r, errors.Wrap(err, "failed updating job"))
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed updating job"))
+		return
+	}
+
+	if resolve {
+		logrus.Infof("Admin %s resolved job %s/%s#%d from status %s", login, owner, repo, num, j.Status)
+		h.events.Publish(events.Event{
+			Install: j.Install,
+			Owner:   owner,
+			Repo:    repo,
+			Num:     num,
+			Status:  status.Resolved,
+			Message: j.Message,
+			PR:      j.PR,
+		})
+	} else {
+		logrus.Infof("Admin %s set note of job %s/%s#%d", login, owner, repo, num)
+	}
+	http.Redirect(w, r, jobsURL, http.StatusFound)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// apiRoute is a route of the REST API. The API routes are registered and documented in
+// the OpenAPI spec from the same definitions.
+type apiRoute struct {
+	Method  string
+	Path    string
+	Summary string
+	// Token is true if the route requires an API token.
+	Token bool
+	// Form are the form parameters of the route.
+	Form    []apiParam
+	Handler http.HandlerFunc
+}
+
+type apiParam struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// apiRoutes returns the routes of the REST API.
+func (h *handler) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			Method:  "GET",
+			Path:    "/api/v1/projects/{owner}/{repo}/snippet",
+			Summary: "Get snippets that add the badges of a project to a readme.",
+			Handler: h.snippet,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v1/projects/{owner}/{repo}/health",
+			Summary: "Get the status, last job, readme quality, pull request and warnings of a project.",
+			Token:   true,
+			Handler: h.projectHealthAPI,
+		},
+		{
+			Method:  "POST",
+			Path:    "/api/v1/auth/device",
+			Summary: "Start a Github device authorization flow.",
+			Handler: h.deviceCode,
+		},
+		{
+			Method:  "POST",
+			Path:    "/api/v1/auth/device/token",
+			Summary: "Complete a Github device authorization flow and issue an API token.",
+			Form: []apiParam{
+				{Name: "device_code", Description: "Device code of the device flow.", Required: true},
+				{Name: "name", Description: "Name of the issued token."},
+			},
+			Handler: h.deviceToken,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v1/user",
+			Summary: "Get the user of the API token.",
+			Token:   true,
+			Handler: h.apiUser,
+		},
+	}
+}
+
+// addAPIRoutes registers the REST API routes and the OpenAPI spec route.
+func (h *handler) addAPIRoutes(m *mux.Router) {
+	routes := h.apiRoutes()
+	for _, route := range routes {
+		var handler http.Handler = route.Handler
+		if route.Token {
+			handler = h.requireToken(handler)
+		}
+		m.Methods(route.Method).Path(route.Path).Handler(versionHeader(h.authenticate(h.rateLimit(handler))))
+	}
+	spec := openAPISpec(routes)
+	m.Methods("GET").Path("/api/v1/openapi.json").Handler(versionHeader(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, spec)
+	})))
+}
+
+var pathParam = regexp.MustCompile(`{([^}]+)}`)
+
+// openAPISpec returns an OpenAPI 3 spec of API routes.
+func openAPISpec(routes []apiRoute) map[string]interface{} {
+	type object = map[string]interface{}
+
+	paths := object{}
+	for _, route := range routes {
+		var params []object
+		for _, m := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, object{"name": m[1], "in": "path", "required": true, "schema": object{"type": "string"}})
+		}
+		op := object{
+			"summary": route.Summary,
+			"responses": object{
+				"200": object{"description": "OK"},
+				"429": object{"description": "Rate limit exceeded"},
+			},
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if len(route.Form) > 0 {
+			properties := object{}
+			var required []string
+			for _, p := range route.Form {
+				properties[p.Name] = object{"type": "string", "description": p.Description}
+				if p.Required {
+					required = append(required, p.Name)
+				}
+			}
+			schema := object{"type": "object", "properties": properties}
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			op["requestBody"] = object{
+				"required": len(required) > 0,
+				"content":  object{"application/x-www-form-urlencoded": object{"schema": schema}},
+			}
+		}
+		if route.Token {
+			op["security"] = []object{{"token": []string{}}}
+		}
+		item, _ := paths[route.Path].(object)
+		if item == nil {
+			item = object{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return object{
+		"openapi": "3.0.0",
+		"info": object{
+			"title":   "Goreadme",
+			"version": "v1",
+		},
+		"servers": []object{{"url": cfg.Domain}},
+		"paths":   paths,
+		"components": object{
+			"securitySchemes": object{
+				"token": object{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
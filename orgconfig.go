@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// orgConfigRepo is the repository of an account that holds a goreadme.json which applies
+// to all the repositories of the installation, following Github's community health files
+// convention.
+const orgConfigRepo = ".github"
+
+// orgConfigExpiry is the duration that an account configuration is cached.
+const orgConfigExpiry = 10 * time.Minute
+
+// orgConfig is a cached account configuration.
+type orgConfig struct {
+	content string
+	found   bool
+}
+
+// getOrgConfig returns the content of the goreadme.json file of the account configuration
+// repository, and whether it exists.
+func (j *Job) getOrgConfig(ctx context.Context) (content string, found bool, err error) {
+	key := strconv.FormatInt(j.Install, 10)
+	if v, ok := j.orgConfigs.Get(key); ok {
+		c := v.(orgConfig)
+		return c.content, c.found, nil
+	}
+	content, found, err = j.getRepoFile(ctx, orgConfigRepo, configPath)
+	if err != nil {
+		return "", false, err
+	}
+	j.orgConfigs.Set(key, orgConfig{content: content, found: found}, orgConfigExpiry)
+	return content, found, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/posener/goreadme-server/internal/githubapp"
+)
+
+// requiredPermissions are the permissions that goreadme needs write access to, for opening
+// pull requests.
+var requiredPermissions = []string{"contents", "pull_requests"}
+
+// missingPermissions returns the required permissions that were not granted to an
+// installation.
+func missingPermissions(install *githubapp.Installation) []string {
+	// Permissions are unknown, assume that they were granted.
+	if install.Permissions == nil {
+		return nil
+	}
+	var missing []string
+	for _, p := range requiredPermissions {
+		if !install.HasPermission(p, "write") {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
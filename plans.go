@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/plans"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+// checkPlan returns a *plans.LimitError if running goreadme on a project exceeds the plan
+// of its installation.
+func (h *handler) checkPlan(p *Project) error {
+	if !p.Private {
+		return nil
+	}
+	plan, err := h.plans.Of(p.Install)
+	if err != nil {
+		return err
+	}
+	// Count the other private projects that goreadme runs on.
+	var used int
+	err = h.db.Model(&Project{}).
+		Where("install = ? AND private = TRUE AND status != ?", p.Install, status.PlanLimit).
+		Where("NOT (owner = ? AND repo = ?)", p.Owner, p.Repo).
+		Count(&used).Error
+	if err != nil {
+		return errors.Wrap(err, "failed counting private projects")
+	}
+	return plan.CheckPrivateRepos(used)
+}
+
+func (h *handler) adminPlans(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+
+	var err error
+	data.Plans, err = h.plans.List()
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	data.AvailablePlans = plans.All
+	err = templates.AdminPlans.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+func (h *handler) adminPlansAction(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+
+	name := r.FormValue("plan")
+	install, err := strconv.ParseInt(r.FormValue("install"), 10, 64)
+	if err != nil {
+		redirectError(w, r, "/admin/plans", "Invalid installation")
+		return
+	}
+	if _, ok := plans.Get(name); !ok {
+		redirectError(w, r, "/admin/plans", "Invalid plan")
+		return
+	}
+	err = h.plans.Set(install, name)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	logrus.Infof("Admin %s set plan of install %d to %s", data.User.GetLogin(), install, name)
+	http.Redirect(w, r, "/admin/plans", http.StatusFound)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/diff"
+	"github.com/posener/goreadme-server/internal/repohooks"
+	"github.com/posener/goreadme-server/internal/templates"
+)
+
+// goreadmeLabel is the label of goreadme pull requests, which is created in repositories
+// that don't have it.
+const goreadmeLabel = "goreadme"
+
+// goreadmeModule is the module of the goreadme library, which its version is shown in pull
+// requests.
+const goreadmeModule = "github.com/posener/goreadme"
+
+// prChange is a file that a job changed in its pull request, and the number of lines that
+// were added to it and removed from it.
+type prChange struct {
+	Path    string
+	Added   int
+	Removed int
+}
+
+// addPRChange records a change of a file, for the pull request body.
+func (j *Job) addPRChange(path, current, content string) {
+	removed, added := diff.Lines(current, content)
+	j.prChanges = append(j.prChanges, prChange{Path: path, Added: len(added), Removed: len(removed)})
+}
+
+// prBody returns the body of the pull request of the job.
+func (j *Job) prBody() (string, error) {
+	installationURL := fmt.Sprintf("https://github.com/settings/installations/%d", j.Install)
+	if repohooks.IsHook(j.Install) {
+		installationURL = cfg.Domain + "/hooks"
+	}
+	var b strings.Builder
+	err := templates.PRBody.Execute(&b, map[string]interface{}{
+		"Branch":          j.DefaultBranch,
+		"HeadSHA":         j.HeadSHA,
+		"Changes":         j.prChanges,
+		"Version":         moduleVersion(goreadmeModule),
+		"ConfigHash":      j.configHash,
+		"Num":             j.Num,
+		"JobURL":          j.jobsURL(),
+		"Trigger":         j.Trigger,
+		"SettingsURL":     fmt.Sprintf("%s/projects/%s/%s/settings", cfg.Domain, j.Owner, j.Repo),
+		"InstallationURL": installationURL,
+		"RepoHook":        repohooks.IsHook(j.Install),
+		"ClosesIssue":     j.closesIssue,
+	})
+	return b.String(), errors.Wrap(err, "failed executing PR body template")
+}
+
+// updatePRBody sets the body of an existing pull request, so it describes the last job that
+// updated it.
+func (j *Job) updatePRBody(ctx context.Context, prNum int) error {
+	body, err := j.prBody()
+	if err != nil {
+		return err
+	}
+	_, _, err = j.github.PullRequests.Edit(ctx, j.Owner, j.Repo, prNum, &github.PullRequest{
+		Body: github.String(body),
+	})
+	return errors.Wrap(err, "failed editing PR")
+}
+
+// labelPR adds the goreadme label to a pull request, and creates the label if the
+// repository doesn't have it.
+func (j *Job) labelPR(ctx context.Context, prNum int) error {
+	_, resp, err := j.github.Issues.GetLabel(ctx, j.Owner, j.Repo, goreadmeLabel)
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		_, _, err = j.github.Issues.CreateLabel(ctx, j.Owner, j.Repo, &github.Label{
+			Name:        github.String(goreadmeLabel),
+			Color:       github.String("00add8"),
+			Description: github.String("Readme updates by goreadme"),
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed creating label")
+		}
+	case err != nil:
+		return errors.Wrap(err, "failed getting label")
+	}
+	_, _, err = j.github.Issues.AddLabelsToIssue(ctx, j.Owner, j.Repo, prNum, []string{goreadmeLabel})
+	return errors.Wrap(err, "failed adding label")
+}
+
+// jobsURL returns the URL of the jobs page of the project.
+func (j *Job) jobsURL() string {
+	return fmt.Sprintf("%s/jobs?owner=%s&repo=%s", cfg.Domain, j.Owner, j.Repo)
+}
+
+// moduleVersion returns the version of a module that the server was built with.
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, m := range info.Deps {
+		if m.Path != path {
+			continue
+		}
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		return m.Version
+	}
+	return "unknown"
+}
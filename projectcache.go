@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/events"
+)
+
+// projectCacheExpiry is the duration that projects are cached for the public endpoints.
+// Projects are removed from the cache when their jobs change state on this server, and the
+// expiry bounds the staleness of projects whose jobs run on other servers.
+const projectCacheExpiry = time.Minute
+
+// cachedProject returns a project for the public endpoints, such as the badges, from the
+// cache or from the database. A project that does not exist is returned empty, with found
+// set to false. If the database fails, the last known project is returned with the error.
+func (h *handler) cachedProject(owner, repo string) (p Project, found bool, err error) {
+	key := owner + "/" + repo
+	if v, ok := h.projects.Get(key); ok {
+		p = v.(Project)
+		return p, p.Owner != "", nil
+	}
+	query := h.replica.Where("owner = ? AND repo = ?", owner, repo).First(&p)
+	switch {
+	case query.RecordNotFound():
+	case query.Error != nil:
+		// The database might be unavailable, fall back to the last known project.
+		if v, ok := h.lastKnown.Get(key); ok {
+			p = v.(Project)
+		}
+		return p, p.Owner != "", errors.Wrapf(query.Error, "failed getting project %s/%s", owner, repo)
+	}
+	h.projects.SetDefault(key, p)
+	h.lastKnown.SetDefault(key, p)
+	return p, p.Owner != "", nil
+}
+
+// invalidateProject removes the project of a job event from the cache.
+func (h *handler) invalidateProject(e events.Event) {
+	h.projects.Delete(e.Owner + "/" + e.Repo)
+}
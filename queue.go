@@ -0,0 +1,141 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultJobDuration is the estimated job duration before any job has finished.
+const defaultJobDuration = 10 * time.Second
+
+// queue runs jobs in the background with a bounded number of workers, in the order
+// that they were pushed.
+type queue struct {
+	items chan queueItem
+
+	// wg counts the jobs that were pushed and did not finish.
+	wg sync.WaitGroup
+
+	mu      sync.Mutex
+	workers int
+	// stops has a channel for every worker, which is closed to stop the worker after its
+	// current job.
+	stops   []chan struct{}
+	pending []*Job
+	running int
+	// avgDuration is a moving average of jobs durations.
+	avgDuration time.Duration
+}
+
+type queueItem struct {
+	job  *Job
+	done chan<- struct{}
+}
+
+// newQueue returns a queue and starts its workers.
+func newQueue(workers int) *queue {
+	q := &queue{
+		items:       make(chan queueItem, 1000),
+		avgDuration: defaultJobDuration,
+	}
+	q.resize(workers)
+	return q
+}
+
+// resize changes the number of workers. Workers that are removed finish their current job
+// before they stop.
+func (q *queue) resize(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.stops) < workers {
+		stop := make(chan struct{})
+		q.stops = append(q.stops, stop)
+		go q.work(stop)
+	}
+	for len(q.stops) > workers {
+		last := len(q.stops) - 1
+		close(q.stops[last])
+		q.stops = q.stops[:last]
+	}
+	q.workers = workers
+}
+
+// push adds a job to the queue. The done channel is closed when the job is finished.
+func (q *queue) push(j *Job, done chan<- struct{}) {
+	q.wg.Add(1)
+	q.mu.Lock()
+	q.pending = append(q.pending, j)
+	q.mu.Unlock()
+	q.items <- queueItem{job: j, done: done}
+}
+
+// idle returns the number of workers that have no running or pending job.
+func (q *queue) idle() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.workers - q.running - len(q.pending)
+}
+
+// wait blocks until all the pushed jobs are finished.
+func (q *queue) wait() {
+	q.wg.Wait()
+}
+
+func (q *queue) work(stop <-chan struct{}) {
+	for {
+		// Check the stop channel first, since select doesn't prefer it over a pending item.
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		var item queueItem
+		select {
+		case <-stop:
+			return
+		case item = <-q.items:
+		}
+		q.mu.Lock()
+		for i, j := range q.pending {
+			if j == item.job {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+		q.running++
+		q.mu.Unlock()
+
+		item.job.runInBackground(item.done)
+
+		q.mu.Lock()
+		q.running--
+		// Exponential moving average, giving the last job a weight of 20%.
+		q.avgDuration = (4*q.avgDuration + item.job.Duration) / 5
+		q.mu.Unlock()
+		q.wg.Done()
+	}
+}
+
+// position returns the 1-based position of a pending job in the queue, and the
+// estimated time that it will start. It returns 0 if the job is not pending.
+func (q *queue) position(owner, repo string, num int) (pos int, start time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, j := range q.pending {
+		if j.Owner == owner && j.Repo == repo && j.Num == num {
+			pos = i + 1
+			break
+		}
+	}
+	if pos == 0 {
+		return 0, time.Time{}
+	}
+	// All the running jobs and the jobs ahead in the queue should finish
+	// before this job will start.
+	ahead := q.running + pos - 1
+	waves := ahead / q.workers
+	return pos, time.Now().Add(time.Duration(waves) * q.avgDuration)
+}
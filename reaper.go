@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hako/durafmt"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// reapInterval is the interval between checks for stuck jobs.
+	reapInterval = time.Minute
+	// reapAfter is the duration after which a started job is considered stuck. Jobs time out
+	// after timeout, so a job that didn't finish by then will never finish.
+	reapAfter = 2 * timeout
+)
+
+// reapLoop aborts stuck jobs periodically, until the context is done.
+func (h *handler) reapLoop(ctx context.Context) {
+	t := time.NewTicker(reapInterval)
+	defer t.Stop()
+	for {
+		h.reap(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// reap marks jobs that were started and did not finish in time as aborted. If requeuing of
+// aborted jobs is enabled, a new job is run for their projects.
+func (h *handler) reap(ctx context.Context) {
+	var jobs []Job
+	err := h.db.Where("status = ? AND updated_at < ?", status.Started, time.Now().Add(-reapAfter)).Find(&jobs).Error
+	if err != nil {
+		logrus.Errorf("Failed getting stuck jobs: %s", err)
+		return
+	}
+	for i := range jobs {
+		j := &jobs[i]
+		message := fmt.Sprintf("Aborted: the job did not finish within %s", durafmt.ParseShort(reapAfter))
+		debug := fmt.Sprintf("Started at %s, last updated by instance %s", j.UpdatedAt.Format(time.RFC3339), h.leaseInstance(j))
+
+		// Abort the job, unless another instance already did, or it has just finished.
+		query := h.db.Model(&Job{}).
+			Where("owner = ? AND repo = ? AND num = ? AND status = ?", j.Owner, j.Repo, j.Num, status.Started).
+			UpdateColumns(map[string]interface{}{"status": status.Aborted, "message": message, "debug": debug})
+		if query.Error != nil {
+			logrus.Errorf("Failed aborting %s/%s#%d: %s", j.Owner, j.Repo, j.Num, query.Error)
+			continue
+		}
+		if query.RowsAffected == 0 {
+			continue
+		}
+
+		j.db = h.db
+		j.events = h.events
+		j.settings = h.settings
+		j.notify = h.notify
+		j.log = logrus.WithField("job", fmt.Sprintf("%s/%s#%d", j.Owner, j.Repo, j.Num))
+		if err := j.setStatus(status.Aborted); err != nil {
+			j.log.Errorf("Not finishing aborted job: %s", err)
+			continue
+		}
+		j.Message, j.Debug = message, debug
+		j.log.Warn(message)
+		j.finish()
+
+		if !cfg.RequeueAborted {
+			continue
+		}
+		p := j.Project
+		p.HeadSHA = ""
+		_, _, err := h.runJob(ctx, &p, trigger{Name: fmt.Sprintf("Requeued #%d", j.Num)})
+		if err != nil {
+			logrus.Errorf("Failed requeuing aborted job %s/%s#%d: %s", j.Owner, j.Repo, j.Num, err)
+		}
+	}
+}
+
+// leaseInstance returns the server instance that holds the lease of a job.
+func (h *handler) leaseInstance(j *Job) string {
+	var l Lease
+	err := h.db.Where("owner = ? AND repo = ? AND num = ?", j.Owner, j.Repo, j.Num).First(&l).Error
+	if err != nil {
+		return "unknown"
+	}
+	return l.Instance
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/posener/goreadme-server/internal/githubtest"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/sirupsen/logrus"
+)
+
+// stuckJob inserts a started job that was last updated before it is considered stuck, on
+// a commit that is no longer the head.
+func stuckJob(t *testing.T, h *handler) *Job {
+	t.Helper()
+	j := &Job{Num: 1, Trigger: "Push"}
+	j.Owner, j.Repo, j.Install, j.LastJob = "posener", "hello", testInstall, 1
+	j.HeadSHA = "0000000000000000000000000000000000000000"
+	j.Status = status.Started
+	j.UpdatedAt = time.Now().Add(-2 * reapAfter)
+	githubtest.Fixtures(t, h.db, j, &Lease{Owner: "posener", Repo: "hello", Num: 1, Instance: "other"})
+	return j
+}
+
+func TestFinishAfterAbort(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+
+	j := stuckJob(t, h)
+	j.db, j.log, j.start = h.db, logrus.New(), time.Now()
+	h.reap(context.Background())
+
+	// The running job finishes after it was aborted.
+	j.done(nil, "Done")
+
+	js := jobs(t, h)
+	if len(js) != 1 || js[0].Status != status.Aborted {
+		t.Fatalf("Got jobs %+v, want the aborted job", js)
+	}
+}
+
+func TestReap(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+
+	stuckJob(t, h)
+	// Recently started and finished jobs are not aborted.
+	recent := &Job{Num: 2}
+	recent.Owner, recent.Repo, recent.Status = "posener", "hello", status.Started
+	old := &Job{Num: 3}
+	old.Owner, old.Repo, old.Status = "posener", "hello", status.Success
+	old.UpdatedAt = time.Now().Add(-2 * reapAfter)
+	githubtest.Fixtures(t, h.db, recent, old)
+
+	h.reap(context.Background())
+	h.queue.wait()
+
+	js := jobs(t, h)
+	want := []status.Status{status.Aborted, status.Started, status.Success}
+	if len(js) != len(want) {
+		t.Fatalf("Got %d jobs, want %d", len(js), len(want))
+	}
+	for i, j := range js {
+		if j.Status != want[i] {
+			t.Errorf("Job #%d status = %s, want %s", j.Num, j.Status, want[i])
+		}
+	}
+	if js[0].Message == "" || js[0].Debug == "" {
+		t.Errorf("Aborted job has no message or debug info: %+v", js[0])
+	}
+
+	// Reaping again does not change the aborted job.
+	h.reap(context.Background())
+	if js := jobs(t, h); len(js) != 3 || js[0].Status != status.Aborted {
+		t.Errorf("Got jobs %+v after reaping again", js)
+	}
+}
+
+func TestReapRequeue(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+	cfg.RequeueAborted = true
+
+	stuckJob(t, h)
+	h.reap(context.Background())
+	h.queue.wait()
+
+	js := jobs(t, h)
+	if len(js) != 2 {
+		t.Fatalf("Got %d jobs, want the aborted and the requeued jobs", len(js))
+	}
+	if js[1].Trigger != "Requeued #1" || js[1].Status != status.Success {
+		t.Errorf("Requeued job = %+v, want a successful job triggered by the aborted job", js[1])
+	}
+	if got, want := js[1].HeadSHA, gh.HeadSHA("posener", "hello", "master"); got != want {
+		t.Errorf("Requeued job ran on %s, want the current head %s", got, want)
+	}
+}
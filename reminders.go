@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// remindInterval is the interval between checks for stale goreadme pull requests.
+const remindInterval = time.Hour
+
+// settingRemind is the project setting key that disables stale pull request reminders
+// when set to "off".
+const settingRemind = "remind_stale_pr"
+
+// StalePR returns whether the project has a goreadme pull request that is open for
+// longer than the configured number of days.
+func (p Project) StalePR() bool {
+	return p.PR != 0 && p.PRCreatedAt != nil && cfg.StalePRDays > 0 &&
+		time.Since(*p.PRCreatedAt) > staleAfter()
+}
+
+func staleAfter() time.Duration {
+	return time.Duration(cfg.StalePRDays) * 24 * time.Hour
+}
+
+// remindLoop comments on stale goreadme pull requests periodically, until the context
+// is done.
+func (h *handler) remindLoop(ctx context.Context) {
+	if cfg.StalePRDays <= 0 {
+		logrus.Info("Stale PR reminders are disabled")
+		return
+	}
+	t := time.NewTicker(remindInterval)
+	defer t.Stop()
+	for {
+		h.remindStale(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// remindStale comments on goreadme pull requests that are open for longer than the
+// configured number of days, and were not reminded about in that period.
+func (h *handler) remindStale(ctx context.Context) {
+	since := time.Now().Add(-staleAfter())
+	var projects []Project
+	err := h.db.
+		Where("pr != 0 AND pr_created_at < ?", since).
+		Where("pr_reminded_at IS NULL OR pr_reminded_at < ?", since).
+		Find(&projects).Error
+	if err != nil {
+		logrus.Errorf("Failed getting projects with stale PRs: %s", err)
+		return
+	}
+	for i := range projects {
+		p := &projects[i]
+		prefs, err := h.settings.Project(p.Owner, p.Repo)
+		if err != nil {
+			logrus.Errorf("Failed getting settings of %s/%s: %s", p.Owner, p.Repo, err)
+			continue
+		}
+		if prefs[settingRemind] == "off" {
+			continue
+		}
+		if err := h.remind(ctx, p); err != nil {
+			logrus.Errorf("Failed reminding about %s/%s#%d: %s", p.Owner, p.Repo, p.PR, err)
+		}
+	}
+}
+
+// remind comments on the goreadme pull request of a project, if it is still open.
+func (h *handler) remind(ctx context.Context, p *Project) error {
+	install, err := h.installation(ctx, p)
+	if err != nil {
+		return errors.Wrap(err, "failed getting installation")
+	}
+	pr, _, err := install.Github.PullRequests.Get(ctx, p.Owner, p.Repo, p.PR)
+	if err != nil {
+		return errors.Wrap(err, "failed getting PR")
+	}
+	if pr.GetState() != "open" {
+		return nil
+	}
+	logrus.Infof("Reminding about stale PR %s/%s#%d", p.Owner, p.Repo, p.PR)
+	_, _, err = install.Github.Issues.CreateComment(ctx, p.Owner, p.Repo, p.PR, &github.IssueComment{
+		Body: github.String(fmt.Sprintf(
+			"This PR keeps the readme up to date with the go doc, and has been open for %d days. "+
+				"Please consider merging it, or closing it if it is not needed. "+
+				"Reminders can be turned off in the [project settings](%s/projects/%s/%s/settings).",
+			int(time.Since(pr.GetCreatedAt()).Hours()/24), cfg.Domain, p.Owner, p.Repo)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed commenting")
+	}
+	return errors.Wrap(
+		h.db.Model(p).UpdateColumn("pr_reminded_at", time.Now()).Error,
+		"failed saving reminder time")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+// Installation settings of the repositories that goreadme runs on. Both are lists of glob
+// patterns of repository names, separated by commas or spaces.
+const (
+	// settingAllowRepos limits goreadme to the repositories that match the patterns.
+	settingAllowRepos = "allow_repos"
+	// settingDenyRepos skips the repositories that match the patterns.
+	settingDenyRepos = "deny_repos"
+)
+
+// settingTimezone is the user setting of the timezone that times are shown in, such as
+// "Europe/London".
+const settingTimezone = "timezone"
+
+// repoAllowed returns whether an installation allows running goreadme on a repository.
+// Denied repositories are not allowed even if they are also in the allow list.
+func repoAllowed(settings map[string]string, repo string) bool {
+	if matchRepo(repo, repoPatterns(settings[settingDenyRepos])) {
+		return false
+	}
+	allow := repoPatterns(settings[settingAllowRepos])
+	return len(allow) == 0 || matchRepo(repo, allow)
+}
+
+// hookRepoAllowed returns whether a hook should run goreadme on a repository, according to
+// the settings of its installation.
+func (h *handler) hookRepoAllowed(owner, repo string) bool {
+	prefs, err := h.settings.Installation(owner)
+	if err != nil {
+		// Don't block jobs since the settings could not be read.
+		logrus.Errorf("Failed getting installation settings of %s: %s", owner, err)
+		return true
+	}
+	if !repoAllowed(prefs, repo) {
+		logrus.Infof("Skipping %s/%s by the installation repository lists", owner, repo)
+		return false
+	}
+	return true
+}
+
+func repoPatterns(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+func matchRepo(repo string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) installSettings(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+	var err error
+	data.Settings, err = h.settings.Installation(data.Account)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	data.TeamRoles = formatTeamRoles(data.Settings)
+	err = templates.InstallSettings.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+func (h *handler) installSettingsAction(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+	if data.InstallID == 0 {
+		redirectError(w, r, r.URL.Path, "Goreadme is not installed")
+		return
+	}
+	if !requireRole(w, data, roles.Admin) {
+		return
+	}
+	current, err := h.settings.Installation(data.Account)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	values, err := parseTeamRoles(r.FormValue("team_roles"), current)
+	if err != nil {
+		redirectError(w, r, r.URL.Path, err.Error())
+		return
+	}
+	for key, value := range map[string]string{
+		settingAllowRepos: strings.Join(repoPatterns(r.FormValue(settingAllowRepos)), ", "),
+		settingDenyRepos:  strings.Join(repoPatterns(r.FormValue(settingDenyRepos)), ", "),
+		settingTimezone:   strings.TrimSpace(r.FormValue(settingTimezone)),
+	} {
+		values[key] = value
+	}
+	if _, err := time.LoadLocation(values[settingTimezone]); err != nil {
+		redirectError(w, r, r.URL.Path, "Unknown timezone: "+values[settingTimezone])
+		return
+	}
+	for _, key := range []string{settingAllowRepos, settingDenyRepos} {
+		for _, pattern := range repoPatterns(values[key]) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				redirectError(w, r, r.URL.Path, "Invalid pattern: "+pattern)
+				return
+			}
+		}
+	}
+	err = h.settings.Set(int64(data.InstallID), data.Account, "", values)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, r.URL.Path, http.StatusFound)
+}
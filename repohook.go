@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/githubapp"
+	"github.com/posener/goreadme-server/internal/repohooks"
+	"github.com/posener/goreadme-server/internal/status"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+// repoHookPath is the path of the endpoint of repository webhooks.
+const repoHookPath = "/github/repohook"
+
+// repoHookRow is a repository webhook of a user in the webhooks page.
+type repoHookRow struct {
+	Owner  string
+	Repo   string
+	Secret string
+	// Status is the status of the last job of the repository.
+	Status status.Status
+}
+
+// installation returns the Github clients of a project. Projects of repository webhooks use
+// the token of the user that added the webhook.
+func (h *handler) installation(ctx context.Context, p *Project) (*githubapp.Installation, error) {
+	if !repohooks.IsHook(p.Install) {
+		return h.github.Installation(ctx, p.Owner)
+	}
+	hook, token, err := h.repoHooks.Get(p.Owner, p.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil || hook.Install() != p.Install {
+		return nil, errors.Errorf("repository webhook of %s/%s was removed", p.Owner, p.Repo)
+	}
+	return h.github.TokenInstallation(token), nil
+}
+
+// installs returns the installation IDs of the projects that the user can see: the projects
+// of the installation, and the projects of the repository webhooks that the user added.
+func (data *templateData) installs() []int64 {
+	return []int64{int64(data.InstallID), repohooks.Install(data.User.GetID())}
+}
+
+// repoHook is called by github on events of repositories that users added a webhook to.
+// Each repository has its own webhook secret, so the payload is validated only after the
+// repository is known.
+func (h *handler) repoHook(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	var payload struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Payload must be sent as application/json", http.StatusBadRequest)
+		return
+	}
+	parts := strings.SplitN(payload.Repository.FullName, "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "Missing repository", http.StatusBadRequest)
+		return
+	}
+	owner, repo := parts[0], parts[1]
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	body, err = github.ValidatePayload(r, []byte(h.repoHooks.Secret(owner, repo)))
+	if err != nil {
+		logrus.Warnf("Unauthorized repository webhook request of %s/%s: %s", owner, repo, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	hook, _, err := h.repoHooks.Get(owner, repo)
+	if err != nil {
+		logrus.Errorf("Failed getting webhook of %s/%s: %s", owner, repo, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if hook == nil {
+		logrus.Infof("Skipping event of removed repository webhook of %s/%s", owner, repo)
+		http.Error(w, "Repository webhook was removed", http.StatusGone)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(r), body)
+	if err != nil {
+		logrus.Warnf("Got unexpected repository webhook payload of %s/%s: %s", owner, repo, err)
+		return
+	}
+	switch e := event.(type) {
+	case *github.PushEvent:
+		logrus.Infof("Repository push hook triggered for %s/%s", owner, repo)
+		if err := h.push(r.Context(), e, hook.Install()); err != nil {
+			logrus.Error(err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	case *github.PingEvent:
+		logrus.Infof("Repository ping hook of %s/%s: %s", owner, repo, e.GetZen())
+	default:
+		logrus.Infof("Skipping repository webhook event %q of %s/%s", github.WebHookType(r), owner, repo)
+	}
+}
+
+// repoHooksPage shows the repository webhooks of the user, and how to add them.
+func (h *handler) repoHooksPage(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+
+	hooks, err := h.repoHooks.List(data.User.GetLogin())
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	for _, hook := range hooks {
+		row := repoHookRow{Owner: hook.Owner, Repo: hook.Repo, Secret: h.repoHooks.Secret(hook.Owner, hook.Repo)}
+		var p Project
+		err := h.replica.Where("owner = ? AND repo = ? AND install = ?", hook.Owner, hook.Repo, hook.Install()).First(&p).Error
+		if err == nil {
+			row.Status = p.Status
+		}
+		data.RepoHooks = append(data.RepoHooks, row)
+	}
+	data.RepoHookURL = cfg.Domain + repoHookPath
+	err = templates.RepoHooks.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+// repoHooksAction adds or deletes a repository webhook of the user.
+func (h *handler) repoHooksAction(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+
+	var (
+		login = data.User.GetLogin()
+		owner = strings.TrimSpace(r.FormValue("owner"))
+		repo  = strings.TrimSpace(r.FormValue("repo"))
+	)
+	if owner == "" || repo == "" {
+		redirectError(w, r, "/hooks", "Repository owner and name are required")
+		return
+	}
+
+	if r.FormValue("action") == "delete" {
+		if err := h.repoHooks.Delete(owner, repo, login); err != nil {
+			h.doError(w, r, err)
+			return
+		}
+		logrus.Infof("User %s deleted repository webhook of %s/%s", login, owner, repo)
+		http.Redirect(w, r, "/hooks", http.StatusFound)
+		return
+	}
+
+	hook, _, err := h.repoHooks.Get(owner, repo)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if hook != nil && hook.Login != login {
+		redirectError(w, r, "/hooks", fmt.Sprintf("Repository %s/%s already has a webhook of another user", owner, repo))
+		return
+	}
+
+	// Check that the token can push to the repository before it is stored.
+	token := strings.TrimSpace(r.FormValue("token"))
+	install := h.github.TokenInstallation(token)
+	ghRepo, _, err := install.Github.Repositories.Get(r.Context(), owner, repo)
+	if err != nil {
+		logrus.Infof("User %s provided a token without access to %s/%s: %s", login, owner, repo, err)
+		redirectError(w, r, "/hooks", fmt.Sprintf("The token can't access %s/%s", owner, repo))
+		return
+	}
+	if !ghRepo.GetPermissions()["push"] {
+		redirectError(w, r, "/hooks", fmt.Sprintf("The token must have push access to %s/%s", owner, repo))
+		return
+	}
+
+	if err := h.repoHooks.Set(owner, repo, login, data.User.GetID(), token); err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	logrus.Infof("User %s added repository webhook of %s/%s", login, owner, repo)
+
+	_, jobNum, err := h.runJob(r.Context(), &Project{
+		Owner:   owner,
+		Repo:    repo,
+		Install: repohooks.Install(data.User.GetID()),
+	}, trigger{Name: "New Webhook", Manual: true})
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/jobs?owner=%s&repo=%s&num=%d", owner, repo, jobNum), http.StatusFound)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+const (
+	// breakerThreshold is the number of consecutive failures of a Github API category after
+	// which its requests fail immediately.
+	breakerThreshold = 5
+	// breakerCooldown is the duration that requests of a failing Github API category fail
+	// immediately, before checking again if it recovered.
+	breakerCooldown = time.Minute
+	// maxRetries is the number of times that a job is retried when Github is unavailable.
+	maxRetries = 3
+)
+
+// retry queues the job again after the breaker cooldown, since Github is unavailable. The
+// job lease is kept, so the job is also requeued if the server is stopped in the meanwhile.
+func (j *Job) retry(err error) {
+	if serr := j.setStatus(status.Retrying); serr != nil {
+		j.log.Errorf("Not retrying: %s", serr)
+		return
+	}
+	j.retries++
+	j.Message = "Github unavailable, will retry"
+	j.Debug = err.Error()
+	j.Duration = time.Now().Sub(j.start)
+	j.log.Warnf("Retrying in %s (%d/%d): %s", breakerCooldown, j.retries, maxRetries, err)
+	if err := j.db.Save(j).Error; err != nil {
+		j.log.Errorf("Failed saving retrying job: %s", err)
+	}
+	j.publish()
+
+	time.AfterFunc(breakerCooldown, func() {
+		if err := j.setStatus(status.Queued); err != nil {
+			j.log.Errorf("Not requeuing: %s", err)
+			return
+		}
+		if err := j.db.Save(j).Error; err != nil {
+			j.log.Errorf("Failed saving requeued job: %s", err)
+		}
+		j.publish()
+		j.queue.push(j, make(chan struct{}))
+	})
+}
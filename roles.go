@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/sirupsen/logrus"
+)
+
+// accountCookie holds the installation account that the user acts on in the dashboard.
+// The personal installation of the user is used if it is not set.
+const accountCookie = "goreadme-account"
+
+// requireRole responds with an error and returns false if the user does not have the
+// given role in the current installation.
+func requireRole(w http.ResponseWriter, data *templateData, role roles.Role) bool {
+	if !data.Role.Allows(role) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// switchAccount sets the installation account that the user acts on. The user must be a
+// member of the organization of the account, which requires the read:org scope, and have a
+// role in its installation.
+func (h *handler) switchAccount(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+	login := data.User.GetLogin()
+	account := strings.TrimSpace(r.FormValue("account"))
+	if account == "" || account == login {
+		http.SetCookie(w, &http.Cookie{Name: accountCookie, Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/projects", http.StatusFound)
+		return
+	}
+
+	if !isMember(h.auth.Orgs(r), account) {
+		redirectError(w, r, "/settings", fmt.Sprintf("You are not a member of %s", account))
+		return
+	}
+	install, err := h.github.Installation(r.Context(), account)
+	if err != nil {
+		logrus.Warnf("User %s tried to switch to account %s: %s", login, account, err)
+		redirectError(w, r, "/settings", fmt.Sprintf("Goreadme is not installed on %s", account))
+		return
+	}
+	prefs, err := h.settings.Installation(account)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	role, err := h.roles.Role(r.Context(), install.Github, account, login, roles.Teams(prefs))
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if role == roles.None {
+		redirectError(w, r, "/settings", fmt.Sprintf("You are not in a team that has access to %s", account))
+		return
+	}
+	logrus.Infof("User %s switched to account %s with role %s", login, account, role)
+	http.SetCookie(w, &http.Cookie{Name: accountCookie, Value: account, Path: "/", HttpOnly: true, Secure: r.TLS != nil})
+	http.Redirect(w, r, "/projects", http.StatusFound)
+}
+
+// isMember returns whether an account is one of the organizations of the user. Github
+// logins are not case sensitive.
+func isMember(orgs []string, account string) bool {
+	for _, org := range orgs {
+		if strings.EqualFold(org, account) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatTeamRoles formats the team roles of installation settings, one "team=role" in a
+// line.
+func formatTeamRoles(settings map[string]string) string {
+	var lines []string
+	for team, role := range roles.Teams(settings) {
+		lines = append(lines, team+"="+string(role))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// parseTeamRoles parses team roles, one "team=role" in a line, to installation settings.
+// Settings of teams that were removed are set to an empty value, so they are deleted.
+func parseTeamRoles(s string, current map[string]string) (map[string]string, error) {
+	values := make(map[string]string)
+	for key := range current {
+		if strings.HasPrefix(key, roles.SettingPrefix) {
+			values[key] = ""
+		}
+	}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid team role %q, expected team=role", line)
+		}
+		role, err := roles.Parse(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		values[roles.SettingPrefix+strings.TrimSpace(parts[0])] = string(role)
+	}
+	return values, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/templates"
+	"github.com/sirupsen/logrus"
+)
+
+// runtimeOwner is the settings owner of the runtime settings. Runtime settings are server
+// settings that can be changed without a restart, and they override the environment
+// configuration.
+const runtimeOwner = ""
+
+// runtimeReloadInterval is the interval in which the runtime settings are reloaded from
+// the database, so changes that were made on another server instance are applied.
+const runtimeReloadInterval = time.Minute
+
+// runtimeSetting is a runtime setting of the admin runtime page.
+type runtimeSetting struct {
+	Key         string
+	Description string
+	// Default is the value from the environment configuration, and Value is the value in
+	// the database, if it was set.
+	Default string
+	Value   string
+	// check validates a value, and apply applies a valid value.
+	check func(value string) error
+	apply func(h *handler, value string)
+}
+
+// runtimeSettings returns the runtime settings. Feature flags are not listed since they
+// are always read from the database.
+func runtimeSettings() []runtimeSetting {
+	return []runtimeSetting{
+		{
+			Key:         "log_level",
+			Description: "Minimal level of logged entries, for example: debug, info or warning.",
+			Default:     cfg.LogLevel,
+			check: func(value string) error {
+				_, err := logrus.ParseLevel(value)
+				return err
+			},
+			apply: func(h *handler, value string) {
+				lvl, _ := logrus.ParseLevel(value)
+				logrus.SetLevel(lvl)
+			},
+		},
+		{
+			Key:         "workers",
+			Description: "Number of jobs that can run concurrently.",
+			Default:     strconv.Itoa(cfg.Workers),
+			check:       checkPositive,
+			apply: func(h *handler, value string) {
+				n, _ := strconv.Atoi(value)
+				h.queue.resize(n)
+			},
+		},
+		{
+			Key:         "api_rate_limit",
+			Description: "Number of API requests that a client can send in a minute.",
+			Default:     strconv.Itoa(defaultAPIRateLimit),
+			check:       checkPositive,
+			apply: func(h *handler, value string) {
+				n, _ := strconv.Atoi(value)
+				atomic.StoreInt32(&h.apiRateLimit, int32(n))
+			},
+		},
+	}
+}
+
+// checkPositive checks that a value is a positive number.
+func checkPositive(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 {
+		return errors.Errorf("invalid value %q, expected a positive number", value)
+	}
+	return nil
+}
+
+// reloadRuntime loads the runtime settings from the database and applies them. Settings
+// that are not set in the database are reset to their default. Invalid settings are
+// logged and skipped.
+func (h *handler) reloadRuntime() ([]runtimeSetting, error) {
+	values, err := h.settings.Installation(runtimeOwner)
+	if err != nil {
+		return nil, err
+	}
+	settings := runtimeSettings()
+	for i := range settings {
+		s := &settings[i]
+		s.Value = values[s.Key]
+		value := s.Value
+		if value == "" {
+			value = s.Default
+		}
+		if err := s.check(value); err != nil {
+			logrus.Errorf("Invalid runtime setting %s: %s", s.Key, err)
+			continue
+		}
+		s.apply(h, value)
+	}
+	return settings, nil
+}
+
+// reloadRuntimePeriodically reloads the runtime settings in intervals.
+func (h *handler) reloadRuntimePeriodically() {
+	for range time.Tick(runtimeReloadInterval) {
+		if _, err := h.reloadRuntime(); err != nil {
+			logrus.Errorf("Failed reloading runtime settings: %s", err)
+		}
+	}
+}
+
+func (h *handler) adminRuntime(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+
+	var err error
+	data.RuntimeSettings, err = h.reloadRuntime()
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	data.Build = build()
+	err = templates.AdminRuntime.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+// adminRuntimeAction sets the runtime settings and applies them. Empty values reset the
+// settings to their default.
+func (h *handler) adminRuntimeAction(w http.ResponseWriter, r *http.Request) {
+	data := h.adminData(w, r)
+	if data == nil {
+		return
+	}
+
+	values := make(map[string]string)
+	for _, s := range runtimeSettings() {
+		value := r.FormValue(s.Key)
+		if value != "" {
+			if err := s.check(value); err != nil {
+				redirectError(w, r, "/admin/runtime", s.Key+": "+err.Error())
+				return
+			}
+		}
+		values[s.Key] = value
+	}
+	err := h.settings.Set(0, runtimeOwner, "", values)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	if _, err := h.reloadRuntime(); err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	logrus.Infof("Admin %s set runtime settings: %v", data.User.GetLogin(), values)
+	http.Redirect(w, r, "/admin/runtime", http.StatusFound)
+}
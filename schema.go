@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/migrations"
+)
+
+// checkSchema verifies that the database was migrated to the schema version
+// that the server expects.
+func checkSchema(db *gorm.DB) error {
+	version, err := migrations.Version(db)
+	if err != nil {
+		return err
+	}
+	switch latest := migrations.Latest(); {
+	case version == 0:
+		return errors.New("database was never migrated, run with -migrate")
+	case version != latest:
+		return errors.Errorf("database schema version is %d, expected %d", version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+)
+
+// checkGithubConfig verifies the Github app settings on boot, so a misconfigured server
+// fails fast with an actionable message, instead of failing on the first request.
+func checkGithubConfig() error {
+	if cfg.GithubHookSecret == "" {
+		return errors.New("GITHUB_HOOK_SECRET is empty: set it to the webhook secret from the Github app settings")
+	}
+	return nil
+}
+
+// checkGithubApp verifies that the app authenticates with Github using its private key.
+func checkGithubApp(ctx context.Context, client *github.Client) error {
+	app, resp, err := client.Apps.Get(ctx, "")
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusUnauthorized:
+		return errors.Errorf("Github app authentication failed: check that GITHUB_APP_ID=%d matches the app of GITHUB_KEY", cfg.GithubAppID)
+	case err != nil:
+		return errors.Wrap(err, "failed getting Github app")
+	case app.GetID() != int64(cfg.GithubAppID):
+		return errors.Errorf("GITHUB_APP_ID=%d, but GITHUB_KEY belongs to app %d", cfg.GithubAppID, app.GetID())
+	}
+	return nil
+}
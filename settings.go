@@ -0,0 +1,189 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme"
+	"github.com/posener/goreadme-server/internal/notify"
+	"github.com/posener/goreadme-server/internal/roles"
+	"github.com/posener/goreadme-server/internal/templates"
+)
+
+// notifierSetting is a notification sink configuration of a project.
+type notifierSetting struct {
+	Name        string
+	Description string
+	Target      string
+}
+
+// configOption is a goreadme option that can be enabled from the project settings page.
+// Options that are enabled in the settings are added to the options of the goreadme.json
+// file of the repository.
+type configOption struct {
+	Key         string
+	Label       string
+	Description string
+	// Enabled is whether the option is enabled in the project settings.
+	Enabled bool
+	field   func(*goreadme.Config) *bool
+}
+
+// configOptions are the goreadme options that are shown in the project settings page.
+var configOptions = []configOption{
+	{
+		Key:         "goreadme.functions",
+		Label:       "Functions",
+		Description: "Add functions documentation to the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.Functions },
+	},
+	{
+		Key:         "goreadme.skip_examples",
+		Label:       "Skip examples",
+		Description: "Omit the examples section from the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.SkipExamples },
+	},
+	{
+		Key:         "goreadme.skip_sub_packages",
+		Label:       "Skip sub packages",
+		Description: "Omit the sub packages section from the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.SkipSubPackages },
+	},
+	{
+		Key:         "goreadme.badges.go_doc",
+		Label:       "GoDoc badge",
+		Description: "Add a GoDoc badge to the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.Badges.GoDoc },
+	},
+	{
+		Key:         "goreadme.badges.goreadme",
+		Label:       "Goreadme badge",
+		Description: "Add a Goreadme badge to the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.Badges.Goreadme },
+	},
+	{
+		Key:         "goreadme.badges.travis_ci",
+		Label:       "Travis CI badge",
+		Description: "Add a Travis CI build badge to the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.Badges.TravicCI },
+	},
+	{
+		Key:         "goreadme.badges.code_cov",
+		Label:       "Codecov badge",
+		Description: "Add a Codecov coverage badge to the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.Badges.CodeCov },
+	},
+	{
+		Key:         "goreadme.badges.golang_ci",
+		Label:       "GolangCI badge",
+		Description: "Add a GolangCI badge to the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.Badges.GolangCI },
+	},
+	{
+		Key:         "goreadme.badges.go_report_card",
+		Label:       "Go Report Card badge",
+		Description: "Add a Go Report Card badge to the readme",
+		field:       func(c *goreadme.Config) *bool { return &c.Badges.GoReportCard },
+	},
+}
+
+// applyConfigOptions enables the goreadme options that are enabled in the project settings.
+func applyConfigOptions(cfg *goreadme.Config, settings map[string]string) {
+	for _, opt := range configOptions {
+		if settings[opt.Key] == "true" {
+			*opt.field(cfg) = true
+		}
+	}
+}
+
+// userProject returns the project in the request path if it belongs to the user
+// installation. It returns nil and responds with an error otherwise.
+func (h *handler) userProject(w http.ResponseWriter, r *http.Request, data *templateData) *Project {
+	vars := mux.Vars(r)
+	var p Project
+	query := h.db.Where("owner = ? AND repo = ? AND install = ?", vars["owner"], vars["repo"], data.InstallID).First(&p)
+	switch {
+	case query.RecordNotFound():
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil
+	case query.Error != nil:
+		h.doError(w, r, errors.Wrap(query.Error, "failed getting project"))
+		return nil
+	}
+	return &p
+}
+
+func (h *handler) projectSettings(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil {
+		return
+	}
+	if !requireRole(w, data, roles.Operator) {
+		return
+	}
+	data.Project = h.userProject(w, r, data)
+	if data.Project == nil {
+		return
+	}
+
+	var err error
+	data.Settings, err = h.settings.Project(data.Project.Owner, data.Project.Repo)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	for _, name := range h.notify.Names() {
+		data.Notifiers = append(data.Notifiers, notifierSetting{
+			Name:        name,
+			Description: h.notify.Get(name).Description(),
+			Target:      data.Settings[notify.SettingPrefix+name],
+		})
+	}
+	for _, opt := range configOptions {
+		opt.Enabled = data.Settings[opt.Key] == "true"
+		data.ConfigOptions = append(data.ConfigOptions, opt)
+	}
+	data.Badges = projectBadges(data.Project)
+
+	err = templates.ProjectSettings.Execute(w, data)
+	if err != nil {
+		h.doError(w, r, errors.Wrap(err, "failed executing template"))
+	}
+}
+
+func (h *handler) projectSettingsAction(w http.ResponseWriter, r *http.Request) {
+	data := h.dataFromRequest(w, r)
+	if data.User == nil || !requireRole(w, data, roles.Operator) {
+		return
+	}
+	p := h.userProject(w, r, data)
+	if p == nil {
+		return
+	}
+
+	values := map[string]string{
+		notify.SettingOn: r.FormValue(notify.SettingOn),
+		settingRemind:    r.FormValue(settingRemind),
+		settingPushPaths: strings.TrimSpace(r.FormValue(settingPushPaths)),
+		settingForkRuns:  r.FormValue(settingForkRuns),
+	}
+	for _, name := range h.notify.Names() {
+		key := notify.SettingPrefix + name
+		values[key] = r.FormValue(key)
+	}
+	for _, opt := range configOptions {
+		// Unchecked options are deleted, and the goreadme.json value is used.
+		values[opt.Key] = ""
+		if r.FormValue(opt.Key) != "" {
+			values[opt.Key] = "true"
+		}
+	}
+	err := h.settings.Set(p.Install, p.Owner, p.Repo, values)
+	if err != nil {
+		h.doError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, r.URL.Path, http.StatusFound)
+}
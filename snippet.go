@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// badgeSnippet holds ready to paste snippets that add a badge to a readme.
+type badgeSnippet struct {
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	Link     string `json:"link"`
+	Markdown string `json:"markdown"`
+	HTML     string `json:"html"`
+	AsciiDoc string `json:"asciidoc"`
+}
+
+func newBadgeSnippet(name, image, link string) badgeSnippet {
+	return badgeSnippet{
+		Name:     name,
+		Image:    image,
+		Link:     link,
+		Markdown: fmt.Sprintf("[![%s](%s)](%s)", name, image, link),
+		HTML:     fmt.Sprintf(`<a href="%s"><img src="%s" alt="%s"></a>`, html.EscapeString(link), html.EscapeString(image), html.EscapeString(name)),
+		AsciiDoc: fmt.Sprintf("image:%s[%s,link=%s]", image, name, link),
+	}
+}
+
+// projectBadges returns the badge snippets of a project.
+func projectBadges(p *Project) []badgeSnippet {
+	badge := fmt.Sprintf("%s/badge/%s/%s", cfg.Domain, p.Owner, p.Repo)
+	return []badgeSnippet{
+		newBadgeSnippet("goreadme", badge+".svg", githubAppURL),
+		newBadgeSnippet("readme quality", badge+"/quality.svg", githubAppURL),
+	}
+}
+
+// snippet returns the badge snippets of a project.
+func (h *handler) snippet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	owner := vars["owner"]
+	repo := vars["repo"]
+
+	p, found, err := h.cachedProject(owner, repo)
+	switch {
+	case err != nil:
+		logrus.Error(err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	case !found:
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Badges []badgeSnippet `json:"badges"`
+	}{
+		Badges: projectBadges(&p),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logrus.Errorf("Failed encoding snippet of %s/%s: %s", owner, repo, err)
+	}
+}
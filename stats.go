@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// statsRefreshInterval is the interval between refreshes of the home page stats.
+const statsRefreshInterval = 5 * time.Minute
+
+// Size of the jobs per day sparkline of the home page.
+const (
+	sparklineWidth  = 300
+	sparklineHeight = 40
+)
+
+type stats struct {
+	// TopProjects will contain top open source projects
+	TopProjects   []Project
+	TotalProjects int
+	// JobsLastDay is the number of jobs in the last 24 hours.
+	JobsLastDay int
+	// JobsPerDay is the number of jobs in each of the last 30 days, oldest first.
+	JobsPerDay []dayJobs
+	// UpdatedAt is the time that the stats were computed.
+	UpdatedAt time.Time
+}
+
+type dayJobs struct {
+	Day  time.Time
+	Jobs int
+}
+
+// SparklineWidth and SparklineHeight are the size of the jobs per day sparkline.
+func (s stats) SparklineWidth() int  { return sparklineWidth }
+func (s stats) SparklineHeight() int { return sparklineHeight }
+
+// SparklinePoints returns the points of an SVG polyline of the jobs per day.
+func (s stats) SparklinePoints() string {
+	if len(s.JobsPerDay) < 2 {
+		return ""
+	}
+	max := 1
+	for _, d := range s.JobsPerDay {
+		if d.Jobs > max {
+			max = d.Jobs
+		}
+	}
+	// Keep a margin for the line width.
+	const margin = 2
+	step := float64(sparklineWidth-2*margin) / float64(len(s.JobsPerDay)-1)
+	scale := float64(sparklineHeight-2*margin) / float64(max)
+	points := make([]string, len(s.JobsPerDay))
+	for i, d := range s.JobsPerDay {
+		x := margin + float64(i)*step
+		y := sparklineHeight - margin - float64(d.Jobs)*scale
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return strings.Join(points, " ")
+}
+
+// loadStats reads the stats summary.
+func loadStats(db *gorm.DB) (stats, error) {
+	var s stats
+	err := db.Table("stats").Select("total_projects, jobs_last_day, updated_at").Row().Scan(&s.TotalProjects, &s.JobsLastDay, &s.UpdatedAt)
+	if err != nil {
+		return s, errors.Wrap(err, "failed reading stats")
+	}
+	err = db.Table("top_projects").Order("stars DESC").Scan(&s.TopProjects).Error
+	if err != nil {
+		return s, errors.Wrap(err, "failed reading top projects")
+	}
+	err = db.Table("jobs_per_day").Order("day").Scan(&s.JobsPerDay).Error
+	if err != nil {
+		return s, errors.Wrap(err, "failed reading jobs per day")
+	}
+	return s, nil
+}
+
+// refreshStats computes the stats summary.
+func refreshStats(db *gorm.DB) error {
+	for _, view := range []string{"stats", "top_projects", "jobs_per_day"} {
+		if err := db.Exec("REFRESH MATERIALIZED VIEW " + view).Error; err != nil {
+			return errors.Wrapf(err, "failed refreshing %s", view)
+		}
+	}
+	return nil
+}
+
+// refreshStatsLoop refreshes the stats summary periodically, until the context is done.
+func refreshStatsLoop(ctx context.Context, db *gorm.DB) {
+	t := time.NewTicker(statsRefreshInterval)
+	defer t.Stop()
+	for {
+		if err := refreshStats(db); err != nil {
+			logrus.Errorf("Failed refreshing stats: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/diff"
+	"github.com/posener/goreadme-server/internal/lint"
+	"github.com/posener/goreadme-server/internal/quality"
+	"github.com/posener/goreadme-server/internal/regions"
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+// targetsDir is a directory in the repository with a configuration file for every readme
+// that should be generated. When it exists, it replaces the generation of the root readme.
+const targetsDir = ".goreadme"
+
+// targetStatusPrefix is the prefix of the commit status contexts of targets. The context of
+// a target is the prefix and the target file name without the extension, for example
+// goreadme/docs, so branch protection rules can require specific targets.
+const targetStatusPrefix = "goreadme/"
+
+// maxStatusDescription is the maximal length of a commit status description.
+const maxStatusDescription = 140
+
+// target is a readme that is generated from a package of the repository. The options of a
+// target file override the options of the repository configuration.
+type target struct {
+	config
+	// Source is the path of the package in the repository. The root package is used if it
+	// is empty.
+	Source string `json:"source"`
+	// Output is the path of the generated readme. It defaults to a README.md in the package
+	// directory.
+	Output string `json:"output"`
+	// name is the target file name without the extension.
+	name string
+}
+
+// getTargets returns the targets that are defined by the JSON files of the targets
+// directory. It returns no targets if the directory does not exist.
+func (j *Job) getTargets(ctx context.Context, cfg config) ([]target, error) {
+	_, files, resp, err := j.github.Repositories.GetContents(ctx, j.Owner, j.Repo, targetsDir, nil)
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrapf(err, "failed listing %s", targetsDir)
+	}
+
+	var targets []target
+	outputs := make(map[string]string)
+	for _, f := range files {
+		if f.GetType() != "file" || path.Ext(f.GetName()) != ".json" {
+			continue
+		}
+		content, _, err := j.getFile(ctx, f.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		t := target{config: cfg, name: strings.TrimSuffix(f.GetName(), ".json")}
+		err = json.Unmarshal([]byte(content), &t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unmarshaling target %s", f.GetPath())
+		}
+		t.Source = strings.Trim(path.Clean("/"+t.Source), "/")
+		if t.Output == "" {
+			t.Output = path.Join(t.Source, defaultReadmePath)
+		}
+		t.Output = strings.Trim(path.Clean("/"+t.Output), "/")
+		if other, ok := outputs[t.Output]; ok {
+			return nil, errors.Errorf("targets %s and %s have the same output %s", other, f.GetPath(), t.Output)
+		}
+		outputs[t.Output] = f.GetPath()
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// runTargets generates the readmes of the targets, and commits the changed readmes to one
+// pull request.
+func (j *Job) runTargets(ctx context.Context, cfg config, targets []target) {
+	var (
+		outputs  = make([]targetOutput, len(targets))
+		changed  []*targetOutput
+		failed   *targetOutput
+		warnings []string
+		all      strings.Builder
+	)
+	defer func() { j.reportTargets(ctx, outputs) }()
+
+	j.Quality = 100
+	for i, t := range targets {
+		outputs[i].target = t
+		content, err := j.generate(ctx, t.config, t.Source)
+		if err == nil {
+			outputs[i].current, outputs[i].found, err = j.getFile(ctx, t.Output)
+		}
+		if err != nil {
+			// Generate the other targets, so the status of each of them is reported.
+			outputs[i].err = err
+			if failed == nil {
+				failed = &outputs[i]
+			}
+			continue
+		}
+		if merged, ok := regions.Merge(outputs[i].current, content); ok {
+			content = merged
+		}
+		// The quality of the readmes is the quality of the worst one.
+		if score, _ := quality.Score(content); score < j.Quality {
+			j.Quality = score
+		}
+		findings := j.lint(ctx, content)
+		for _, f := range findings {
+			warnings = append(warnings, t.Output+": "+f.String())
+		}
+		all.WriteString(content)
+
+		if outputs[i].found {
+			n := diff.ChangedChars(outputs[i].current, content, t.IgnoreWhitespace)
+			if n == 0 || n < t.MinChange {
+				continue
+			}
+		}
+		outputs[i].content, outputs[i].findings, outputs[i].changed = content, findings, true
+		changed = append(changed, &outputs[i])
+	}
+	if failed != nil {
+		j.done(failed.err, "Failed generating %s: %s", failed.Output, failed.err)
+		return
+	}
+	j.scored = true
+	j.Warnings = strings.Join(warnings, "\n")
+	j.checkLinks(all.String())
+
+	if len(changed) == 0 {
+		j.done(nil, "Readmes in branch %s are up to date", j.DefaultBranch)
+		return
+	}
+	if !j.checkPermissions() {
+		return
+	}
+	if err := j.setPROptions(cfg); err != nil {
+		j.done(err, "Invalid configuration")
+		return
+	}
+
+	err := j.createBranch(ctx)
+	if err != nil {
+		j.done(err, "Failed creating branch")
+		return
+	}
+	for _, o := range changed {
+		j.addPRChange(o.Output, o.current, o.content)
+		sha, err := j.fileSHA(ctx, j.headBranch(), o.Output)
+		if err != nil {
+			j.done(err, "Failed get remote %s SHA", o.Output)
+			return
+		}
+		if sha == computeSHA([]byte(o.content)) {
+			j.log.Infof("%s in branch %s is up to date", o.Output, j.headBranch())
+			continue
+		}
+		commitSHA, err := j.commit(ctx, o.Output, []byte(o.content), sha, "Update "+o.Output+" according to go doc")
+		if err != nil {
+			j.done(err, "Failed pushing %s content", o.Output)
+			return
+		}
+		if o.LintCheckRun {
+			if err := j.annotate(ctx, commitSHA, o.Output, o.findings); err != nil {
+				j.log.Warnf("Failed annotating lint findings: %s", err)
+			}
+		}
+	}
+	j.openPR(ctx)
+}
+
+// targetOutput is the result of generating a target.
+type targetOutput struct {
+	target
+	content  string
+	findings []lint.Finding
+	// current is the readme in the default branch, and found is true if it exists.
+	current string
+	found   bool
+	// changed is true if the readme differs from the readme in the default branch, and err
+	// is the generation error.
+	changed bool
+	err     error
+}
+
+// reportTargets sets a commit status on the head commit for every target, according to its
+// result and to the result of the job.
+func (j *Job) reportTargets(ctx context.Context, outputs []targetOutput) {
+	if j.Status == status.Retrying {
+		// The statuses are reported when the job runs again.
+		return
+	}
+	for _, o := range outputs {
+		var state, description string
+		switch {
+		case o.err != nil:
+			state, description = "failure", "Failed: "+o.err.Error()
+		case !o.changed:
+			state, description = "success", "Readme is up to date"
+		case j.Status == status.Success:
+			state, description = "success", fmt.Sprintf("Readme is updated in PR #%d", j.PR)
+		default:
+			state, description = "error", j.Message
+		}
+		if len(description) > maxStatusDescription {
+			description = description[:maxStatusDescription-3] + "..."
+		}
+		_, _, err := j.github.Repositories.CreateStatus(ctx, j.Owner, j.Repo, j.HeadSHA, &github.RepoStatus{
+			State:       github.String(state),
+			Description: github.String(description),
+			Context:     github.String(targetStatusPrefix + o.name),
+			TargetURL:   github.String(j.jobsURL()),
+		})
+		if err != nil {
+			j.log.Warnf("Failed setting status of target %s: %s", o.name, err)
+		}
+	}
+}
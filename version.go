@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+)
+
+// version and commit are the server version and its commit SHA. They are set when the
+// server is built:
+//
+// 	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD)"
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// buildInfo describes the build of the server, for bug reports.
+type buildInfo struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit"`
+	GoVersion       string `json:"go_version"`
+	GoreadmeVersion string `json:"goreadme_version"`
+}
+
+// build returns the build information of the server. The commit is taken from the Heroku
+// dyno metadata if it was not set at build time.
+func build() buildInfo {
+	c := commit
+	if c == "" {
+		c = os.Getenv("HEROKU_SLUG_COMMIT")
+	}
+	return buildInfo{
+		Version:         version,
+		Commit:          c,
+		GoVersion:       runtime.Version(),
+		GoreadmeVersion: moduleVersion(goreadmeModule),
+	}
+}
+
+// versionInfo responds with the build information of the server.
+func versionInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, build())
+}
+
+// contactURL is the contact URL in the default user agent of Github API requests.
+const contactURL = "https://github.com/posener/goreadme-server"
+
+// userAgent returns the User-Agent of Github API requests, which identifies the app, its
+// version and a contact URL, unless it is set by the configuration.
+func userAgent() string {
+	if cfg.UserAgent != "" {
+		return cfg.UserAgent
+	}
+	return fmt.Sprintf("goreadme-server/%s (+%s; %s)", version, contactURL, cfg.Domain)
+}
+
+// versionHeader sets the server version header of the responses of a handler.
+func versionHeader(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Goreadme-Version", version)
+		h.ServeHTTP(w, r)
+	})
+}
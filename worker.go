@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/posener/goreadme-server/internal/events"
+	"github.com/sirupsen/logrus"
+)
+
+// Process modes, set by the MODE environment variable or the -mode flag.
+const (
+	// modeAll serves HTTP and runs jobs in the same process.
+	modeAll = "all"
+	// modeWeb serves HTTP, and leaves the jobs in the database for worker processes.
+	modeWeb = "web"
+	// modeWorker runs the jobs that web processes queued, and doesn't serve HTTP.
+	modeWorker = "worker"
+)
+
+const (
+	// claimInterval is the interval in which workers check for queued jobs.
+	claimInterval = 2 * time.Second
+	// relayInterval is the interval in which web processes check for job changes of
+	// workers.
+	relayInterval = 2 * time.Second
+)
+
+// checkMode returns an error if the process mode is unknown.
+func checkMode(mode string) error {
+	switch mode {
+	case modeAll, modeWeb, modeWorker:
+		return nil
+	default:
+		return errors.Errorf("unknown mode %q, expected %s, %s or %s", mode, modeAll, modeWeb, modeWorker)
+	}
+}
+
+// enqueue creates the job and leaves it in the database, with a lease without an instance,
+// until a worker claims it.
+func (j *Job) enqueue() (done <-chan struct{}, jobNum int, err error) {
+	if err := j.init(); err != nil {
+		return nil, 0, errors.Wrap(err, "failed creating job entry in database")
+	}
+	err = j.db.Save(&Lease{Owner: j.Owner, Repo: j.Repo, Num: j.Num, HeartbeatAt: time.Now()}).Error
+	if err != nil {
+		j.log.Errorf("Failed queuing job for workers: %s", err)
+	}
+	j.log.Infof("Queued PR process for workers")
+
+	// The job runs in another process, the caller can't wait for it.
+	ch := make(chan struct{})
+	close(ch)
+	return ch, j.Num, nil
+}
+
+// claimLoop claims jobs that web processes queued, as long as there are idle workers, until
+// the context is done.
+func (h *handler) claimLoop(ctx context.Context) {
+	t := time.NewTicker(claimInterval)
+	defer t.Stop()
+	for {
+		if n := h.queue.idle(); n > 0 {
+			h.claim(ctx, n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// claim takes up to n queued jobs, in the order that they were queued, and runs them.
+func (h *handler) claim(ctx context.Context, n int) {
+	var leases []Lease
+	err := h.db.Where("instance = ''").Order("heartbeat_at").Limit(n).Find(&leases).Error
+	if err != nil {
+		logrus.Errorf("Failed getting queued jobs: %s", err)
+		return
+	}
+	for _, l := range leases {
+		// Take the lease, unless another worker already did.
+		query := h.db.Model(&Lease{}).
+			Where("owner = ? AND repo = ? AND num = ? AND instance = ''", l.Owner, l.Repo, l.Num).
+			UpdateColumns(map[string]interface{}{"instance": instanceID, "heartbeat_at": time.Now()})
+		if query.Error != nil {
+			logrus.Errorf("Failed claiming %s/%s#%d: %s", l.Owner, l.Repo, l.Num, query.Error)
+			continue
+		}
+		if query.RowsAffected == 0 {
+			continue
+		}
+		logrus.Infof("Claimed queued job %s/%s#%d", l.Owner, l.Repo, l.Num)
+		l.Instance = instanceID
+		if err := h.resume(ctx, l, "Could not be started by a worker"); err != nil {
+			logrus.Errorf("Failed running %s/%s#%d: %s", l.Owner, l.Repo, l.Num, err)
+		}
+	}
+}
+
+// relayEvents publishes the changes of jobs that run in worker processes to the dashboard
+// clients of the web process, until the context is done.
+func (h *handler) relayEvents(ctx context.Context) {
+	t := time.NewTicker(relayInterval)
+	defer t.Stop()
+	since := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		var jobs []Job
+		err := h.db.Where("updated_at > ?", since).Order("updated_at").Find(&jobs).Error
+		if err != nil {
+			logrus.Errorf("Failed getting changed jobs: %s", err)
+			continue
+		}
+		for _, j := range jobs {
+			h.events.Publish(events.Event{
+				Install: j.Install,
+				Owner:   j.Owner,
+				Repo:    j.Repo,
+				Num:     j.Num,
+				Status:  j.Status,
+				Message: j.Message,
+				PR:      j.PR,
+			})
+			since = j.UpdatedAt
+		}
+	}
+}
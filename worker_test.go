@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/posener/goreadme-server/internal/status"
+)
+
+func TestClaim(t *testing.T) {
+	gh := newTestServer()
+	defer gh.Close()
+	h, cleanup := newTestHandler(t, gh)
+	defer cleanup()
+
+	// A web process records the jobs for workers.
+	h.mode = modeWeb
+	p := func() *Project { return &Project{Owner: "posener", Repo: "hello", Install: testInstall} }
+	for i := 0; i < 2; i++ {
+		if _, _, err := h.runJob(context.Background(), p(), trigger{Name: "Manual"}); err != nil {
+			t.Fatalf("runJob: %s", err)
+		}
+	}
+	if ls := leases(t, h); len(ls) != 2 || ls[0].Instance != "" || ls[1].Instance != "" {
+		t.Fatalf("Got leases %+v, want two unclaimed leases", ls)
+	}
+
+	// A worker claims the oldest job.
+	h.mode = modeWorker
+	h.claim(context.Background(), 1)
+	h.queue.wait()
+	js := jobs(t, h)
+	if len(js) != 2 || js[0].Status != status.Success || js[1].Status != status.Queued {
+		t.Fatalf("Got jobs %+v, want the first job to succeed and the second to stay queued", js)
+	}
+	if ls := leases(t, h); len(ls) != 1 || ls[0].Num != 2 {
+		t.Errorf("Got leases %+v, want the lease of the second job", ls)
+	}
+
+	h.claim(context.Background(), 1)
+	h.queue.wait()
+	if js := jobs(t, h); js[1].Status != status.Success {
+		t.Errorf("Second job status = %s, want %s", js[1].Status, status.Success)
+	}
+	if ls := leases(t, h); len(ls) != 0 {
+		t.Errorf("Got leases %+v after the jobs finished", ls)
+	}
+}